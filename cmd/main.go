@@ -3,6 +3,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
@@ -12,19 +13,24 @@ import (
 	"maqhaa/auth_service/internal/database"
 	"maqhaa/auth_service/internal/interface/http/handler"
 	"maqhaa/auth_service/internal/interface/http/router"
+	"maqhaa/auth_service/internal/notification"
 	"maqhaa/library/logging"
-	"maqhaa/library/middleware"
 	"net"
 	"net/http"
 	"os"
 	"strings"
 	"time"
 
+	"maqhaa/auth_service/internal/interface/grpc/gateway"
 	grpcHandler "maqhaa/auth_service/internal/interface/grpc/handler"
+	"maqhaa/auth_service/internal/interface/grpc/interceptor"
 	pb "maqhaa/auth_service/internal/interface/grpc/model"
 
 	"github.com/soheilhy/cmux"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
 func main() {
@@ -58,30 +64,116 @@ func main() {
 	defer sqlDB.Close()
 
 	// Initialize handlers
+	trustedProxies, err := service.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		logging.Log.Fatalf("Error loading configuration: %v", err)
+	}
+
 	httpRouter := router.NewMuxRouter()
+	httpRouter.GetRouter().Use(handler.RequestMetadataMiddleware(trustedProxies))
 
 	pingHandler := handler.NewPingHandler()
 	httpRouter.GET("/ping", pingHandler.Ping)
 
+	// Notifications are delivered in the background, queued ones are flushed on exit
+	if err := cfg.Notification.Validate(); err != nil {
+		logging.Log.Fatalf("Error loading configuration: %v", err)
+	}
+	notifier, err := notification.New(cfg.Notification)
+	if err != nil {
+		logging.Log.Fatalf("Error loading configuration: %v", err)
+	}
+	defer notifier.Close()
+
 	//Initialize Auth srvice
 	userRepository := repository.NewUserRepository(db)
-	authService := service.NewAuthService(userRepository)
+	oauthRepository := repository.NewOAuthRepository(db)
+	auditRepository := repository.NewAuditRepository(db)
+	authService := service.NewAuthService(userRepository, oauthRepository, auditRepository, notifier)
 	authHandler := handler.NewAuthHandler(authService)
 
-	httpRouter.POST("/login", authHandler.LoginHandler)
-	httpRouter.GET("/user", authHandler.GetAllUserHandler)
-	httpRouter.POST("/user", authHandler.AddUserHandler)
-	httpRouter.PUT("/user", authHandler.EditUserHandler)
-	httpRouter.DELETE("/user/{userID}", authHandler.DeactivateUserHandler)
-	httpRouter.DELETE("/logout", authHandler.LogoutHandler)
+	// Versioned REST API, the unversioned paths are deprecated aliases of v1
+	v1Router := httpRouter.VERSION("v1")
+	authHandler.RegisterRoutesV1(v1Router)
 
-	userHandlerGrpc := grpcHandler.NewUserGRPCHandler(authService)
+	legacyDeprecation, legacySunset, err := cfg.HTTP.LegacySchedule()
+	if err != nil {
+		logging.Log.Fatalf("Error loading configuration: %v", err)
+	}
+	authHandler.RegisterLegacyRoutes(httpRouter.DEPRECATED("v1", legacyDeprecation, legacySunset))
+
+	//Initialize OAuth service
+	if err := cfg.OAuth.Validate(); err != nil {
+		logging.Log.Fatalf("Error loading configuration: %v", err)
+	}
+	tokenSigner, err := service.NewTokenSigner(cfg.OAuth.SigningKeyFile)
+	if err != nil {
+		logging.Log.Fatalf("Error loading signing key: %v", err)
+	}
+	if cfg.OAuth.SigningKeyFile == "" {
+		logging.Log.Warn("No oauth.signingkeyfile configured, tokens are signed with an ephemeral key (development only)")
+	}
+	oauthService := service.NewOAuthService(oauthRepository, authService, tokenSigner, cfg.OAuth)
+	oauthHandler := handler.NewOAuthHandler(oauthService)
+
+	httpRouter.GET("/oauth/authorize", oauthHandler.AuthorizeHandler)
+	httpRouter.POST("/oauth/authorize", oauthHandler.AuthorizeHandler)
+	httpRouter.POST("/oauth/token", oauthHandler.TokenHandler)
+	httpRouter.POST("/oauth/introspect", oauthHandler.IntrospectHandler)
+	httpRouter.POST("/oauth/revoke", oauthHandler.RevokeHandler)
+	httpRouter.GET("/.well-known/openid-configuration", oauthHandler.OpenIDConfigurationHandler)
+	httpRouter.GET("/.well-known/jwks.json", oauthHandler.JWKSHandler)
+	httpRouter.GET("/userinfo", oauthHandler.UserInfoHandler)
+	httpRouter.POST("/userinfo", oauthHandler.UserInfoHandler)
+
+	userHandlerGrpc := grpcHandler.NewUserGRPCHandler(authService, cfg.Grpc.LegacyErrors)
 	// Initialize gRPC server
-	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(middleware.LoggingInterceptor))
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			interceptor.RequestIDInterceptor,
+			interceptor.RequestMetadataInterceptor(trustedProxies),
+			interceptor.AuthInterceptor(authService, oauthService, cfg.OAuth.RequireServiceToken),
+		),
+		grpc.ChainStreamInterceptor(
+			interceptor.RequestIDStreamInterceptor,
+			interceptor.RequestMetadataStreamInterceptor(trustedProxies),
+			interceptor.AuthStreamInterceptor(authService, oauthService),
+		),
+	)
 
 	// Register gRPC service implementation
 	pb.RegisterUserServer(grpcServer, userHandlerGrpc)
 
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	go grpcHandler.WatchDatabaseHealth(context.Background(), healthServer, sqlDB, cfg.Grpc.HealthCheckInterval)
+
+	if cfg.Grpc.Reflection {
+		reflection.Register(grpcServer)
+	}
+
+	if cfg.Grpc.Gateway {
+		// The gateway calls the gRPC server through its listener so the interceptors apply
+		grpcEndpoint := cfg.AppPort
+		if cfg.Grpc.DedicatedPort {
+			grpcEndpoint = cfg.GrpcPort
+		}
+		gatewayHandler, err := gateway.NewHandler(context.Background(), grpcEndpoint)
+		if err != nil {
+			logging.Log.Fatalf("Error creating gRPC gateway: %v", err)
+		}
+		httpRouter.PREFIX(gateway.PathPrefix, gatewayHandler)
+	}
+
+	httpServer := &http.Server{
+		Handler: httpRouter.GetRouter(),
+	}
+
+	if cfg.Grpc.DedicatedPort {
+		serveDedicated(cfg, grpcServer, httpServer)
+		return
+	}
+
 	// Create a TCP listener on the app port for both HTTP and gRPC
 	listener, err := net.Listen("tcp", cfg.AppPort)
 	if err != nil {
@@ -106,9 +198,6 @@ func main() {
 
 	// Start HTTP server on HTTP listener
 	go func() {
-		httpServer := &http.Server{
-			Handler: httpRouter.GetRouter(),
-		}
 		logging.Log.Infof("HTTP server listening on %s", cfg.AppPort)
 		if err := httpServer.Serve(httpListener); err != nil && err != http.ErrServerClosed {
 			logging.Log.Errorf("HTTP server error: %v", err)
@@ -122,6 +211,28 @@ func main() {
 	}
 }
 
+// serveDedicated serves HTTP on AppPort and gRPC on its own listener on GrpcPort.
+func serveDedicated(cfg *config.Config, grpcServer *grpc.Server, httpServer *http.Server) {
+	grpcListener, err := net.Listen("tcp", cfg.GrpcPort)
+	if err != nil {
+		logging.Log.Fatalf("Error creating gRPC listener: %v", err)
+	}
+	defer grpcListener.Close()
+
+	go func() {
+		logging.Log.Infof("gRPC server listening on %s", cfg.GrpcPort)
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			logging.Log.Fatalf("gRPC server error: %v", err)
+		}
+	}()
+
+	httpServer.Addr = cfg.AppPort
+	logging.Log.Infof("HTTP server listening on %s", cfg.AppPort)
+	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logging.Log.Fatalf("HTTP server error: %v", err)
+	}
+}
+
 func initLogging(logFolder string) {
 	logging.InitLogger()
 
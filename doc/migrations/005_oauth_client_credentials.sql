@@ -0,0 +1,29 @@
+-- OAuth2 client-credentials grant for internal services
+
+-- Registered OAuth2 clients (service principals), stored separately from human users
+CREATE TABLE IF NOT EXISTS oauth_client (
+    id BIGSERIAL PRIMARY KEY,
+    client_id VARCHAR(255) NOT NULL,
+    client_secret TEXT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    scopes TEXT NOT NULL DEFAULT '',
+    grant_types TEXT NOT NULL DEFAULT 'client_credentials',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT uk_oauth_client_client_id UNIQUE (client_id)
+);
+
+-- Tokens issued by /oauth/token
+CREATE TABLE IF NOT EXISTS oauth_token (
+    id BIGSERIAL PRIMARY KEY,
+    token VARCHAR(255) NOT NULL,
+    token_type VARCHAR(50) NOT NULL,
+    client_id VARCHAR(255) NOT NULL,
+    user_id BIGINT NOT NULL DEFAULT 0,
+    scope TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT uk_oauth_token_token UNIQUE (token)
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_token_client_id ON oauth_token (client_id);
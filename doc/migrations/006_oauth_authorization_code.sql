@@ -0,0 +1,29 @@
+-- OAuth2 authorization-code flow with PKCE for the web back-office
+
+-- Redirect URIs are registered per client application (space-delimited).
+-- Public clients such as SPAs have no secret and must use PKCE.
+ALTER TABLE oauth_client ADD COLUMN IF NOT EXISTS redirect_uris TEXT NOT NULL DEFAULT '';
+ALTER TABLE oauth_client ALTER COLUMN client_secret SET DEFAULT '';
+
+-- Single-use authorization codes issued by /oauth/authorize
+CREATE TABLE IF NOT EXISTS oauth_authorization_code (
+    id BIGSERIAL PRIMARY KEY,
+    code VARCHAR(255) NOT NULL,
+    client_id VARCHAR(255) NOT NULL,
+    user_id BIGINT NOT NULL,
+    redirect_uri TEXT NOT NULL,
+    scope TEXT NOT NULL DEFAULT '',
+    code_challenge VARCHAR(255) NOT NULL,
+    code_challenge_method VARCHAR(10) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT uk_oauth_authorization_code_code UNIQUE (code),
+    CONSTRAINT fk_oauth_authorization_code_user
+        FOREIGN KEY (user_id)
+        REFERENCES "user" (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_token_user_id ON oauth_token (user_id);
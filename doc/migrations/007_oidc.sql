@@ -0,0 +1,7 @@
+-- OpenID Connect: nonce is carried from /oauth/authorize into the ID token
+
+ALTER TABLE oauth_authorization_code ADD COLUMN IF NOT EXISTS nonce VARCHAR(255) NOT NULL DEFAULT '';
+
+-- Allow the back-office to request ID tokens
+UPDATE oauth_client SET scopes = 'openid ' || scopes
+WHERE client_id = 'backoffice-web' AND scopes NOT LIKE '%openid%';
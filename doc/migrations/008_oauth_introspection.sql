@@ -0,0 +1,3 @@
+-- RFC 7662 token introspection
+-- Callers of /oauth/introspect are service principals holding the token.introspect scope.
+-- No schema change: introspecting clients are registered per environment (see doc/seed).
@@ -0,0 +1,11 @@
+-- RFC 7009 token revocation
+-- Revoked tokens keep their row and are marked with the revocation time.
+
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS token_revoked_at TIMESTAMPTZ NULL;
+ALTER TABLE oauth_token ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMPTZ NULL;
+
+-- Logout used to write a random 8 character replacement token that stayed valid until the
+-- old expiry, while logins issue 16 character tokens. Clear the replacement tokens, which no
+-- session ever received, together with the expired leftovers.
+UPDATE "user" SET token = '', token_revoked_at = NOW()
+WHERE token <> '' AND (token_expired < NOW() OR length(token) <> 16);
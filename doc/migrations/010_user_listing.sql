@@ -0,0 +1,8 @@
+-- User listing: outlet assignment and indexes for paginated, filtered listing per client
+
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS outlet_id BIGINT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_user_client_id_id ON "user" (client_id, id);
+CREATE INDEX IF NOT EXISTS idx_user_client_outlet ON "user" (client_id, outlet_id);
+CREATE INDEX IF NOT EXISTS idx_user_client_username_lower ON "user" (client_id, LOWER(username) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_user_client_full_name_lower ON "user" (client_id, LOWER(full_name) text_pattern_ops);
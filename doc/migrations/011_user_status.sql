@@ -0,0 +1,16 @@
+-- User status: reason and actor of the last activation/deactivation, soft delete
+
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS status_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS status_changed_by BIGINT NULL;
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMPTZ NULL;
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL;
+
+CREATE INDEX IF NOT EXISTS idx_user_deleted_at ON "user" (deleted_at);
+
+-- Users used to be activated on their first login; now AddUser creates them
+-- active and Authenticate refuses inactive users. Only accounts that never
+-- logged in (no session token was ever issued) are activated, since their first
+-- login would have activated them anyway. Accounts an admin deactivated after
+-- they logged in keep their token and stay inactive.
+UPDATE "user" SET is_active = true
+WHERE is_active = false AND deleted_at IS NULL AND password <> '' AND COALESCE(token, '') = '';
@@ -0,0 +1,3 @@
+-- User erasure: personal data removed for good, the row stays as a tombstone
+
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS erased_at TIMESTAMPTZ NULL;
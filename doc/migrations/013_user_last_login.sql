@@ -0,0 +1,3 @@
+-- Last successful login of a user, reported by the user export
+
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS last_login_at TIMESTAMPTZ NULL;
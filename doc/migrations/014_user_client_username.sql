@@ -0,0 +1,14 @@
+-- Per-client usernames: clients get a login code and usernames are unique per client
+
+ALTER TABLE client ADD COLUMN IF NOT EXISTS code TEXT NULL;
+
+-- Existing clients get a code derived from their id, which can be replaced by a readable one
+UPDATE client SET code = 'client' || id WHERE code IS NULL OR code = '';
+
+ALTER TABLE client ALTER COLUMN code SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS uk_client_code ON client (code);
+
+-- The per-client index is created first so usernames are never left without a constraint.
+-- Usernames are globally unique until then, so existing users keep logging in without a code.
+CREATE UNIQUE INDEX IF NOT EXISTS uk_user_client_username ON "user" (client_id, username);
+DROP INDEX IF EXISTS uk_user_username;
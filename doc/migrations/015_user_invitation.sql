@@ -0,0 +1,22 @@
+-- User invitations: single-use activation tokens letting an invited user set its own password
+
+CREATE TABLE IF NOT EXISTS user_invitation (
+    id BIGSERIAL PRIMARY KEY,
+    client_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    invited_by BIGINT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    accepted_at TIMESTAMPTZ NULL,
+    revoked_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT uk_user_invitation_token_hash UNIQUE (token_hash),
+    CONSTRAINT fk_user_invitation_user
+        FOREIGN KEY (user_id)
+        REFERENCES "user" (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_invitation_client_id ON user_invitation (client_id);
+CREATE INDEX IF NOT EXISTS idx_user_invitation_user_id ON user_invitation (user_id);
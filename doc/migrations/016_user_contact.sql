@@ -0,0 +1,31 @@
+-- User contacts: optional email and phone, unique per client, verified through one-time codes
+
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS email TEXT NOT NULL DEFAULT '';
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS phone TEXT NOT NULL DEFAULT '';
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ NULL;
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS phone_verified_at TIMESTAMPTZ NULL;
+
+-- Emails are stored lower case and phones as digits with an optional leading +
+CREATE UNIQUE INDEX IF NOT EXISTS uk_user_client_email ON "user" (client_id, email) WHERE email <> '';
+CREATE UNIQUE INDEX IF NOT EXISTS uk_user_client_phone ON "user" (client_id, phone) WHERE phone <> '';
+
+CREATE TABLE IF NOT EXISTS user_verification (
+    id BIGSERIAL PRIMARY KEY,
+    client_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    channel VARCHAR(10) NOT NULL,
+    target TEXT NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    verified_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user_verification_user
+        FOREIGN KEY (user_id)
+        REFERENCES "user" (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_verification_client_id ON user_verification (client_id);
+CREATE INDEX IF NOT EXISTS idx_user_verification_user_id ON user_verification (user_id);
@@ -0,0 +1,24 @@
+-- Audit log: authentication and administration events, queried per client by GET /v1/audit
+
+CREATE TABLE IF NOT EXISTS audit_event (
+    id BIGSERIAL PRIMARY KEY,
+    -- 0 for failed logins that could not be tied to a client
+    client_id BIGINT NOT NULL DEFAULT 0,
+    event VARCHAR(50) NOT NULL,
+    outcome VARCHAR(10) NOT NULL,
+    actor_id BIGINT NULL,
+    target_id BIGINT NULL,
+    username TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT '',
+    changes TEXT NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Events outlive the users they name, so actor_id and target_id have no foreign key
+CREATE INDEX IF NOT EXISTS idx_audit_event_client_id ON audit_event (client_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_event_event ON audit_event (event);
+CREATE INDEX IF NOT EXISTS idx_audit_event_target_id ON audit_event (target_id);
+CREATE INDEX IF NOT EXISTS idx_audit_event_created_at ON audit_event (created_at);
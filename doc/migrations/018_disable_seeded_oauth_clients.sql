@@ -0,0 +1,11 @@
+-- Earlier migrations seeded test OAuth2 clients, which now live in
+-- doc/seed/oauth_clients_dev.sql. Databases that already ran those migrations get
+-- the service principals with the published secret "service-secret" deactivated.
+UPDATE oauth_client SET is_active = false
+WHERE client_secret = '$2a$10$3BhWmYEdRFEkkiqVxuuI1.ZSJQu5r5QZS1191cN/QvTJu2Tpz8NAq';
+
+-- The back-office client was seeded with a localhost redirect URI. Only the redirect
+-- URIs registered for the environment are kept.
+UPDATE oauth_client
+SET redirect_uris = btrim(replace(' ' || redirect_uris || ' ', ' http://localhost:3000/callback ', ' '))
+WHERE client_id = 'backoffice-web';
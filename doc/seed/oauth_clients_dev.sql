@@ -0,0 +1,19 @@
+-- Development and test OAuth2 clients
+-- Run after doc/migrations on local and test databases only, NEVER in production:
+-- the secrets below are published. Production clients are registered per environment
+-- with their own secrets and redirect URIs.
+
+-- Service principal, secret "service-secret" hashed with bcrypt
+INSERT INTO oauth_client (client_id, client_secret, name, scopes, grant_types, is_active, created_at)
+VALUES ('order-service', '$2a$10$3BhWmYEdRFEkkiqVxuuI1.ZSJQu5r5QZS1191cN/QvTJu2Tpz8NAq', 'Order Service', 'user.read', 'client_credentials', true, NOW())
+ON CONFLICT DO NOTHING;
+
+-- Introspecting gateway, same secret "service-secret"
+INSERT INTO oauth_client (client_id, client_secret, name, scopes, grant_types, is_active, created_at)
+VALUES ('api-gateway', '$2a$10$3BhWmYEdRFEkkiqVxuuI1.ZSJQu5r5QZS1191cN/QvTJu2Tpz8NAq', 'API Gateway', 'token.introspect', 'client_credentials', true, NOW())
+ON CONFLICT DO NOTHING;
+
+-- Back-office SPA (public client) served locally
+INSERT INTO oauth_client (client_id, client_secret, name, scopes, grant_types, redirect_uris, is_active, created_at)
+VALUES ('backoffice-web', '', 'Maqha Back-office', 'profile user.read user.write', 'authorization_code refresh_token', 'http://localhost:3000/callback', true, NOW())
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,38 @@
+package entity
+
+import (
+	"time"
+)
+
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditRedactedValue replaces secrets, and the personal data of erased users, in the changes of an
+// audit event.
+const AuditRedactedValue = "***"
+
+// AuditEvent is an authentication or administration event of a client. ActorID is who acted, nil for
+// an anonymous caller (e.g. a failed login), and TargetID what was acted on. Username is the login
+// name given to a login. Changes is the JSON diff of the changed fields, passwords masked. Erasing a
+// user masks its personal data in the events naming it.
+type AuditEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ClientID  uint      `gorm:"index" json:"clientId"`
+	Event     string    `gorm:"index" json:"event"`
+	Outcome   string    `json:"outcome"`
+	ActorID   *uint     `json:"actorId"`
+	TargetID  *uint     `gorm:"index" json:"targetId"`
+	Username  string    `json:"username"`
+	Reason    string    `json:"reason"`
+	Changes   string    `json:"changes"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"userAgent"`
+	RequestID string    `json:"requestId"`
+	CreatedAt time.Time `gorm:"index" json:"createdAt"`
+}
+
+func (AuditEvent) TableName() string {
+	return "audit_event"
+}
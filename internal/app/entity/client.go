@@ -6,6 +6,7 @@ import (
 
 type Client struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
+	Code        string    `json:"code"`
 	CompanyName string    `json:"companyName"`
 	Email       string    `json:"email"`
 	PhoneNumber string    `json:"phoneNumber"`
@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+)
+
+const (
+	CodeChallengeMethodS256 = "S256"
+)
+
+// OAuthAuthorizationCode represents a single-use code issued by the authorization endpoint.
+type OAuthAuthorizationCode struct {
+	ID                  uint       `gorm:"primaryKey" json:"id"`
+	Code                string     `gorm:"uniqueIndex" json:"code"`
+	ClientID            string     `json:"clientId"`
+	UserID              uint       `json:"userId"`
+	RedirectURI         string     `json:"redirectUri"`
+	Scope               string     `json:"scope"`
+	CodeChallenge       string     `json:"codeChallenge"`
+	CodeChallengeMethod string     `json:"codeChallengeMethod"`
+	Nonce               string     `json:"nonce"`
+	ExpiresAt           time.Time  `json:"expiresAt"`
+	UsedAt              *time.Time `json:"usedAt"`
+	CreatedAt           time.Time  `json:"createdAt"`
+}
+
+func (OAuthAuthorizationCode) TableName() string {
+	return "oauth_authorization_code"
+}
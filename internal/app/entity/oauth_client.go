@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"time"
+)
+
+const (
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+)
+
+// OAuthClient represents a registered OAuth2 client, such as an internal service principal.
+type OAuthClient struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	ClientID     string    `gorm:"uniqueIndex" json:"clientId"`
+	ClientSecret string    `json:"-"`
+	Name         string    `json:"name"`
+	Scopes       string    `json:"scopes"`
+	GrantTypes   string    `json:"grantTypes"`
+	RedirectURIs string    `json:"redirectUris"`
+	IsActive     bool      `json:"isActive"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+func (OAuthClient) TableName() string {
+	return "oauth_client"
+}
+
+// IsPublic reports whether the client has no secret, like a browser SPA, and must rely on PKCE.
+func (c OAuthClient) IsPublic() bool {
+	return c.ClientSecret == ""
+}
@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"time"
+)
+
+const (
+	TokenTypeAccess  = "access_token"
+	TokenTypeRefresh = "refresh_token"
+)
+
+// OAuthToken represents a token issued by the OAuth2 endpoints.
+type OAuthToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	Token     string     `gorm:"uniqueIndex" json:"token"`
+	TokenType string     `json:"tokenType"`
+	ClientID  string     `json:"clientId"`
+	UserID    uint       `json:"userId"`
+	Scope     string     `json:"scope"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+func (OAuthToken) TableName() string {
+	return "oauth_token"
+}
+
+// IsActive reports whether the token is neither expired nor revoked.
+func (t OAuthToken) IsActive() bool {
+	return t.RevokedAt == nil && t.ExpiresAt.After(time.Now())
+}
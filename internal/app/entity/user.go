@@ -1,7 +1,10 @@
 package entity
 
 import (
+	"fmt"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 const (
@@ -9,18 +12,67 @@ const (
 	RoleEmployeCode = 2
 )
 
-// User represents a user in the system.
+// rolePermissions is the role catalogue: the known roles and what each may do in the user API.
+var rolePermissions = map[uint][]string{
+	RoleAdminCode:   {"profile.read", "user.read", "user.write"},
+	RoleEmployeCode: {"profile.read"},
+}
+
+// roleNames are the names of the roles of the catalogue.
+var roleNames = map[uint]string{
+	RoleAdminCode:   "admin",
+	RoleEmployeCode: "employee",
+}
+
+// RoleName returns the name of a role, empty for a role outside the catalogue.
+func RoleName(role uint) string {
+	return roleNames[role]
+}
+
+// IsValidRole reports whether role is in the role catalogue.
+func IsValidRole(role uint) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RolePermissions returns the permissions granted to a role.
+func RolePermissions(role uint) []string {
+	return append([]string{}, rolePermissions[role]...)
+}
+
+// User represents a user in the system. StatusReason, StatusChangedBy and StatusChangedAt record the
+// last activation, deactivation, deletion or restore. Deleted users are hidden from every query.
+// Erased users are deleted users whose personal data was removed for good; only the row is kept so
+// references to the id stay valid. Email and Phone are optional and unique per client; their
+// VerifiedAt is set once the user proved it receives messages there and cleared when they change.
 type User struct {
-	ID           uint      `gorm:"primaryKey" json:"id"`
-	ClientID     uint      `json:"clientId"`
-	Username     string    `json:"username"`
-	Password     string    `json:"password"`
-	FullName     string    `json:"fullName"`
-	Role         uint      `json:"role"`
-	Token        string    `json:"token"`
-	TokenExpired time.Time `json:"tokenExpired"`
-	IsActive     bool      `json:"isActive"`
-	CreatedAt    time.Time `json:"createdAt"`
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	ClientID        uint           `gorm:"uniqueIndex:uk_user_client_username,priority:1" json:"clientId"`
+	Username        string         `gorm:"uniqueIndex:uk_user_client_username,priority:2" json:"username"`
+	Password        string         `json:"password"`
+	FullName        string         `json:"fullName"`
+	Email           string         `json:"email"`
+	Phone           string         `json:"phone"`
+	EmailVerifiedAt *time.Time     `json:"emailVerifiedAt"`
+	PhoneVerifiedAt *time.Time     `json:"phoneVerifiedAt"`
+	Role            uint           `json:"role"`
+	OutletID        *uint          `json:"outletId"`
+	Token           string         `json:"token"`
+	TokenExpired    time.Time      `json:"tokenExpired"`
+	TokenRevokedAt  *time.Time     `json:"tokenRevokedAt"`
+	LastLoginAt     *time.Time     `json:"lastLoginAt"`
+	IsActive        bool           `json:"isActive"`
+	StatusReason    string         `json:"statusReason"`
+	StatusChangedBy *uint          `json:"statusChangedBy"`
+	StatusChangedAt *time.Time     `json:"statusChangedAt"`
+	CreatedAt       time.Time      `json:"createdAt"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"deletedAt"`
+	ErasedAt        *time.Time     `json:"erasedAt"`
+}
+
+// ErasedUsername is the username left on the row of an erased user.
+func ErasedUsername(ID uint) string {
+	return fmt.Sprintf("erased-%d", ID)
 }
 
 func (User) TableName() string {
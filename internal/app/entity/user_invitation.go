@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"time"
+)
+
+const (
+	InvitationStatusPending  = "pending"
+	InvitationStatusAccepted = "accepted"
+	InvitationStatusRevoked  = "revoked"
+	InvitationStatusExpired  = "expired"
+)
+
+// UserInvitation is a single-use activation token letting an invited user set its own password.
+// Only a hash of the token is stored.
+type UserInvitation struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	ClientID   uint       `gorm:"index" json:"clientId"`
+	UserID     uint       `gorm:"index" json:"userId"`
+	User       User       `gorm:"foreignKey:UserID" json:"-"`
+	TokenHash  string     `gorm:"uniqueIndex" json:"-"`
+	InvitedBy  uint       `json:"invitedBy"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	AcceptedAt *time.Time `json:"acceptedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+func (UserInvitation) TableName() string {
+	return "user_invitation"
+}
+
+// Status returns the state of the invitation at now.
+func (i UserInvitation) Status(now time.Time) string {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationStatusAccepted
+	case i.RevokedAt != nil:
+		return InvitationStatusRevoked
+	case !i.ExpiresAt.After(now):
+		return InvitationStatusExpired
+	}
+	return InvitationStatusPending
+}
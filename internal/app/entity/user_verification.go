@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"time"
+)
+
+const (
+	VerificationChannelEmail = "email"
+	VerificationChannelPhone = "phone"
+)
+
+// UserVerification is a one-time code sent to the email address or phone number of a user, proving
+// the user receives messages there once it is confirmed. Target is the address the code was sent
+// to; only a hash of the code is stored.
+type UserVerification struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	ClientID   uint       `gorm:"index" json:"clientId"`
+	UserID     uint       `gorm:"index" json:"userId"`
+	Channel    string     `json:"channel"`
+	Target     string     `json:"target"`
+	CodeHash   string     `json:"-"`
+	Attempts   int        `json:"attempts"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	VerifiedAt *time.Time `json:"verifiedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+func (UserVerification) TableName() string {
+	return "user_verification"
+}
@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// FieldChange is the value of a field before and after a change.
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// AuditEvent is an entry of the audit log of a client.
+type AuditEvent struct {
+	ID        uint                   `json:"id"`
+	Event     string                 `json:"event"`
+	Outcome   string                 `json:"outcome"`
+	ActorID   *uint                  `json:"actor_id"`
+	TargetID  *uint                  `json:"target_id"`
+	Username  string                 `json:"username,omitempty"`
+	Reason    string                 `json:"reason,omitempty"`
+	Changes   map[string]FieldChange `json:"changes,omitempty"`
+	IP        string                 `json:"ip"`
+	UserAgent string                 `json:"user_agent"`
+	RequestID string                 `json:"request_id"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// ListAuditEventsRequest holds the paging and filter parameters of an audit log listing.
+type ListAuditEventsRequest struct {
+	// Cursor is the NextCursor of the previous page, empty for the first page.
+	Cursor   string
+	PageSize int
+	Event    string
+	Outcome  string
+	ActorID  uint
+	TargetID uint
+	// From and To limit the events to those created in [From, To).
+	From time.Time
+	To   time.Time
+}
+
+// AuditEventPage is one page of the audit log, newest events first.
+type AuditEventPage struct {
+	Events     []*AuditEvent `json:"events"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
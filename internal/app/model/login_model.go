@@ -1,9 +1,11 @@
 package model
 
 // LoginRequest represents the structure of a login request.
+// ClientCode is optional while the username is used by a single client.
 type LoginRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	ClientCode string `json:"client_code,omitempty"`
+	Username   string `json:"username"`
+	Password   string `json:"password"`
 }
 
 type LoginResponse struct {
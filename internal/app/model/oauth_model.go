@@ -0,0 +1,72 @@
+package model
+
+// TokenRequest represents an OAuth2 token request (RFC 6749 sections 4.1.3, 4.4.2 and 6).
+type TokenRequest struct {
+	GrantType    string
+	ClientID     string
+	ClientSecret string
+	Scope        string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+}
+
+// TokenResponse represents a successful OAuth2 token response.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// AuthorizeRequest represents an OAuth2 authorization request with PKCE (RFC 7636).
+type AuthorizeRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+}
+
+// OAuthErrorResponse represents an OAuth2 error response (RFC 6749 section 5.2).
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// ServiceIdentity describes the service principal behind a validated service token.
+type ServiceIdentity struct {
+	ClientID string   `json:"client_id"`
+	Name     string   `json:"name"`
+	Scopes   []string `json:"scopes"`
+}
+
+// HasScope reports whether the service identity was granted the given scope.
+func (s *ServiceIdentity) HasScope(scope string) bool {
+	for _, granted := range s.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IntrospectionResponse represents a token introspection response (RFC 7662 section 2.2).
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Expiry    int64  `json:"exp,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TenantID  uint   `json:"tenant_id,omitempty"`
+	Role      uint   `json:"role,omitempty"`
+	IsAdmin   bool   `json:"is_admin,omitempty"`
+}
@@ -1,10 +1,18 @@
 package model
 
+import "time"
+
+// AddUserRequest creates a user. With Invite the user sets its own password through an invitation
+// and Password must be left empty. Email and Phone are optional.
 type AddUserRequest struct {
 	Username string `json:"username" validate:"required"`
-	Password string `json:"password" validate:"required"`
+	Password string `json:"password" validate:"required_unless=Invite true"`
 	FullName string `json:"fullName" validate:"required"`
+	Email    string `json:"email,omitempty"`
+	Phone    string `json:"phone,omitempty"`
 	Role     uint   `json:"role" validate:"required"`
+	OutletID *uint  `json:"outlet_id,omitempty"`
+	Invite   bool   `json:"invite,omitempty"`
 }
 
 type EditUserRequest struct {
@@ -12,17 +20,147 @@ type EditUserRequest struct {
 	AddUserRequest
 }
 
+// PatchUserRequest holds the fields of a partial user update. Nil fields are left unchanged,
+// an OutletID of 0 removes the outlet assignment and an empty Email or Phone removes it.
+type PatchUserRequest struct {
+	Username *string `json:"username,omitempty"`
+	Password *string `json:"password,omitempty"`
+	FullName *string `json:"fullName,omitempty"`
+	Email    *string `json:"email,omitempty"`
+	Phone    *string `json:"phone,omitempty"`
+	Role     *uint   `json:"role,omitempty"`
+	OutletID *uint   `json:"outlet_id,omitempty"`
+}
+
+// UserStatusRequest is the body of the activate, deactivate and delete actions.
+type UserStatusRequest struct {
+	Reason string `json:"reason" validate:"max=255"`
+}
+
 type User struct {
-	ID       uint   `json:"id"`
-	ClientID uint   `json:"client_id"`
-	Username string `json:"username"`
-	FullName string `json:"fullName"`
-	Role     uint   `json:"role"`
-	IsAdmin  bool   `json:"is_admin"`
-	IsLogin  bool   `json:"is_login"`
+	ID            uint       `json:"id"`
+	ClientID      uint       `json:"client_id"`
+	Username      string     `json:"username"`
+	FullName      string     `json:"fullName"`
+	Email         string     `json:"email,omitempty"`
+	Phone         string     `json:"phone,omitempty"`
+	EmailVerified bool       `json:"email_verified"`
+	PhoneVerified bool       `json:"phone_verified"`
+	Role          uint       `json:"role"`
+	OutletID      *uint      `json:"outlet_id,omitempty"`
+	IsAdmin       bool       `json:"is_admin"`
+	IsActive      bool       `json:"is_active"`
+	StatusReason  string     `json:"status_reason,omitempty"`
+	IsLogin       bool       `json:"is_login"`
+	TokenExpired  *time.Time `json:"token_expired,omitempty"`
 }
 
 type GetUserResponse struct {
 	HTTPResponse
 	Data *[]User `json:"data,omitempty"`
 }
+
+// ListUsersRequest holds the paging, filter and sort parameters of a user listing.
+type ListUsersRequest struct {
+	// Cursor is the NextCursor of the previous page, empty for the first page.
+	Cursor   string
+	PageSize int
+	Role     uint
+	IsActive *bool
+	OutletID uint
+	// Search matches users whose username or full name starts with it, ignoring case.
+	Search string
+	// Sort is id, username, full_name or created_at, prefixed with - for descending order.
+	Sort string
+	// Deleted lists the deleted users instead of the others.
+	Deleted bool
+}
+
+// UserPage is one page of a user listing.
+type UserPage struct {
+	Users      []*User `json:"users"`
+	Total      int64   `json:"total"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// Profile is the profile of the caller, returned by GET /me.
+type Profile struct {
+	User
+	ClientName  string   `json:"client_name"`
+	Permissions []string `json:"permissions"`
+}
+
+// UserImportRow is the outcome of one row of a user import.
+type UserImportRow struct {
+	// Row is the line of the row in the CSV file, the header being line 1.
+	Row      int    `json:"row"`
+	Username string `json:"username"`
+	// Status is "ok" or "error".
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Password is the generated password of a row without one. It is only returned once, by the
+	// import that created the user.
+	Password string `json:"password,omitempty"`
+}
+
+// UserImportReport is the result of a user import. Users are only created when every row is valid
+// and DryRun is false.
+type UserImportReport struct {
+	DryRun   bool            `json:"dry_run"`
+	Imported int             `json:"imported"`
+	Failed   int             `json:"failed"`
+	Rows     []UserImportRow `json:"rows"`
+}
+
+// UserExportRow is a user as listed by the user export. It never carries credentials.
+type UserExportRow struct {
+	ID          uint       `json:"id"`
+	Username    string     `json:"username"`
+	FullName    string     `json:"full_name"`
+	Role        uint       `json:"role"`
+	RoleName    string     `json:"role_name"`
+	IsActive    bool       `json:"is_active"`
+	OutletID    *uint      `json:"outlet_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	Email       string     `json:"email"`
+	Phone       string     `json:"phone"`
+}
+
+// UserInvitation is an invitation to set the password of a pending user. Token is only returned
+// when the invitation is issued and cannot be retrieved afterwards.
+type UserInvitation struct {
+	ID         uint       `json:"id"`
+	UserID     uint       `json:"user_id"`
+	Username   string     `json:"username"`
+	Status     string     `json:"status"`
+	InvitedBy  uint       `json:"invited_by"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Token      string     `json:"token,omitempty"`
+}
+
+// VerificationRequest asks for a code to verify the email ("email") or phone ("phone") of the caller.
+type VerificationRequest struct {
+	Channel string `json:"channel" validate:"required,oneof=email phone"`
+}
+
+// ConfirmVerificationRequest verifies the email or phone of the caller with the code it received.
+type ConfirmVerificationRequest struct {
+	Channel string `json:"channel" validate:"required,oneof=email phone"`
+	Code    string `json:"code" validate:"required"`
+}
+
+// Verification is a verification code sent to the caller. The code itself is only sent to Target.
+type Verification struct {
+	Channel   string    `json:"channel"`
+	Target    string    `json:"target"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AcceptInvitationRequest sets the password of an invited user.
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
@@ -0,0 +1,148 @@
+// internal/repository/audit_repo.go
+
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"maqhaa/auth_service/internal/app/entity"
+	"maqhaa/library/logging"
+	"time"
+
+	"maqhaa/library/middleware"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// AuditRepository handles database interactions related to the audit log.
+type AuditRepository interface {
+	CreateAuditEvent(ctx context.Context, event *entity.AuditEvent) error
+	ListAuditEvents(ctx context.Context, query AuditQuery) ([]*entity.AuditEvent, error)
+}
+
+// AuditQuery selects a page of the audit events of the client of the context, newest first. Zero
+// fields do not filter. BeforeID continues right after the last event of the previous page.
+type AuditQuery struct {
+	Event    string
+	Outcome  string
+	ActorID  uint
+	TargetID uint
+	From     time.Time
+	To       time.Time
+	BeforeID uint
+	Limit    int
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{
+		db: db,
+	}
+}
+
+// CreateAuditEvent stores an audit event.
+func (r *auditRepository) CreateAuditEvent(ctx context.Context, event *entity.AuditEvent) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	result := r.db.Create(event)
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error CreateAuditEvent  %s", result.Error.Error())
+		return result.Error
+	}
+	return nil
+}
+
+// ListAuditEvents returns the audit events of the client of the context matching query.
+func (r *auditRepository) ListAuditEvents(ctx context.Context, query AuditQuery) ([]*entity.AuditEvent, error) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	tenant, err := requireTenant(ctx)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ListAuditEvents  %s", err.Error())
+		return nil, err
+	}
+
+	db := r.db.Scopes(tenant)
+	if query.Event != "" {
+		db = db.Where("event = ?", query.Event)
+	}
+	if query.Outcome != "" {
+		db = db.Where("outcome = ?", query.Outcome)
+	}
+	if query.ActorID != 0 {
+		db = db.Where("actor_id = ?", query.ActorID)
+	}
+	if query.TargetID != 0 {
+		db = db.Where("target_id = ?", query.TargetID)
+	}
+	if !query.From.IsZero() {
+		db = db.Where("created_at >= ?", query.From)
+	}
+	if !query.To.IsZero() {
+		db = db.Where("created_at < ?", query.To)
+	}
+	if query.BeforeID != 0 {
+		db = db.Where("id < ?", query.BeforeID)
+	}
+
+	var events []*entity.AuditEvent
+	result := db.Order("id DESC").Limit(query.Limit).Find(&events)
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ListAuditEvents  %s", result.Error.Error())
+		return nil, result.Error
+	}
+	return events, nil
+}
+
+// auditPersonalFields are the changed fields of an audit event holding personal data.
+var auditPersonalFields = []string{"username", "full_name", "email", "phone"}
+
+// eraseAuditEvents masks the personal data of an erased user in the audit events of its client:
+// the username given to its logins, the values of its personal fields in the changes made to it and
+// the IP and user agent of what it did itself. The events themselves are kept.
+func eraseAuditEvents(tx *gorm.DB, tenant func(*gorm.DB) *gorm.DB, userID uint, username string) error {
+	var events []*entity.AuditEvent
+	result := tx.Scopes(tenant).Where("(target_id = ? OR actor_id = ? OR (actor_id IS NULL AND username = ?))", userID, userID, username).
+		Find(&events)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	redacted := map[string]string{"old": entity.AuditRedactedValue, "new": entity.AuditRedactedValue}
+	for _, event := range events {
+		updates := map[string]interface{}{}
+		if event.Username != "" {
+			updates["username"] = entity.ErasedUsername(userID)
+		}
+		if event.ActorID == nil || *event.ActorID == userID {
+			updates["ip"] = ""
+			updates["user_agent"] = ""
+		}
+		if event.Changes != "" && event.TargetID != nil && *event.TargetID == userID {
+			var changes map[string]interface{}
+			if err := json.Unmarshal([]byte(event.Changes), &changes); err != nil {
+				return err
+			}
+			for _, field := range auditPersonalFields {
+				if _, ok := changes[field]; ok {
+					changes[field] = redacted
+				}
+			}
+			masked, err := json.Marshal(changes)
+			if err != nil {
+				return err
+			}
+			updates["changes"] = string(masked)
+		}
+		if len(updates) == 0 {
+			continue
+		}
+		if err := tx.Model(event).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
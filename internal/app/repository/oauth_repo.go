@@ -0,0 +1,156 @@
+// internal/repository/oauth_repo.go
+
+package repository
+
+import (
+	"context"
+	"maqhaa/auth_service/internal/app/entity"
+	"maqhaa/library/logging"
+	"time"
+
+	"maqhaa/library/middleware"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// OAuthRepository handles database interactions related to OAuth2 clients and tokens.
+type OAuthRepository interface {
+	GetClientByClientID(ctx context.Context, clientID string) (*entity.OAuthClient, error)
+	CreateToken(ctx context.Context, token *entity.OAuthToken) error
+	GetToken(ctx context.Context, token string) (*entity.OAuthToken, error)
+	CreateAuthorizationCode(ctx context.Context, code *entity.OAuthAuthorizationCode) error
+	GetAuthorizationCode(ctx context.Context, code string) (*entity.OAuthAuthorizationCode, error)
+	MarkAuthorizationCodeUsed(ctx context.Context, ID uint) (bool, error)
+	RevokeToken(ctx context.Context, ID uint) error
+	ConsumeToken(ctx context.Context, ID uint) (bool, error)
+	RevokeUserTokens(ctx context.Context, clientID string, userID uint) error
+}
+
+type oauthRepository struct {
+	db *gorm.DB
+}
+
+func NewOAuthRepository(db *gorm.DB) OAuthRepository {
+	return &oauthRepository{
+		db: db,
+	}
+}
+
+// GetClientByClientID retrieves a registered OAuth2 client by its public client_id.
+func (r *oauthRepository) GetClientByClientID(ctx context.Context, clientID string) (*entity.OAuthClient, error) {
+	var client entity.OAuthClient
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	result := r.db.Where("client_id = ?", clientID).First(&client)
+	if result.Error != nil {
+		if result.Error.Error() != "record not found" {
+			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetClientByClientID  %s", result.Error.Error())
+		}
+		return nil, result.Error
+	}
+	return &client, nil
+}
+
+// CreateToken stores a newly issued token.
+func (r *oauthRepository) CreateToken(ctx context.Context, token *entity.OAuthToken) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	result := r.db.Create(token)
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error CreateToken  %s", result.Error.Error())
+		return result.Error
+	}
+	return nil
+}
+
+// GetToken retrieves an issued token by its value.
+func (r *oauthRepository) GetToken(ctx context.Context, token string) (*entity.OAuthToken, error) {
+	var oauthToken entity.OAuthToken
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	result := r.db.Where("token = ?", token).First(&oauthToken)
+	if result.Error != nil {
+		if result.Error.Error() != "record not found" {
+			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetToken  %s", result.Error.Error())
+		}
+		return nil, result.Error
+	}
+	return &oauthToken, nil
+}
+
+// CreateAuthorizationCode stores a newly issued authorization code.
+func (r *oauthRepository) CreateAuthorizationCode(ctx context.Context, code *entity.OAuthAuthorizationCode) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	result := r.db.Create(code)
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error CreateAuthorizationCode  %s", result.Error.Error())
+		return result.Error
+	}
+	return nil
+}
+
+// GetAuthorizationCode retrieves an authorization code by its value.
+func (r *oauthRepository) GetAuthorizationCode(ctx context.Context, code string) (*entity.OAuthAuthorizationCode, error) {
+	var authorizationCode entity.OAuthAuthorizationCode
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	result := r.db.Where("code = ?", code).First(&authorizationCode)
+	if result.Error != nil {
+		if result.Error.Error() != "record not found" {
+			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetAuthorizationCode  %s", result.Error.Error())
+		}
+		return nil, result.Error
+	}
+	return &authorizationCode, nil
+}
+
+// MarkAuthorizationCodeUsed marks a code as redeemed. It returns false when the code
+// had already been redeemed, so a code can only be exchanged once even under concurrent requests.
+func (r *oauthRepository) MarkAuthorizationCodeUsed(ctx context.Context, ID uint) (bool, error) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	result := r.db.Model(&entity.OAuthAuthorizationCode{}).
+		Where("id = ? AND used_at IS NULL", ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error MarkAuthorizationCodeUsed  %s", result.Error.Error())
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeToken marks a token as revoked.
+func (r *oauthRepository) RevokeToken(ctx context.Context, ID uint) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	result := r.db.Model(&entity.OAuthToken{}).
+		Where("id = ? AND revoked_at IS NULL", ID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error RevokeToken  %s", result.Error.Error())
+		return result.Error
+	}
+	return nil
+}
+
+// ConsumeToken revokes a token that is used once, such as a rotated refresh token. It returns false
+// when the token had already been revoked, so it can only be used once even under concurrent requests.
+func (r *oauthRepository) ConsumeToken(ctx context.Context, ID uint) (bool, error) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	result := r.db.Model(&entity.OAuthToken{}).
+		Where("id = ? AND revoked_at IS NULL", ID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ConsumeToken  %s", result.Error.Error())
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeUserTokens marks every token a client holds on behalf of a user as revoked.
+func (r *oauthRepository) RevokeUserTokens(ctx context.Context, clientID string, userID uint) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	result := r.db.Model(&entity.OAuthToken{}).
+		Where("client_id = ? AND user_id = ? AND revoked_at IS NULL", clientID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error RevokeUserTokens  %s", result.Error.Error())
+		return result.Error
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+// internal/repository/tenant.go
+
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type tenantKey struct{}
+
+// ErrNoTenant is returned by queries that must be tenant scoped when the context carries no client.
+var ErrNoTenant = errors.New("no tenant in context")
+
+// WithTenant returns a context scoping the user queries run with it to the users of clientID.
+func WithTenant(ctx context.Context, clientID uint) context.Context {
+	return context.WithValue(ctx, tenantKey{}, clientID)
+}
+
+// TenantFromContext returns the client the queries of ctx are scoped to.
+func TenantFromContext(ctx context.Context) (uint, bool) {
+	clientID, ok := ctx.Value(tenantKey{}).(uint)
+	return clientID, ok && clientID != 0
+}
+
+// tenantScope restricts a query to the client of ctx, if any. Lookups made before the caller is
+// known (login, token resolution) run without a tenant.
+func tenantScope(ctx context.Context) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if clientID, ok := TenantFromContext(ctx); ok {
+			return db.Where("client_id = ?", clientID)
+		}
+		return db
+	}
+}
+
+// requireTenant is tenantScope for queries that must never run across clients.
+func requireTenant(ctx context.Context) (func(*gorm.DB) *gorm.DB, error) {
+	if _, ok := TenantFromContext(ctx); !ok {
+		return nil, ErrNoTenant
+	}
+	return tenantScope(ctx), nil
+}
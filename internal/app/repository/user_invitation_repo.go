@@ -0,0 +1,173 @@
+// internal/repository/user_invitation_repo.go
+
+package repository
+
+import (
+	"context"
+	"maqhaa/auth_service/internal/app/entity"
+	"maqhaa/library/logging"
+	"time"
+
+	"maqhaa/library/middleware"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// pendingUser selects the users an invitation may still activate: users without a password that
+// an admin has not deactivated. Invited users are created inactive with no status change, so an
+// inactive user with a recorded status change was deactivated, or deleted and restored, on purpose.
+const pendingUser = "password = '' AND (is_active OR status_changed_by IS NULL)"
+
+// CreateInvitedUser creates a pending user and its invitation in one transaction.
+func (r *userRepository) CreateInvitedUser(ctx context.Context, user *entity.User, invitation *entity.UserInvitation) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		invitation.UserID = user.ID
+		return tx.Create(invitation).Error
+	})
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error CreateInvitedUser  %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// ReplaceInvitation issues a new invitation to a pending user of the client of the context and
+// revokes the ones still open. It returns gorm.ErrRecordNotFound when the user does not exist in
+// that client, has already set a password or was deactivated.
+func (r *userRepository) ReplaceInvitation(ctx context.Context, invitation *entity.UserInvitation) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	tenant, err := requireTenant(ctx)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ReplaceInvitation  %s", err.Error())
+		return err
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		var pending int64
+		result := tx.Model(&entity.User{}).Scopes(tenant).Where("id = ?", invitation.UserID).Where(pendingUser).Count(&pending)
+		if result.Error != nil {
+			return result.Error
+		}
+		if pending == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := revokeOpenInvitations(tx, invitation.UserID, time.Now()); err != nil {
+			return err
+		}
+		return tx.Create(invitation).Error
+	})
+	if err != nil && err != gorm.ErrRecordNotFound {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ReplaceInvitation  %s", err.Error())
+	}
+	return err
+}
+
+// ListInvitations returns the invitations of the client of the context with their user, newest first.
+func (r *userRepository) ListInvitations(ctx context.Context) ([]*entity.UserInvitation, error) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	tenant, err := requireTenant(ctx)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ListInvitations  %s", err.Error())
+		return nil, err
+	}
+
+	var invitations []*entity.UserInvitation
+	result := r.db.Scopes(tenant).Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Order("id DESC").Find(&invitations)
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ListInvitations  %s", result.Error.Error())
+		return nil, result.Error
+	}
+	return invitations, nil
+}
+
+// RevokeInvitation revokes an open invitation of the client of the context. It returns
+// gorm.ErrRecordNotFound when no such invitation exists in that client or it was already used or
+// revoked.
+func (r *userRepository) RevokeInvitation(ctx context.Context, ID uint) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	tenant, err := requireTenant(ctx)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error RevokeInvitation  %s", err.Error())
+		return err
+	}
+
+	result := r.db.Model(&entity.UserInvitation{}).Scopes(tenant).Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", ID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error RevokeInvitation  %s", result.Error.Error())
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetInvitationByTokenHash retrieves an invitation by the hash of its token, with its user. The
+// user is left empty when it has been deleted.
+func (r *userRepository) GetInvitationByTokenHash(ctx context.Context, tokenHash string) (*entity.UserInvitation, error) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	var invitation entity.UserInvitation
+	result := r.db.Preload("User").Where("token_hash = ?", tokenHash).First(&invitation)
+	if result.Error != nil {
+		if result.Error.Error() != "record not found" {
+			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetInvitationByTokenHash  %s", result.Error.Error())
+		}
+		return nil, result.Error
+	}
+	return &invitation, nil
+}
+
+// AcceptInvitation uses an invitation: it is marked accepted and its user gets the password and is
+// activated, in one transaction. It returns gorm.ErrRecordNotFound when the invitation is no longer
+// open or its user has been deleted or deactivated, so a token is only ever used once and cannot
+// bring back a user an admin disabled.
+func (r *userRepository) AcceptInvitation(ctx context.Context, invitation *entity.UserInvitation, password string) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	now := time.Now()
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.UserInvitation{}).
+			Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", invitation.ID, now).
+			Update("accepted_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		result = tx.Model(&entity.User{}).Where("id = ?", invitation.UserID).Where(pendingUser).Updates(map[string]interface{}{
+			"password":          password,
+			"is_active":         true,
+			"status_changed_at": now,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil && err != gorm.ErrRecordNotFound {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error AcceptInvitation  %s", err.Error())
+	}
+	return err
+}
+
+// revokeOpenInvitations revokes the invitations of a user that are still open.
+func revokeOpenInvitations(tx *gorm.DB, userID uint, at interface{}) error {
+	return tx.Model(&entity.UserInvitation{}).Where("user_id = ? AND accepted_at IS NULL AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
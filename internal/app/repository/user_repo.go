@@ -4,9 +4,12 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"maqhaa/auth_service/internal/app/entity"
 	"maqhaa/library/logging"
+	"strings"
+	"time"
 
 	"maqhaa/library/middleware"
 
@@ -14,19 +17,74 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrAmbiguousUsername is returned by username lookups made without a client when several clients
+// have a user with that username.
+var ErrAmbiguousUsername = errors.New("username used by several clients")
+
 // UserRepository handles database interactions related to users.
 type UserRepository interface {
 	CreateUser(ctx context.Context, user *entity.User) error
+	CreateUsers(ctx context.Context, users []*entity.User) error
+	ExistingUsernames(ctx context.Context, usernames []string) ([]string, error)
 	GetUserByID(ctx context.Context, userID uint) (*entity.User, error)
 	GetUserByToken(ctx context.Context, token string) (*entity.User, error)
 	GetUserByUsername(ctx context.Context, username string) (*entity.User, error)
+	GetUserByClientCode(ctx context.Context, clientCode, username string) (*entity.User, error)
 	UpdateUser(ctx context.Context, user *entity.User) error
+	UpdateUserFields(ctx context.Context, ID uint, fields map[string]interface{}) error
+	UpdateUserToken(ctx context.Context, user *entity.User) error
+	RevokeUserToken(ctx context.Context, token string) (bool, error)
 	GetClientByToken(ctx context.Context, token string) (*entity.Client, error)
+	GetClientByID(ctx context.Context, clientID uint) (*entity.Client, error)
+	GetClientByCode(ctx context.Context, code string) (*entity.Client, error)
 	GetAllUserByClientID(ctx context.Context, clientID int) ([]*entity.User, error)
-	DeactivateUser(ctx context.Context, ID uint) error
+	ListUsers(ctx context.Context, query UserQuery) ([]*entity.User, int64, error)
+	EachUser(ctx context.Context, batchSize int, fn func(users []*entity.User) error) error
+	SetUserActive(ctx context.Context, ID uint, active bool, change UserStatusChange) error
+	DeleteUser(ctx context.Context, ID uint, change UserStatusChange) error
+	RestoreUser(ctx context.Context, ID uint, change UserStatusChange) error
+	EraseUser(ctx context.Context, ID uint, change UserStatusChange) error
+	CreateInvitedUser(ctx context.Context, user *entity.User, invitation *entity.UserInvitation) error
+	ReplaceInvitation(ctx context.Context, invitation *entity.UserInvitation) error
+	ListInvitations(ctx context.Context) ([]*entity.UserInvitation, error)
+	RevokeInvitation(ctx context.Context, ID uint) error
+	GetInvitationByTokenHash(ctx context.Context, tokenHash string) (*entity.UserInvitation, error)
+	AcceptInvitation(ctx context.Context, invitation *entity.UserInvitation, password string) error
+	ContactInUse(ctx context.Context, channel, value string, exceptID uint) (bool, error)
+	ReplaceVerification(ctx context.Context, verification *entity.UserVerification) error
+	GetOpenVerification(ctx context.Context, userID uint, channel string) (*entity.UserVerification, error)
+	UseVerificationAttempt(ctx context.Context, ID uint, max int) (bool, error)
+	ConfirmVerification(ctx context.Context, verification *entity.UserVerification) error
 	// Add other user-related methods as needed
 }
 
+// UserQuery selects a page of the users of the client of the context. Rows are ordered by SortColumn then id, and
+// After continues right after the last row of the previous page (keyset pagination).
+type UserQuery struct {
+	Role       uint
+	IsActive   *bool
+	OutletID   uint
+	Search     string
+	Deleted    bool
+	SortColumn string
+	Descending bool
+	After      *UserCursor
+	Limit      int
+}
+
+// UserStatusChange is who changes the status of a user (activation, deactivation, deletion or
+// restore) and why.
+type UserStatusChange struct {
+	ActorID uint
+	Reason  string
+}
+
+// UserCursor is the position of the last row of a page: its SortColumn value and its id.
+type UserCursor struct {
+	Value interface{}
+	ID    uint
+}
+
 // Implement the interface in the UserRepository struct
 type userRepository struct {
 	db *gorm.DB
@@ -42,7 +100,7 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 func (r *userRepository) GetAllUserByClientID(ctx context.Context, clientID int) ([]*entity.User, error) {
 	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
 	var users []*entity.User
-	result := r.db.Where("client_id = ?", clientID).Find(&users)
+	result := r.db.Scopes(tenantScope(ctx)).Where("client_id = ?", clientID).Find(&users)
 	if result.Error != nil {
 		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetAllUserByClientID  %s", result.Error.Error())
 		return nil, result.Error
@@ -50,6 +108,103 @@ func (r *userRepository) GetAllUserByClientID(ctx context.Context, clientID int)
 	return users, nil
 }
 
+// ListUsers returns a page of the users matching query and the number of users matching its filters.
+// SortColumn must be a column name checked by the caller. Deleted lists the deleted users instead,
+// leaving out erased users since they can no longer be restored.
+func (r *userRepository) ListUsers(ctx context.Context, query UserQuery) ([]*entity.User, int64, error) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	tenant, err := requireTenant(ctx)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ListUsers  %s", err.Error())
+		return nil, 0, err
+	}
+
+	filters := func(db *gorm.DB) *gorm.DB {
+		db = db.Scopes(tenant)
+		if query.Deleted {
+			db = db.Where("deleted_at IS NOT NULL AND erased_at IS NULL")
+		}
+		if query.Role != 0 {
+			db = db.Where("role = ?", query.Role)
+		}
+		if query.IsActive != nil {
+			db = db.Where("is_active = ?", *query.IsActive)
+		}
+		if query.OutletID != 0 {
+			db = db.Where("outlet_id = ?", query.OutletID)
+		}
+		if query.Search != "" {
+			prefix := likeEscaper.Replace(strings.ToLower(query.Search)) + "%"
+			db = db.Where("(LOWER(username) LIKE ? OR LOWER(full_name) LIKE ?)", prefix, prefix)
+		}
+		return db
+	}
+
+	base := r.db
+	if query.Deleted {
+		base = base.Unscoped()
+	}
+
+	var total int64
+	result := base.Model(&entity.User{}).Scopes(filters).Count(&total)
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ListUsers  %s", result.Error.Error())
+		return nil, 0, result.Error
+	}
+
+	direction, operator := "ASC", ">"
+	if query.Descending {
+		direction, operator = "DESC", "<"
+	}
+
+	db := base.Scopes(filters)
+	if query.After != nil {
+		if query.SortColumn == "id" {
+			db = db.Where(fmt.Sprintf("id %s ?", operator), query.After.ID)
+		} else {
+			db = db.Where(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", query.SortColumn, operator),
+				query.After.Value, query.After.Value, query.After.ID)
+		}
+	}
+	if query.SortColumn != "id" {
+		db = db.Order(query.SortColumn + " " + direction)
+	}
+
+	var users []*entity.User
+	result = db.Order("id " + direction).Limit(query.Limit).Find(&users)
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ListUsers  %s", result.Error.Error())
+		return nil, 0, result.Error
+	}
+	return users, total, nil
+}
+
+// EachUser calls fn with the users of the client of the context, batchSize at a time in id order,
+// until fn returns an error. Deleted users are left out.
+func (r *userRepository) EachUser(ctx context.Context, batchSize int, fn func(users []*entity.User) error) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	tenant, err := requireTenant(ctx)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error EachUser  %s", err.Error())
+		return err
+	}
+
+	var users []*entity.User
+	result := r.db.Scopes(tenant).Order("id").FindInBatches(&users, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(users)
+	})
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error EachUser  %s", result.Error.Error())
+		return result.Error
+	}
+	return nil
+}
+
+// likeEscaper escapes the LIKE wildcards of a search term.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 // CreateUser creates a new user in the database.
 func (r *userRepository) CreateUser(ctx context.Context, user *entity.User) error {
 	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
@@ -61,11 +216,46 @@ func (r *userRepository) CreateUser(ctx context.Context, user *entity.User) erro
 	return nil
 }
 
-// GetUserByID retrieves a user by ID from the database.
+// CreateUsers creates several users in one transaction: either all of them are created or none.
+func (r *userRepository) CreateUsers(ctx context.Context, users []*entity.User) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(users, 100).Error
+	})
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error CreateUsers  %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// ExistingUsernames returns which of usernames are taken in the client of the context, by any user
+// including deleted ones.
+func (r *userRepository) ExistingUsernames(ctx context.Context, usernames []string) ([]string, error) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	var existing []string
+	if len(usernames) == 0 {
+		return existing, nil
+	}
+	tenant, err := requireTenant(ctx)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ExistingUsernames  %s", err.Error())
+		return nil, err
+	}
+	result := r.db.Unscoped().Model(&entity.User{}).Scopes(tenant).Where("username IN ?", usernames).Pluck("username", &existing)
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ExistingUsernames  %s", result.Error.Error())
+		return nil, result.Error
+	}
+	return existing, nil
+}
+
+// GetUserByID retrieves a user by ID from the database. Users of another client than the one of
+// the context are not found.
 func (r *userRepository) GetUserByID(ctx context.Context, userID uint) (*entity.User, error) {
 	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
 	var user entity.User
-	result := r.db.First(&user, userID)
+	result := r.db.Scopes(tenantScope(ctx)).First(&user, userID)
 	if result.Error != nil {
 		if result.Error.Error() != "record not found" {
 			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetUserByID  %s", result.Error.Error())
@@ -88,47 +278,290 @@ func (r *userRepository) GetUserByToken(ctx context.Context, token string) (*ent
 	return &user, nil
 }
 
+// GetUserByUsername retrieves a user by username in the client of the context. Without a client in
+// the context the username is looked up across clients, and ErrAmbiguousUsername is returned when
+// more than one client uses it.
 func (r *userRepository) GetUserByUsername(ctx context.Context, username string) (*entity.User, error) {
+	var users []entity.User
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	result := r.db.Scopes(tenantScope(ctx)).Where("username = ?", username).Order("id").Limit(2).Find(&users)
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetUserByUsername  %s", result.Error.Error())
+		return nil, result.Error
+	}
+	if len(users) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if len(users) > 1 {
+		return nil, ErrAmbiguousUsername
+	}
+	return &users[0], nil
+}
+
+// GetUserByClientCode retrieves a user by username in the client identified by clientCode.
+func (r *userRepository) GetUserByClientCode(ctx context.Context, clientCode, username string) (*entity.User, error) {
 	var user entity.User
 	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
-	result := r.db.Where("username = ?", username).First(&user)
+	client := r.db.Model(&entity.Client{}).Select("id").Where("code = ?", clientCode)
+	result := r.db.Where("client_id = (?) AND username = ?", client, username).First(&user)
 	if result.Error != nil {
 		if result.Error.Error() != "record not found" {
-			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetUserByUsername  %s", result.Error.Error())
+			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetUserByClientCode  %s", result.Error.Error())
 		}
 		return nil, result.Error
 	}
 	return &user, nil
 }
 
+// UpdateUser updates a user of the client of the context. It returns gorm.ErrRecordNotFound when
+// the user does not exist in that client.
 func (r *userRepository) UpdateUser(ctx context.Context, user *entity.User) error {
-	fmt.Println(user)
-	result := r.db.Model(user).Updates(user)
 	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	tenant, err := requireTenant(ctx)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error UpdateUser  %s", err.Error())
+		return err
+	}
+	result := r.db.Model(user).Scopes(tenant).Updates(user)
 	if result.Error != nil {
 		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error UpdateUser  %s", result.Error.Error())
 		return result.Error
 	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// UpdateUserFields updates only the given columns of a user of the client of the context, zero
+// values included. It returns gorm.ErrRecordNotFound when the user does not exist in that client.
+func (r *userRepository) UpdateUserFields(ctx context.Context, ID uint, fields map[string]interface{}) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	tenant, err := requireTenant(ctx)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error UpdateUserFields  %s", err.Error())
+		return err
+	}
+	result := r.db.Model(&entity.User{}).Scopes(tenant).Where("id = ?", ID).Updates(fields)
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error UpdateUserFields  %s", result.Error.Error())
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
 	return nil
 }
 
-func (r *userRepository) DeactivateUser(ctx context.Context, ID uint) error {
+// UpdateUserToken stores a newly issued login token and the login time, and clears any previous revocation.
+func (r *userRepository) UpdateUserToken(ctx context.Context, user *entity.User) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	result := r.db.Model(user).Select("token", "token_expired", "token_revoked_at", "last_login_at").Updates(user)
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error UpdateUserToken  %s", result.Error.Error())
+		return result.Error
+	}
+	return nil
+}
 
+// RevokeUserToken erases a login token and marks it as revoked. It returns false when no user holds the token.
+func (r *userRepository) RevokeUserToken(ctx context.Context, token string) (bool, error) {
 	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
 
 	updates := map[string]interface{}{
-		"IsActive": false,
+		"token":            "",
+		"token_revoked_at": time.Now(),
 	}
 
-	// Perform the update operation
-	result := r.db.Model(&entity.User{}).Where("id = ?", ID).Updates(updates)
+	result := r.db.Model(&entity.User{}).Where("token = ?", token).Updates(updates)
 	if result.Error != nil {
-		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error UpdateUser  %s", result.Error.Error())
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error RevokeUserToken  %s", result.Error.Error())
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// SetUserActive activates or deactivates a user of the client of the context. Deactivation also
+// ends the login session of the user and revokes its OAuth2 tokens and open invitations. It returns
+// gorm.ErrRecordNotFound when the user does not exist in that client.
+func (r *userRepository) SetUserActive(ctx context.Context, ID uint, active bool, change UserStatusChange) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	tenant, err := requireTenant(ctx)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error SetUserActive  %s", err.Error())
+		return err
+	}
+
+	updates := statusUpdates(change)
+	updates["is_active"] = active
+	if !active {
+		updates["token"] = ""
+		updates["token_revoked_at"] = updates["status_changed_at"]
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.User{}).Scopes(tenant).Where("id = ?", ID).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if active {
+			return nil
+		}
+		if err := revokeOAuthTokens(tx, ID, updates["status_changed_at"]); err != nil {
+			return err
+		}
+		return revokeOpenInvitations(tx, ID, updates["status_changed_at"])
+	})
+	if err != nil && err != gorm.ErrRecordNotFound {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error SetUserActive  %s", err.Error())
+	}
+	return err
+}
+
+// DeleteUser soft deletes a user of the client of the context, ends its login session and revokes
+// its OAuth2 tokens and open invitations. It returns gorm.ErrRecordNotFound when the user does not
+// exist in that client or is already deleted.
+func (r *userRepository) DeleteUser(ctx context.Context, ID uint, change UserStatusChange) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	tenant, err := requireTenant(ctx)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error DeleteUser  %s", err.Error())
+		return err
+	}
+
+	updates := statusUpdates(change)
+	updates["deleted_at"] = updates["status_changed_at"]
+	updates["token"] = ""
+	updates["token_revoked_at"] = updates["status_changed_at"]
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.User{}).Scopes(tenant).Where("id = ?", ID).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := revokeOAuthTokens(tx, ID, updates["status_changed_at"]); err != nil {
+			return err
+		}
+		return revokeOpenInvitations(tx, ID, updates["status_changed_at"])
+	})
+	if err != nil && err != gorm.ErrRecordNotFound {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error DeleteUser  %s", err.Error())
+	}
+	return err
+}
+
+// RestoreUser restores a deleted user of the client of the context. It returns
+// gorm.ErrRecordNotFound when no deleted user with this ID exists in that client. Erased users
+// cannot be restored.
+func (r *userRepository) RestoreUser(ctx context.Context, ID uint, change UserStatusChange) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	tenant, err := requireTenant(ctx)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error RestoreUser  %s", err.Error())
+		return err
+	}
+
+	updates := statusUpdates(change)
+	updates["deleted_at"] = nil
+
+	result := r.db.Unscoped().Model(&entity.User{}).Scopes(tenant).Where("id = ? AND deleted_at IS NOT NULL AND erased_at IS NULL", ID).Updates(updates)
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error RestoreUser  %s", result.Error.Error())
 		return result.Error
 	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
 	return nil
 }
 
+// EraseUser removes the personal data of a user of the client of the context for good: the username
+// and full name are anonymized, the password hash and the login session are cleared and its OAuth2
+// tokens, authorization codes and invitations are deleted and its personal data is masked in the
+// audit log. The row stays, deleted, so references to the user id remain valid. Deleted users can be erased; erased users are not found.
+func (r *userRepository) EraseUser(ctx context.Context, ID uint, change UserStatusChange) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	tenant, err := requireTenant(ctx)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error EraseUser  %s", err.Error())
+		return err
+	}
+
+	updates := statusUpdates(change)
+	now := updates["status_changed_at"]
+	updates["username"] = entity.ErasedUsername(ID)
+	updates["full_name"] = ""
+	updates["email"] = ""
+	updates["phone"] = ""
+	updates["email_verified_at"] = nil
+	updates["phone_verified_at"] = nil
+	updates["password"] = ""
+	updates["last_login_at"] = nil
+	updates["token"] = ""
+	updates["token_revoked_at"] = now
+	updates["is_active"] = false
+	updates["deleted_at"] = gorm.Expr("COALESCE(deleted_at, ?)", now)
+	updates["erased_at"] = now
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		var user entity.User
+		result := tx.Unscoped().Scopes(tenant).Select("id", "username").Where("id = ? AND erased_at IS NULL", ID).Take(&user)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		result = tx.Unscoped().Model(&entity.User{}).Scopes(tenant).Where("id = ? AND erased_at IS NULL", ID).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := eraseAuditEvents(tx, tenant, ID, user.Username); err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", ID).Delete(&entity.OAuthToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", ID).Delete(&entity.UserInvitation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", ID).Delete(&entity.UserVerification{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", ID).Delete(&entity.OAuthAuthorizationCode{}).Error
+	})
+	if err != nil && err != gorm.ErrRecordNotFound {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error EraseUser  %s", err.Error())
+	}
+	return err
+}
+
+// statusUpdates returns the columns recording a status change.
+func statusUpdates(change UserStatusChange) map[string]interface{} {
+	return map[string]interface{}{
+		"status_reason":     change.Reason,
+		"status_changed_by": change.ActorID,
+		"status_changed_at": time.Now(),
+	}
+}
+
+// revokeOAuthTokens revokes the OAuth2 access and refresh tokens of a user that are not revoked yet.
+func revokeOAuthTokens(tx *gorm.DB, userID uint, at interface{}) error {
+	return tx.Model(&entity.OAuthToken{}).Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
+
 func (r *userRepository) GetClientByToken(ctx context.Context, token string) (*entity.Client, error) {
 	var client entity.Client
 	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
@@ -141,3 +574,31 @@ func (r *userRepository) GetClientByToken(ctx context.Context, token string) (*e
 	}
 	return &client, nil
 }
+
+// GetClientByCode retrieves the client (tenant) with a login code.
+func (r *userRepository) GetClientByCode(ctx context.Context, code string) (*entity.Client, error) {
+	var client entity.Client
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	result := r.db.Where("code = ?", code).First(&client)
+	if result.Error != nil {
+		if result.Error.Error() != "record not found" {
+			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetClientByCode  %s", result.Error.Error())
+		}
+		return nil, result.Error
+	}
+	return &client, nil
+}
+
+// GetClientByID retrieves the client (tenant) a user belongs to.
+func (r *userRepository) GetClientByID(ctx context.Context, clientID uint) (*entity.Client, error) {
+	var client entity.Client
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	result := r.db.First(&client, clientID)
+	if result.Error != nil {
+		if result.Error.Error() != "record not found" {
+			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetClientByID  %s", result.Error.Error())
+		}
+		return nil, result.Error
+	}
+	return &client, nil
+}
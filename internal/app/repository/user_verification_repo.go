@@ -0,0 +1,141 @@
+// internal/repository/user_verification_repo.go
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"maqhaa/auth_service/internal/app/entity"
+	"maqhaa/library/logging"
+	"time"
+
+	"maqhaa/library/middleware"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// contactColumns are the user columns holding the address and verification time of a channel.
+var contactColumns = map[string][2]string{
+	entity.VerificationChannelEmail: {"email", "email_verified_at"},
+	entity.VerificationChannelPhone: {"phone", "phone_verified_at"},
+}
+
+// ContactInUse reports whether a user of the client of the context other than exceptID has value as
+// its email or phone, depending on channel. Deleted users keep their address until they are erased.
+func (r *userRepository) ContactInUse(ctx context.Context, channel, value string, exceptID uint) (bool, error) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	tenant, err := requireTenant(ctx)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ContactInUse  %s", err.Error())
+		return false, err
+	}
+	columns, ok := contactColumns[channel]
+	if !ok {
+		return false, fmt.Errorf("unknown verification channel %q", channel)
+	}
+
+	var count int64
+	result := r.db.Unscoped().Model(&entity.User{}).Scopes(tenant).
+		Where(columns[0]+" = ? AND id <> ?", value, exceptID).Count(&count)
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ContactInUse  %s", result.Error.Error())
+		return false, result.Error
+	}
+	return count > 0, nil
+}
+
+// ReplaceVerification stores a new verification code and discards the codes of the same user and
+// channel that were not confirmed, so only the latest code can be used.
+func (r *userRepository) ReplaceVerification(ctx context.Context, verification *entity.UserVerification) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND channel = ? AND verified_at IS NULL", verification.UserID, verification.Channel).
+			Delete(&entity.UserVerification{})
+		if result.Error != nil {
+			return result.Error
+		}
+		return tx.Create(verification).Error
+	})
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ReplaceVerification  %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// GetOpenVerification retrieves the unconfirmed verification code of a user of the client of the
+// context for a channel.
+func (r *userRepository) GetOpenVerification(ctx context.Context, userID uint, channel string) (*entity.UserVerification, error) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	tenant, err := requireTenant(ctx)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetOpenVerification  %s", err.Error())
+		return nil, err
+	}
+
+	var verification entity.UserVerification
+	result := r.db.Scopes(tenant).Where("user_id = ? AND channel = ? AND verified_at IS NULL", userID, channel).
+		Order("id DESC").First(&verification)
+	if result.Error != nil {
+		if result.Error.Error() != "record not found" {
+			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetOpenVerification  %s", result.Error.Error())
+		}
+		return nil, result.Error
+	}
+	return &verification, nil
+}
+
+// UseVerificationAttempt counts an attempt to confirm an open verification, in one statement so
+// concurrent attempts cannot exceed max. It reports false once max attempts were counted.
+func (r *userRepository) UseVerificationAttempt(ctx context.Context, ID uint, max int) (bool, error) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	result := r.db.Model(&entity.UserVerification{}).
+		Where("id = ? AND attempts < ? AND verified_at IS NULL", ID, max).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error UseVerificationAttempt  %s", result.Error.Error())
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ConfirmVerification marks a verification confirmed and its user verified for the channel, in one
+// transaction. It returns gorm.ErrRecordNotFound when the code is no longer open or expired, or the
+// address of the user changed since the code was sent, so a code is only ever used once.
+func (r *userRepository) ConfirmVerification(ctx context.Context, verification *entity.UserVerification) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	columns, ok := contactColumns[verification.Channel]
+	if !ok {
+		return fmt.Errorf("unknown verification channel %q", verification.Channel)
+	}
+
+	now := time.Now()
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.UserVerification{}).
+			Where("id = ? AND verified_at IS NULL AND expires_at > ?", verification.ID, now).
+			Update("verified_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		result = tx.Model(&entity.User{}).Where("id = ? AND "+columns[0]+" = ?", verification.UserID, verification.Target).
+			Update(columns[1], now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil && err != gorm.ErrRecordNotFound {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ConfirmVerification  %s", err.Error())
+	}
+	return err
+}
@@ -0,0 +1,325 @@
+// internal/service/audit.go
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maqhaa/auth_service/internal/app/entity"
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/repository"
+	"maqhaa/library/logging"
+	"net"
+	"strconv"
+	"strings"
+
+	"maqhaa/library/middleware"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Audit log events.
+const (
+	AuditEventLogin              = "login"
+	AuditEventLogout             = "logout"
+	AuditEventTokenRevoked       = "token_revoked"
+	AuditEventUserCreated        = "user_created"
+	AuditEventUserUpdated        = "user_updated"
+	AuditEventRoleChanged        = "role_changed"
+	AuditEventUserActivated      = "user_activated"
+	AuditEventUserDeactivated    = "user_deactivated"
+	AuditEventUserDeleted        = "user_deleted"
+	AuditEventUserRestored       = "user_restored"
+	AuditEventUserErased         = "user_erased"
+	AuditEventUserInvited        = "user_invited"
+	AuditEventInvitationRevoked  = "invitation_revoked"
+	AuditEventInvitationAccepted = "invitation_accepted"
+	AuditEventUsersExported      = "users_exported"
+	// AuditEventVerificationRequested is followed by email_verified or phone_verified once confirmed.
+	AuditEventVerificationRequested = "verification_requested"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
+// RequestMetadata describes the caller of a request for the audit log.
+type RequestMetadata struct {
+	IP        string
+	UserAgent string
+}
+
+// TrustedProxies are the addresses allowed to tell the address of the caller they forward, in
+// X-Forwarded-For. The header of any other caller is ignored, so it cannot forge the IP of the
+// audit log.
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies parses a list of IP addresses and CIDR ranges.
+func ParseTrustedProxies(values []string) (TrustedProxies, error) {
+	proxies := TrustedProxies{}
+	for _, value := range values {
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", value)
+			}
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			value = fmt.Sprintf("%s/%d", value, bits)
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %v", value, err)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+// contains reports whether ip is the address of a trusted proxy.
+func (p TrustedProxies) contains(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range p {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the caller of a request received from remoteIP with the
+// X-Forwarded-For values forwarded. The forwarded addresses are followed from the last one, added
+// by the nearest proxy, only while they are trusted proxies; remoteIP is returned when it is not one.
+func (p TrustedProxies) ClientIP(remoteIP string, forwarded []string) string {
+	if !p.contains(remoteIP) {
+		return remoteIP
+	}
+
+	var hops []string
+	for _, value := range forwarded {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+
+	ip := remoteIP
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip = hops[i]
+		if !p.contains(ip) {
+			break
+		}
+	}
+	return ip
+}
+
+type requestMetadataKey struct{}
+
+// WithRequestMetadata returns a copy of ctx carrying the metadata of the request.
+func WithRequestMetadata(ctx context.Context, metadata RequestMetadata) context.Context {
+	return context.WithValue(ctx, requestMetadataKey{}, metadata)
+}
+
+// requestMetadataFrom returns the request metadata of ctx, empty when there is none.
+func requestMetadataFrom(ctx context.Context) RequestMetadata {
+	metadata, _ := ctx.Value(requestMetadataKey{}).(RequestMetadata)
+	return metadata
+}
+
+// auditEntry is an event to record in the audit log. Actor is nil for anonymous callers, ClientID
+// defaults to the client of Actor and Outcome to success.
+type auditEntry struct {
+	Event    string
+	Outcome  string
+	Actor    *model.User
+	ClientID uint
+	TargetID uint
+	Username string
+	Reason   string
+	Changes  map[string]model.FieldChange
+}
+
+// actorID returns the id of the actor of the entry, 0 for an anonymous caller.
+func (entry auditEntry) actorID() uint {
+	if entry.Actor == nil {
+		return 0
+	}
+	return entry.Actor.ID
+}
+
+// recordAudit writes an event to the audit log and the service log. A failure to store the event
+// is logged only, the action it records has already been done.
+func (a *authServiceImpl) recordAudit(ctx context.Context, entry auditEntry) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	metadata := requestMetadataFrom(ctx)
+
+	event := &entity.AuditEvent{
+		ClientID:  entry.ClientID,
+		Event:     entry.Event,
+		Outcome:   entry.Outcome,
+		Username:  entry.Username,
+		Reason:    entry.Reason,
+		IP:        metadata.IP,
+		UserAgent: metadata.UserAgent,
+		RequestID: logID,
+	}
+	if event.Outcome == "" {
+		event.Outcome = entity.AuditOutcomeSuccess
+	}
+	if entry.Actor != nil {
+		actorID := entry.actorID()
+		event.ActorID = &actorID
+		if event.ClientID == 0 {
+			event.ClientID = entry.Actor.ClientID
+		}
+	}
+	if entry.TargetID != 0 {
+		targetID := entry.TargetID
+		event.TargetID = &targetID
+	}
+	if len(entry.Changes) > 0 {
+		changes, err := json.Marshal(entry.Changes)
+		if err != nil {
+			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error Marshal audit changes  %s", err.Error())
+		}
+		event.Changes = string(changes)
+	}
+
+	logging.Log.WithFields(logrus.Fields{
+		"request_id": logID,
+		"event":      event.Event,
+		"outcome":    event.Outcome,
+		"actor_id":   entry.actorID(),
+		"client_id":  event.ClientID,
+		"target_id":  entry.TargetID,
+		"ip":         event.IP,
+	}).Info("Audit event")
+
+	// The error is logged by the repository
+	_ = a.auditRepository.CreateAuditEvent(ctx, event)
+}
+
+// userChanges returns what setting the columns of fields changes on user, keyed by column. Columns
+// left unchanged are skipped and passwords are masked.
+func userChanges(user *entity.User, fields map[string]interface{}) map[string]model.FieldChange {
+	changes := map[string]model.FieldChange{}
+	for column, value := range fields {
+		var old interface{}
+		switch column {
+		case "username":
+			old = user.Username
+		case "full_name":
+			old = user.FullName
+		case "email":
+			old = user.Email
+		case "phone":
+			old = user.Phone
+		case "role":
+			old = user.Role
+		case "outlet_id":
+			if user.OutletID != nil {
+				old = *user.OutletID
+			}
+		case "password":
+			changes[column] = model.FieldChange{Old: entity.AuditRedactedValue, New: entity.AuditRedactedValue}
+			continue
+		default:
+			continue
+		}
+		if old != value {
+			changes[column] = model.FieldChange{Old: old, New: value}
+		}
+	}
+	return changes
+}
+
+// ListAuditEvents returns a page of the audit log of the caller's client (admin only), newest first.
+func (a *authServiceImpl) ListAuditEvents(ctx context.Context, request model.ListAuditEventsRequest, token string) (*model.AuditEventPage, AppError) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	user, appError := a.Authorize(ctx, token)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	if !user.IsLogin {
+		return nil, *NewInvalidTokenError()
+	}
+
+	if !user.IsAdmin {
+		return nil, *NewUserNotAllowError()
+	}
+
+	ctx = repository.WithTenant(ctx, user.ClientID)
+
+	if request.PageSize < 0 || request.PageSize > maxAuditPageSize {
+		return nil, *NewInvalidRequestError("Invalid page_size")
+	}
+	pageSize := request.PageSize
+	if pageSize == 0 {
+		pageSize = defaultAuditPageSize
+	}
+
+	if request.Outcome != "" && request.Outcome != entity.AuditOutcomeSuccess && request.Outcome != entity.AuditOutcomeFailure {
+		return nil, *NewInvalidRequestError("Invalid outcome")
+	}
+
+	query := repository.AuditQuery{
+		Event:    request.Event,
+		Outcome:  request.Outcome,
+		ActorID:  request.ActorID,
+		TargetID: request.TargetID,
+		From:     request.From,
+		To:       request.To,
+		// One more event than the page tells whether there is a next page
+		Limit: pageSize + 1,
+	}
+	if request.Cursor != "" {
+		beforeID, err := strconv.ParseUint(request.Cursor, 10, 64)
+		if err != nil || beforeID == 0 {
+			return nil, *NewInvalidRequestError("Invalid cursor")
+		}
+		query.BeforeID = uint(beforeID)
+	}
+
+	events, err := a.auditRepository.ListAuditEvents(ctx, query)
+	if err != nil {
+		return nil, *NewQueryDBError()
+	}
+
+	page := &model.AuditEventPage{Events: []*model.AuditEvent{}}
+	if len(events) > pageSize {
+		events = events[:pageSize]
+		page.NextCursor = strconv.FormatUint(uint64(events[pageSize-1].ID), 10)
+	}
+	for _, e := range events {
+		event := &model.AuditEvent{
+			ID:        e.ID,
+			Event:     e.Event,
+			Outcome:   e.Outcome,
+			ActorID:   e.ActorID,
+			TargetID:  e.TargetID,
+			Username:  e.Username,
+			Reason:    e.Reason,
+			IP:        e.IP,
+			UserAgent: e.UserAgent,
+			RequestID: e.RequestID,
+			CreatedAt: e.CreatedAt,
+		}
+		if e.Changes != "" {
+			if err := json.Unmarshal([]byte(e.Changes), &event.Changes); err != nil {
+				logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error Unmarshal audit changes  %s", err.Error())
+			}
+		}
+		page.Events = append(page.Events, event)
+	}
+
+	return page, *NewSuccessError()
+}
@@ -4,9 +4,12 @@ package service
 
 import (
 	"context"
+	"errors"
+	"io"
 	"maqhaa/auth_service/internal/app/entity"
 	"maqhaa/auth_service/internal/app/model"
 	"maqhaa/auth_service/internal/app/repository"
+	"maqhaa/auth_service/internal/notification"
 	"maqhaa/library/helper"
 	"maqhaa/library/logging"
 	"strings"
@@ -20,79 +23,193 @@ import (
 
 // AuthService handles user authentication and authorization.
 type AuthService interface {
-	Authenticate(ctx context.Context, username, password string) (*entity.User, AppError)
+	Authenticate(ctx context.Context, clientCode, username, password string) (*entity.User, AppError)
+	VerifyCredentials(ctx context.Context, clientCode, username, password string) (*entity.User, AppError)
 	Authorize(ctx context.Context, token string) (*model.User, AppError)
-	AddUser(ctx context.Context, request model.AddUserRequest, token string) AppError
+	AuthorizeUser(ctx context.Context, ID uint) (*model.User, AppError)
+	AddUser(ctx context.Context, request model.AddUserRequest, token string) (*model.UserInvitation, AppError)
+	ImportUsers(ctx context.Context, data io.Reader, dryRun bool, token string) (*model.UserImportReport, AppError)
 	EditUser(ctx context.Context, request model.EditUserRequest, token string) AppError
+	PatchUser(ctx context.Context, ID uint, request model.PatchUserRequest, token string) AppError
 	GetAllUser(ctx context.Context, token string) ([]*model.User, AppError)
-	DeactivateUser(ctx context.Context, ID uint, token string) AppError
+	ListUsers(ctx context.Context, request model.ListUsersRequest, token string) (*model.UserPage, AppError)
+	ExportUsers(ctx context.Context, token string, write func(rows []*model.UserExportRow) error) AppError
+	GetUserByID(ctx context.Context, ID uint, token string) (*model.User, AppError)
+	GetProfile(ctx context.Context, token string) (*model.Profile, AppError)
+	ActivateUser(ctx context.Context, ID uint, request model.UserStatusRequest, token string) AppError
+	DeactivateUser(ctx context.Context, ID uint, request model.UserStatusRequest, token string) AppError
+	DeleteUser(ctx context.Context, ID uint, request model.UserStatusRequest, token string) AppError
+	RestoreUser(ctx context.Context, ID uint, token string) AppError
+	EraseUser(ctx context.Context, ID uint, token string) AppError
+	ListInvitations(ctx context.Context, token string) ([]*model.UserInvitation, AppError)
+	RevokeInvitation(ctx context.Context, ID uint, token string) AppError
+	ResendInvitation(ctx context.Context, userID uint, token string) (*model.UserInvitation, AppError)
+	AcceptInvitation(ctx context.Context, request model.AcceptInvitationRequest) AppError
+	RequestVerification(ctx context.Context, request model.VerificationRequest, token string) (*model.Verification, AppError)
+	ConfirmVerification(ctx context.Context, request model.ConfirmVerificationRequest, token string) AppError
+	ListAuditEvents(ctx context.Context, request model.ListAuditEventsRequest, token string) (*model.AuditEventPage, AppError)
 	Logout(ctx context.Context, token string) AppError
+	RevokeToken(ctx context.Context, token string) AppError
+	WatchRevocations(cursor string) ([]RevocationEvent, <-chan RevocationEvent, func(), AppError)
 	// Add other authentication and authorization methods as needed
 }
 
 // authServiceImpl implements the AuthService interface
 type authServiceImpl struct {
-	userRepository repository.UserRepository
+	userRepository  repository.UserRepository
+	oauthRepository repository.OAuthRepository
+	auditRepository repository.AuditRepository
+	revocations     *RevocationBus
+	notifier        notification.Notifier
 }
 
-// NewAuthService creates a new AuthService instance.
-func NewAuthService(userRepository repository.UserRepository) AuthService {
+// NewAuthService creates a new AuthService instance. Authentication and administration events are
+// recorded in auditRepository and security events are sent to users through notifier.
+func NewAuthService(userRepository repository.UserRepository, oauthRepository repository.OAuthRepository, auditRepository repository.AuditRepository, notifier notification.Notifier) AuthService {
 	return &authServiceImpl{
-		userRepository: userRepository,
+		userRepository:  userRepository,
+		oauthRepository: oauthRepository,
+		auditRepository: auditRepository,
+		revocations:     NewRevocationBus(),
+		notifier:        notifier,
 	}
 }
 
-// Authenticate performs user authentication based on the provided username and password.
-func (a *authServiceImpl) Authenticate(ctx context.Context, username, password string) (*entity.User, AppError) {
+// Authenticate performs user authentication based on the provided username and password (see
+// VerifyCredentials) and starts a new login session, replacing the previous one. Every login is
+// recorded in the audit log, failed ones with the reason of the failure.
+func (a *authServiceImpl) Authenticate(ctx context.Context, clientCode, username, password string) (*entity.User, AppError) {
 	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
-	user, err := a.userRepository.GetUserByUsername(ctx, username)
+	user, appError := a.VerifyCredentials(ctx, clientCode, username, password)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	// Assuming you have a token generation function, replace generateToken with the actual implementation
+	token, err := helper.GenerateRandomString(16)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GenerateRandomString  %s", err.Error())
+		return nil, *NewGeneralSystemError()
+	}
+
+	// Update all fields of the user in the database
+	user.Token = token
+	user.TokenExpired = calculateTokenExpiration()
+	user.TokenRevokedAt = nil
+	loginAt := time.Now()
+	user.LastLoginAt = &loginAt
+
+	err = a.userRepository.UpdateUserToken(ctx, user)
+	if err != nil {
+		return nil, *NewUpdateQueryDBError()
+	}
+
+	a.recordAudit(ctx, auditEntry{
+		Event:    AuditEventLogin,
+		Actor:    &model.User{ID: user.ID, ClientID: user.ClientID},
+		TargetID: user.ID,
+		Username: username,
+	})
+
+	return user, *NewSuccessError()
+}
+
+// VerifyCredentials checks the username and password of a user, the username being looked up in
+// the client with code clientCode (see findLoginUser), without touching its login session.
+// Deleted users are not found and deactivated users are refused. Failures are recorded in the
+// audit log as failed logins.
+func (a *authServiceImpl) VerifyCredentials(ctx context.Context, clientCode, username, password string) (*entity.User, AppError) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	user, err := a.findLoginUser(ctx, clientCode, username)
 	if err != nil {
+		if errors.Is(err, repository.ErrAmbiguousUsername) {
+			return nil, a.recordLoginFailure(ctx, clientCode, username, nil, *NewClientCodeRequiredError())
+		}
 		if err.Error() != "record not found" {
 			return nil, *NewQueryDBError()
 		}
 	}
 
+	if user == nil {
+		return nil, a.recordLoginFailure(ctx, clientCode, username, nil, *NewUserNotFoundError())
+	}
+
 	// Check if the provided password matches the stored password
+	err = helper.CompareHashAndPassword(user.Password, password)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error CompareHashAndPassword  %s", err.Error())
+		return nil, a.recordLoginFailure(ctx, clientCode, username, user, *NewInvalidPasswordError())
+	}
+
+	if !user.IsActive {
+		return nil, a.recordLoginFailure(ctx, clientCode, username, user, *NewUserNotActiveError())
+	}
+
+	return user, *NewSuccessError()
+}
+
+// recordLoginFailure records a failed login of username in the audit log and returns appError.
+// user is the user found for username, nil when there is none; the failure is then recorded under
+// the client the login named by its client code (see findLoginUser), or without a client when it
+// named none, so admins see the usernames guessed in their client.
+func (a *authServiceImpl) recordLoginFailure(ctx context.Context, clientCode, username string, user *entity.User, appError AppError) AppError {
+	entry := auditEntry{
+		Event:    AuditEventLogin,
+		Outcome:  entity.AuditOutcomeFailure,
+		Username: username,
+		Reason:   appError.Message,
+	}
 	if user != nil {
-		err := helper.CompareHashAndPassword(user.Password, password)
-		if err != nil {
-			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error CompareHashAndPassword  %s", err.Error())
-			return nil, *NewInvalidPasswordError()
-		}
-		// Assuming you have a token generation function, replace generateToken with the actual implementation
-		token, err := helper.GenerateRandomString(16)
-		if err != nil {
-			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GenerateRandomString  %s", err.Error())
-			return nil, *NewGeneralSystemError()
+		entry.ClientID, entry.TargetID = user.ClientID, user.ID
+	} else {
+		entry.ClientID = a.loginClientID(ctx, clientCode, username)
+	}
+	a.recordAudit(ctx, entry)
+	return appError
+}
+
+// loginClientID returns the id of the client named by the client code of a login, given apart or
+// as username@clientcode, or 0 when the login names no known client.
+func (a *authServiceImpl) loginClientID(ctx context.Context, clientCode, username string) uint {
+	if clientCode == "" {
+		i := strings.LastIndex(username, "@")
+		if i <= 0 || i == len(username)-1 {
+			return 0
 		}
+		clientCode = username[i+1:]
+	}
+	// Unknown codes and errors, logged by the repository, leave the failure without a client
+	client, err := a.userRepository.GetClientByCode(ctx, strings.ToLower(clientCode))
+	if err != nil {
+		return 0
+	}
+	return client.ID
+}
 
-		// Update all fields of the user in the database
-		user.Token = token
-		user.TokenExpired = calculateTokenExpiration()
-		user.IsActive = true
+// findLoginUser finds the user logging in. Usernames are unique per client only: without a client
+// code the username can be written username@clientcode, and a username used by a single client is
+// still found without one so logins made before usernames were per client keep working.
+func (a *authServiceImpl) findLoginUser(ctx context.Context, clientCode, username string) (*entity.User, error) {
+	if clientCode != "" {
+		return a.userRepository.GetUserByClientCode(ctx, strings.ToLower(clientCode), username)
+	}
 
-		err = a.userRepository.UpdateUser(ctx, user)
-		if err != nil {
-			return nil, *NewUpdateQueryDBError()
+	if i := strings.LastIndex(username, "@"); i > 0 && i < len(username)-1 {
+		user, err := a.userRepository.GetUserByClientCode(ctx, strings.ToLower(username[i+1:]), username[:i])
+		if err == nil || err.Error() != "record not found" {
+			return user, err
 		}
-
-		return user, *NewSuccessError()
+		// Not a client code, the @ belongs to the username
 	}
 
-	return nil, *NewUserNotFoundError()
+	return a.userRepository.GetUserByUsername(ctx, username)
 }
 
 // Authorize performs user authorization based on the provided token.
 func (a *authServiceImpl) Authorize(ctx context.Context, token string) (*model.User, AppError) {
-	result, err := a.userRepository.GetUserByToken(ctx, token)
-	if err != nil {
-		if err.Error() != "record not found" {
-			return nil, *NewQueryDBError()
-		}
-	}
-
-	if result == nil {
-		return nil, *NewUserNotFoundError()
+	result, tokenExpired, appError := a.resolveToken(ctx, token)
+	if appError.Code != SuccessError {
+		return nil, appError
 	}
 
 	// Check if the user is active or any other authorization logic
@@ -101,79 +218,180 @@ func (a *authServiceImpl) Authorize(ctx context.Context, token string) (*model.U
 		return user, *NewUserNotActiveError()
 	}
 
-	if result.TokenExpired.Before(time.Now()) {
+	if tokenExpired.Before(time.Now()) {
 		user = &model.User{
 			IsLogin: false,
 		}
 		return user, *NewSuccessError()
 	}
 
-	user = &model.User{
-		ID:       result.ID,
-		ClientID: result.ClientID,
-		Username: result.Username,
-		FullName: result.FullName,
-		IsLogin:  true,
-		IsAdmin:  result.Role == entity.RoleAdminCode,
-	}
+	user = toUserModel(result)
+	user.IsLogin = true
+	user.TokenExpired = &tokenExpired
 
 	return user, *NewSuccessError()
 
 }
 
-func (a *authServiceImpl) AddUser(ctx context.Context, request model.AddUserRequest, token string) AppError {
+// AuthorizeUser checks the user a grant was issued to still may act, the way Authorize checks the
+// owner of a token: deleted users are not found and inactive users are refused.
+func (a *authServiceImpl) AuthorizeUser(ctx context.Context, ID uint) (*model.User, AppError) {
+	result, err := a.userRepository.GetUserByID(ctx, ID)
+	if err != nil {
+		if err.Error() != "record not found" {
+			return nil, *NewQueryDBError()
+		}
+		return nil, *NewUserNotFoundError()
+	}
+
+	if !result.IsActive {
+		return nil, *NewUserNotActiveError()
+	}
+
+	return toUserModel(result), *NewSuccessError()
+}
+
+// resolveToken finds the user owning a login token or an OAuth2 user access token,
+// together with the expiry of that token.
+func (a *authServiceImpl) resolveToken(ctx context.Context, token string) (*entity.User, time.Time, AppError) {
+	if token == "" {
+		return nil, time.Time{}, *NewUserNotFoundError()
+	}
+
+	result, err := a.userRepository.GetUserByToken(ctx, token)
+	if err != nil {
+		if err.Error() != "record not found" {
+			return nil, time.Time{}, *NewQueryDBError()
+		}
+	}
+
+	if result != nil {
+		return result, result.TokenExpired, *NewSuccessError()
+	}
+
+	oauthToken, err := a.oauthRepository.GetToken(ctx, token)
+	if err != nil {
+		if err.Error() != "record not found" {
+			return nil, time.Time{}, *NewQueryDBError()
+		}
+		return nil, time.Time{}, *NewUserNotFoundError()
+	}
+
+	if oauthToken.TokenType != entity.TokenTypeAccess || oauthToken.UserID == 0 {
+		return nil, time.Time{}, *NewUserNotFoundError()
+	}
+
+	if oauthToken.RevokedAt != nil {
+		return nil, time.Time{}, *NewInvalidTokenError()
+	}
+
+	result, err = a.userRepository.GetUserByID(ctx, oauthToken.UserID)
+	if err != nil {
+		if err.Error() != "record not found" {
+			return nil, time.Time{}, *NewQueryDBError()
+		}
+		return nil, time.Time{}, *NewUserNotFoundError()
+	}
+
+	return result, oauthToken.ExpiresAt, *NewSuccessError()
+}
+
+// AddUser creates a user in the caller's client (admin only). With Invite set the user is created
+// pending, without a password, and the returned invitation carries the token letting the invitee
+// set its own password; otherwise no invitation is returned.
+func (a *authServiceImpl) AddUser(ctx context.Context, request model.AddUserRequest, token string) (*model.UserInvitation, AppError) {
 	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
 	user, appError := a.Authorize(ctx, token)
 	if appError.Code != SuccessError {
-		return appError
+		return nil, appError
 	}
 
 	if !user.IsLogin {
-		return *NewInvalidTokenError()
+		return nil, *NewInvalidTokenError()
 	}
 
 	if !user.IsAdmin {
-		return *NewUserNotAllowError()
+		return nil, *NewUserNotAllowError()
 	}
 
+	ctx = repository.WithTenant(ctx, user.ClientID)
+
 	validate := validator.New()
 	if err := validate.Struct(request); err != nil {
-		return *NewInvalidRequestError(err.Error())
+		return nil, *NewInvalidRequestError(err.Error())
+	}
+
+	if request.Invite && request.Password != "" {
+		return nil, *NewInvalidRequestError("Invited users set their own password")
+	}
+
+	if !entity.IsValidRole(request.Role) {
+		return nil, *NewInvalidRequestError("Invalid role")
+	}
+
+	email, appError := normalizeEmail(request.Email)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+	phone, appError := normalizePhone(request.Phone)
+	if appError.Code != SuccessError {
+		return nil, appError
 	}
 
 	// Check for duplicate username
 	existingUser, err := a.userRepository.GetUserByUsername(ctx, request.Username)
 	if err != nil && err.Error() != "record not found" {
-		return *NewQueryDBError()
+		return nil, *NewQueryDBError()
 	}
 	if existingUser != nil {
-		return *NewDuplicateUserError()
+		return nil, *NewDuplicateUserError()
 	}
 
-	hashedPassword, err := helper.HashPassword(request.Password)
-	if err != nil {
-		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error HashPassword  %s", err.Error())
-		return *NewGeneralSystemError()
+	if appError := a.checkContact(ctx, entity.VerificationChannelEmail, email, 0); appError.Code != SuccessError {
+		return nil, appError
+	}
+	if appError := a.checkContact(ctx, entity.VerificationChannelPhone, phone, 0); appError.Code != SuccessError {
+		return nil, appError
 	}
+
 	newUser := &entity.User{
 		ClientID: user.ClientID,
 		Username: request.Username,
-		Password: hashedPassword,
 		FullName: request.FullName,
+		Email:    email,
+		Phone:    phone,
 		Role:     request.Role,
+		OutletID: request.OutletID,
+		IsActive: true,
+	}
+
+	if request.Invite {
+		return a.inviteUser(ctx, user, newUser)
+	}
+
+	hashedPassword, err := helper.HashPassword(request.Password)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error HashPassword  %s", err.Error())
+		return nil, *NewGeneralSystemError()
 	}
+	newUser.Password = hashedPassword
 
 	err = a.userRepository.CreateUser(ctx, newUser)
 	if err != nil {
-		if strings.Contains(err.Error(), "Duplicate entry") ||
-			strings.Contains(err.Error(), "duplicate key value violates unique constraint") ||
-			strings.Contains(err.Error(), "SQLSTATE 23505") {
-			return *NewDuplicateUserError()
+		if isDuplicateKeyError(err) {
+			return nil, *NewDuplicateUserError()
 		}
-		return *NewUpdateQueryDBError()
+		return nil, *NewUpdateQueryDBError()
 	}
 
-	return *NewSuccessError()
+	a.recordAudit(ctx, auditEntry{
+		Event:    AuditEventUserCreated,
+		Actor:    user,
+		TargetID: newUser.ID,
+		Changes:  createdUserChanges(newUser),
+	})
+
+	return nil, *NewSuccessError()
 }
 
 func (a *authServiceImpl) EditUser(ctx context.Context, request model.EditUserRequest, token string) AppError {
@@ -191,11 +409,31 @@ func (a *authServiceImpl) EditUser(ctx context.Context, request model.EditUserRe
 		return *NewUserNotAllowError()
 	}
 
+	ctx = repository.WithTenant(ctx, user.ClientID)
+
+	// Only new users can be invited, an edit always sets the password
+	if request.Invite {
+		return *NewInvalidRequestError("Invalid invite")
+	}
+
 	validate := validator.New()
 	if err := validate.Struct(request); err != nil {
 		return *NewInvalidRequestError(err.Error())
 	}
 
+	if !entity.IsValidRole(request.Role) {
+		return *NewInvalidRequestError("Invalid role")
+	}
+
+	email, appError := normalizeEmail(request.Email)
+	if appError.Code != SuccessError {
+		return appError
+	}
+	phone, appError := normalizePhone(request.Phone)
+	if appError.Code != SuccessError {
+		return appError
+	}
+
 	hashedPassword, err := helper.HashPassword(request.Password)
 	if err != nil {
 		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error HashPassword  %s", err.Error())
@@ -208,16 +446,254 @@ func (a *authServiceImpl) EditUser(ctx context.Context, request model.EditUserRe
 		Password: hashedPassword,
 		FullName: request.FullName,
 		Role:     request.Role,
+		OutletID: request.OutletID,
+	}
+
+	oldUser, err := a.userRepository.GetUserByID(ctx, request.ID)
+	if err != nil {
+		if err.Error() != "record not found" {
+			return *NewQueryDBError()
+		}
+		return *NewUserIDNotFoundError()
+	}
+
+	// An empty email or phone keeps the current one, a new one has to be verified again
+	contact := map[string]interface{}{}
+	if email != "" && email != oldUser.Email {
+		if appError := a.checkContact(ctx, entity.VerificationChannelEmail, email, oldUser.ID); appError.Code != SuccessError {
+			return appError
+		}
+		contact["email"], contact["email_verified_at"] = email, nil
+	}
+	if phone != "" && phone != oldUser.Phone {
+		if appError := a.checkContact(ctx, entity.VerificationChannelPhone, phone, oldUser.ID); appError.Code != SuccessError {
+			return appError
+		}
+		contact["phone"], contact["phone_verified_at"] = phone, nil
 	}
 
 	err = a.userRepository.UpdateUser(ctx, newUser)
 	if err != nil {
+		if err.Error() == "record not found" {
+			return *NewUserIDNotFoundError()
+		}
+		if isDuplicateKeyError(err) {
+			return *NewDuplicateUserError()
+		}
+		return *NewUpdateQueryDBError()
+	}
+
+	if len(contact) > 0 {
+		err = a.userRepository.UpdateUserFields(ctx, oldUser.ID, contact)
+		if err != nil {
+			if isDuplicateKeyError(err) {
+				return *NewDuplicateContactError()
+			}
+			return *NewUpdateQueryDBError()
+		}
+	}
+
+	// The struct update leaves a missing outlet unchanged
+	fields := map[string]interface{}{
+		"username":  newUser.Username,
+		"full_name": newUser.FullName,
+		"role":      newUser.Role,
+		"password":  newUser.Password,
+	}
+	if newUser.OutletID != nil {
+		fields["outlet_id"] = *newUser.OutletID
+	}
+	for column, value := range contact {
+		fields[column] = value
+	}
+	a.recordUserUpdate(ctx, user, oldUser, fields)
+
+	if oldUser.Role != request.Role {
+		a.revocations.Publish(RevocationEvent{
+			Type:     EventRoleChanged,
+			UserID:   oldUser.ID,
+			ClientID: oldUser.ClientID,
+		})
+	}
+
+	return *NewSuccessError()
+}
+
+// PatchUser updates only the provided fields of a user of the caller's client. The password is
+// re-hashed only when a new one is supplied, and a role change must name a role of the catalogue.
+func (a *authServiceImpl) PatchUser(ctx context.Context, ID uint, request model.PatchUserRequest, token string) AppError {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	user, appError := a.Authorize(ctx, token)
+	if appError.Code != SuccessError {
+		return appError
+	}
+
+	if !user.IsLogin {
+		return *NewInvalidTokenError()
+	}
+
+	if !user.IsAdmin {
+		return *NewUserNotAllowError()
+	}
+
+	ctx = repository.WithTenant(ctx, user.ClientID)
+
+	if ID == 0 {
+		return *NewInvalidRequestError("Invalid UserID")
+	}
+
+	target, err := a.userRepository.GetUserByID(ctx, ID)
+	if err != nil {
+		if err.Error() != "record not found" {
+			return *NewQueryDBError()
+		}
+		return *NewUserIDNotFoundError()
+	}
+
+	fields := map[string]interface{}{}
+	if request.Username != nil {
+		if strings.TrimSpace(*request.Username) == "" {
+			return *NewInvalidRequestError("Invalid username")
+		}
+		if *request.Username != target.Username {
+			existingUser, err := a.userRepository.GetUserByUsername(ctx, *request.Username)
+			if err != nil && err.Error() != "record not found" {
+				return *NewQueryDBError()
+			}
+			if existingUser != nil {
+				return *NewDuplicateUserError()
+			}
+		}
+		fields["username"] = *request.Username
+	}
+	if request.FullName != nil {
+		if strings.TrimSpace(*request.FullName) == "" {
+			return *NewInvalidRequestError("Invalid fullName")
+		}
+		fields["full_name"] = *request.FullName
+	}
+	if request.Email != nil {
+		email, appError := normalizeEmail(*request.Email)
+		if appError.Code != SuccessError {
+			return appError
+		}
+		if email != target.Email {
+			if appError := a.checkContact(ctx, entity.VerificationChannelEmail, email, target.ID); appError.Code != SuccessError {
+				return appError
+			}
+			fields["email_verified_at"] = nil
+		}
+		fields["email"] = email
+	}
+	if request.Phone != nil {
+		phone, appError := normalizePhone(*request.Phone)
+		if appError.Code != SuccessError {
+			return appError
+		}
+		if phone != target.Phone {
+			if appError := a.checkContact(ctx, entity.VerificationChannelPhone, phone, target.ID); appError.Code != SuccessError {
+				return appError
+			}
+			fields["phone_verified_at"] = nil
+		}
+		fields["phone"] = phone
+	}
+	if request.Role != nil {
+		if !entity.IsValidRole(*request.Role) {
+			return *NewInvalidRequestError("Invalid role")
+		}
+		fields["role"] = *request.Role
+	}
+	if request.OutletID != nil {
+		if *request.OutletID == 0 {
+			fields["outlet_id"] = nil
+		} else {
+			fields["outlet_id"] = *request.OutletID
+		}
+	}
+	if request.Password != nil {
+		if *request.Password == "" {
+			return *NewInvalidRequestError("Invalid password")
+		}
+		hashedPassword, err := helper.HashPassword(*request.Password)
+		if err != nil {
+			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error HashPassword  %s", err.Error())
+			return *NewGeneralSystemError()
+		}
+		fields["password"] = hashedPassword
+	}
+
+	if len(fields) == 0 {
+		return *NewInvalidRequestError("No fields to update")
+	}
+
+	err = a.userRepository.UpdateUserFields(ctx, ID, fields)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return *NewUserIDNotFoundError()
+		}
+		if isDuplicateKeyError(err) {
+			return *NewDuplicateUserError()
+		}
 		return *NewUpdateQueryDBError()
 	}
 
+	a.recordUserUpdate(ctx, user, target, fields)
+
+	if request.Role != nil && *request.Role != target.Role {
+		a.revocations.Publish(RevocationEvent{
+			Type:     EventRoleChanged,
+			UserID:   target.ID,
+			ClientID: target.ClientID,
+		})
+	}
+
 	return *NewSuccessError()
 }
 
+// recordUserUpdate records in the audit log the update of target by actor setting the columns of
+// fields, together with a role change when the role changed.
+func (a *authServiceImpl) recordUserUpdate(ctx context.Context, actor *model.User, target *entity.User, fields map[string]interface{}) {
+	changes := userChanges(target, fields)
+	if len(changes) == 0 {
+		return
+	}
+	a.recordAudit(ctx, auditEntry{
+		Event:    AuditEventUserUpdated,
+		Actor:    actor,
+		TargetID: target.ID,
+		Changes:  changes,
+	})
+	if change, ok := changes["role"]; ok {
+		a.recordAudit(ctx, auditEntry{
+			Event:    AuditEventRoleChanged,
+			Actor:    actor,
+			TargetID: target.ID,
+			Changes:  map[string]model.FieldChange{"role": change},
+		})
+	}
+}
+
+// createdUserChanges returns the fields of a created user as changes from nothing, without its password.
+func createdUserChanges(user *entity.User) map[string]model.FieldChange {
+	fields := map[string]interface{}{
+		"username":  user.Username,
+		"full_name": user.FullName,
+		"email":     user.Email,
+		"phone":     user.Phone,
+		"role":      user.Role,
+		"is_active": user.IsActive,
+	}
+	if user.OutletID != nil {
+		fields["outlet_id"] = *user.OutletID
+	}
+	changes := map[string]model.FieldChange{}
+	for column, value := range fields {
+		changes[column] = model.FieldChange{New: value}
+	}
+	return changes
+}
+
 func (a *authServiceImpl) GetAllUser(ctx context.Context, token string) ([]*model.User, AppError) {
 	user, appError := a.Authorize(ctx, token)
 	if appError.Code != SuccessError {
@@ -232,74 +708,201 @@ func (a *authServiceImpl) GetAllUser(ctx context.Context, token string) ([]*mode
 		return nil, *NewUserNotAllowError()
 	}
 
+	ctx = repository.WithTenant(ctx, user.ClientID)
+
 	usersFull, err := a.userRepository.GetAllUserByClientID(ctx, int(user.ClientID))
 	if err != nil {
 		return nil, *NewQueryDBError()
 	}
 	var users []*model.User
 	for _, u := range usersFull {
-		addUser := &model.User{
-			ID:       u.ID,
-			Username: u.Username,
-			FullName: u.FullName,
-			Role:     u.Role,
-		}
-		users = append(users, addUser)
+		users = append(users, toUserModel(u))
 	}
 
 	return users, *NewSuccessError()
 }
 
-func (a *authServiceImpl) DeactivateUser(ctx context.Context, ID uint, token string) AppError {
+// GetUserByID returns a user of the caller's client. Users of other clients are reported as not found.
+func (a *authServiceImpl) GetUserByID(ctx context.Context, ID uint, token string) (*model.User, AppError) {
 	user, appError := a.Authorize(ctx, token)
 	if appError.Code != SuccessError {
-		return appError
+		return nil, appError
 	}
 
 	if !user.IsLogin {
-		return *NewInvalidTokenError()
+		return nil, *NewInvalidTokenError()
 	}
 
 	if !user.IsAdmin {
-		return *NewUserNotAllowError()
+		return nil, *NewUserNotAllowError()
 	}
 
+	ctx = repository.WithTenant(ctx, user.ClientID)
+
 	if ID == 0 {
-		return *NewInvalidRequestError("Invalid UserID")
+		return nil, *NewInvalidRequestError("Invalid UserID")
 	}
 
-	err := a.userRepository.DeactivateUser(ctx, ID)
+	result, err := a.userRepository.GetUserByID(ctx, ID)
 	if err != nil {
-		return *NewUpdateQueryDBError()
+		if err.Error() != "record not found" {
+			return nil, *NewQueryDBError()
+		}
+		return nil, *NewUserIDNotFoundError()
 	}
 
-	return *NewSuccessError()
+	return toUserModel(result), *NewSuccessError()
 }
 
-func (a *authServiceImpl) Logout(ctx context.Context, token string) AppError {
-	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+// GetProfile returns the profile of the token owner: the user, its permissions, the name of its
+// client and the expiry of the session (TokenExpired).
+func (a *authServiceImpl) GetProfile(ctx context.Context, token string) (*model.Profile, AppError) {
 	user, appError := a.Authorize(ctx, token)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	if !user.IsLogin {
+		return nil, *NewInvalidTokenError()
+	}
+
+	client, err := a.userRepository.GetClientByID(ctx, user.ClientID)
+	if err != nil {
+		if err.Error() != "record not found" {
+			return nil, *NewQueryDBError()
+		}
+		return nil, *NewInvalidClientError()
+	}
+
+	return &model.Profile{
+		User:        *user,
+		ClientName:  client.CompanyName,
+		Permissions: entity.RolePermissions(user.Role),
+	}, *NewSuccessError()
+}
+
+func (a *authServiceImpl) Logout(ctx context.Context, token string) AppError {
+	_, appError := a.Authorize(ctx, token)
 	if appError.Code != SuccessError {
 		return appError
 	}
 
-	token, err := helper.GenerateRandomString(8)
+	revoked, appError := a.revokeToken(ctx, token)
+	if appError.Code != SuccessError {
+		return appError
+	}
+
+	if revoked != nil {
+		a.recordAudit(ctx, auditEntry{
+			Event:    AuditEventLogout,
+			Actor:    &model.User{ID: revoked.UserID, ClientID: revoked.ClientID},
+			TargetID: revoked.UserID,
+		})
+	}
+
+	return appError
+}
+
+// RevokeToken revokes a login token or an OAuth2 token by value. Revoking a refresh token
+// also revokes the access tokens issued with it. Unknown tokens are ignored.
+func (a *authServiceImpl) RevokeToken(ctx context.Context, token string) AppError {
+	revoked, appError := a.revokeToken(ctx, token)
+	if appError.Code != SuccessError {
+		return appError
+	}
+
+	if revoked != nil {
+		a.recordAudit(ctx, auditEntry{
+			Event:    AuditEventTokenRevoked,
+			ClientID: revoked.ClientID,
+			TargetID: revoked.UserID,
+		})
+	}
+
+	return appError
+}
+
+// revokeToken revokes a token as described by RevokeToken. It returns the revocation event
+// published for the token, nil when no user session ended.
+func (a *authServiceImpl) revokeToken(ctx context.Context, token string) (*RevocationEvent, AppError) {
+	if token == "" {
+		return nil, *NewSuccessError()
+	}
+
+	user, err := a.userRepository.GetUserByToken(ctx, token)
+	if err != nil && err.Error() != "record not found" {
+		return nil, *NewQueryDBError()
+	}
+
+	if user != nil {
+		revoked, err := a.userRepository.RevokeUserToken(ctx, token)
+		if err != nil {
+			return nil, *NewUpdateQueryDBError()
+		}
+		if !revoked {
+			return nil, *NewSuccessError()
+		}
+		event := RevocationEvent{
+			Type:      EventSessionRevoked,
+			UserID:    user.ID,
+			ClientID:  user.ClientID,
+			TokenHash: hashToken(token),
+		}
+		a.revocations.Publish(event)
+		return &event, *NewSuccessError()
+	}
+
+	oauthToken, err := a.oauthRepository.GetToken(ctx, token)
 	if err != nil {
-		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GenerateRandomString  %s", err.Error())
-		return *NewGeneralSystemError()
+		if err.Error() != "record not found" {
+			return nil, *NewQueryDBError()
+		}
+		return nil, *NewSuccessError()
 	}
 
-	newUser := &entity.User{
-		ID:    user.ID,
-		Token: token,
+	if oauthToken.TokenType == entity.TokenTypeRefresh && oauthToken.UserID != 0 {
+		err = a.oauthRepository.RevokeUserTokens(ctx, oauthToken.ClientID, oauthToken.UserID)
+	} else {
+		err = a.oauthRepository.RevokeToken(ctx, oauthToken.ID)
+	}
+	if err != nil {
+		return nil, *NewUpdateQueryDBError()
 	}
 
-	err = a.userRepository.UpdateUser(ctx, newUser)
+	if oauthToken.UserID != 0 {
+		event := RevocationEvent{
+			Type:   EventSessionRevoked,
+			UserID: oauthToken.UserID,
+		}
+		// Revoking a refresh token ends every session of the user with that client,
+		// so consumers have to drop all cached entries of the user
+		if oauthToken.TokenType == entity.TokenTypeAccess {
+			event.TokenHash = hashToken(token)
+		}
+		if owner, err := a.userRepository.GetUserByID(ctx, oauthToken.UserID); err == nil {
+			event.ClientID = owner.ClientID
+		}
+		a.revocations.Publish(event)
+		return &event, *NewSuccessError()
+	}
+
+	return nil, *NewSuccessError()
+}
+
+// WatchRevocations subscribes to revocation events, starting after cursor. See RevocationBus.Subscribe.
+func (a *authServiceImpl) WatchRevocations(cursor string) ([]RevocationEvent, <-chan RevocationEvent, func(), AppError) {
+	missed, events, cancel, err := a.revocations.Subscribe(cursor)
 	if err != nil {
-		return *NewUpdateQueryDBError()
+		return nil, nil, nil, *NewInvalidCursorError()
 	}
+	return missed, events, cancel, *NewSuccessError()
+}
 
-	return *NewSuccessError()
+// isDuplicateKeyError reports whether err is a unique constraint violation.
+func isDuplicateKeyError(err error) bool {
+	return strings.Contains(err.Error(), "Duplicate entry") ||
+		strings.Contains(err.Error(), "duplicate key value violates unique constraint") ||
+		strings.Contains(err.Error(), "SQLSTATE 23505")
 }
 
 func calculateTokenExpiration() time.Time {
@@ -11,25 +11,52 @@ const (
 	GenaralSystemErrorMessage = "General System Error"
 
 	//100 to 199: Authentication and authorization errors
-	InvalidUsername        = 101
-	InvalidUsernameMessage = "User not found"
-	InvalidPassword        = 102
-	InvalidPasswordMessage = "Invalid Password"
+	InvalidUsername           = 101
+	InvalidUsernameMessage    = "User not found"
+	InvalidPassword           = 102
+	InvalidPasswordMessage    = "Invalid Password"
+	InvalidClient             = 103
+	InvalidClientMessage      = "Invalid Client"
+	UnauthorizedClient        = 104
+	UnauthorizedClientMessage = "Unauthorized Client"
 
 	//200 - 299 input validation error
-	InvalidFormatError        = 201
-	InvalidFormatErrorMessage = "Invalid Format Request"
-	InvalidToken              = 202
-	InvalidTokendMessage      = "Invalid Token"
-	InvalidRequestError       = 203
-	InvalidRequestMessage     = "Invalid Request %s"
-
-	UserNotAllowError    = 211
-	UserNotAllowMessage  = "User Not Active"
-	UserNotActiveError   = 212
-	UserNotActiveMessage = "User Not Active"
-	DuplicateUserError   = 213
-	DuplicateUserMessage = "Duplicate User"
+	InvalidFormatError             = 201
+	InvalidFormatErrorMessage      = "Invalid Format Request"
+	InvalidToken                   = 202
+	InvalidTokendMessage           = "Invalid Token"
+	InvalidRequestError            = 203
+	InvalidRequestMessage          = "Invalid Request %s"
+	InvalidScope                   = 204
+	InvalidScopeMessage            = "Invalid Scope"
+	UnsupportedGrantType           = 205
+	UnsupportedGrantTypeMessage    = "Unsupported Grant Type"
+	InvalidGrant                   = 206
+	InvalidGrantMessage            = "Invalid Grant"
+	InvalidRedirectURI             = 207
+	InvalidRedirectURIMessage      = "Invalid Redirect URI"
+	UnsupportedResponseType        = 208
+	UnsupportedResponseTypeMessage = "Unsupported Response Type"
+
+	UserNotAllowError     = 211
+	UserNotAllowMessage   = "User Not Active"
+	UserNotActiveError    = 212
+	UserNotActiveMessage  = "User Not Active"
+	DuplicateUserError    = 213
+	DuplicateUserMessage  = "Duplicate User"
+	UserIDNotFoundError   = 214
+	UserIDNotFoundMessage = "User ID Not Found"
+	InvalidCursorError    = 215
+	InvalidCursorMessage  = "Cursor Expired"
+
+	ClientCodeRequiredError    = 216
+	ClientCodeRequiredMessage  = "Client Code Required"
+	InvalidInvitationError     = 217
+	InvalidInvitationMessage   = "Invalid Invitation"
+	DuplicateContactError      = 218
+	DuplicateContactMessage    = "Duplicate Contact"
+	InvalidVerificationError   = 219
+	InvalidVerificationMessage = "Invalid Verification Code"
 
 	//300 to 399: Database-related errors
 	QueryError              = 301
@@ -85,6 +112,58 @@ func NewInvalidPasswordError() *AppError {
 	return NewAppError(InvalidPassword, InvalidPasswordMessage)
 }
 
+func NewInvalidClientError() *AppError {
+	return NewAppError(InvalidClient, InvalidClientMessage)
+}
+
+func NewInvalidScopeError() *AppError {
+	return NewAppError(InvalidScope, InvalidScopeMessage)
+}
+
+func NewUnsupportedGrantTypeError() *AppError {
+	return NewAppError(UnsupportedGrantType, UnsupportedGrantTypeMessage)
+}
+
+func NewUnauthorizedClientError() *AppError {
+	return NewAppError(UnauthorizedClient, UnauthorizedClientMessage)
+}
+
+func NewInvalidGrantError() *AppError {
+	return NewAppError(InvalidGrant, InvalidGrantMessage)
+}
+
+func NewInvalidRedirectURIError() *AppError {
+	return NewAppError(InvalidRedirectURI, InvalidRedirectURIMessage)
+}
+
+func NewUnsupportedResponseTypeError() *AppError {
+	return NewAppError(UnsupportedResponseType, UnsupportedResponseTypeMessage)
+}
+
+func NewUserIDNotFoundError() *AppError {
+	return NewAppError(UserIDNotFoundError, UserIDNotFoundMessage)
+}
+
+func NewInvalidCursorError() *AppError {
+	return NewAppError(InvalidCursorError, InvalidCursorMessage)
+}
+
+func NewClientCodeRequiredError() *AppError {
+	return NewAppError(ClientCodeRequiredError, ClientCodeRequiredMessage)
+}
+
+func NewInvalidInvitationError() *AppError {
+	return NewAppError(InvalidInvitationError, InvalidInvitationMessage)
+}
+
+func NewDuplicateContactError() *AppError {
+	return NewAppError(DuplicateContactError, DuplicateContactMessage)
+}
+
+func NewInvalidVerificationError() *AppError {
+	return NewAppError(InvalidVerificationError, InvalidVerificationMessage)
+}
+
 func NewUserNotFoundError() *AppError {
 	return NewAppError(InvalidUsername, InvalidUsernameMessage)
 }
@@ -0,0 +1,701 @@
+// internal/service/oauth_service.go
+
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"maqhaa/auth_service/internal/app/entity"
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/repository"
+	"maqhaa/auth_service/internal/config"
+	"maqhaa/library/helper"
+	"maqhaa/library/logging"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"maqhaa/library/middleware"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultAccessTokenTTL       = 5 * time.Minute
+	defaultRefreshTokenTTL      = 30 * 24 * time.Hour
+	defaultAuthorizationCodeTTL = time.Minute
+	oauthTokenLength            = 32
+	bearerTokenType             = "Bearer"
+	responseTypeCode            = "code"
+	scopeOpenID                 = "openid"
+	scopeTokenIntrospect        = "token.introspect"
+	scopeTokenRevoke            = "token.revoke"
+)
+
+// OAuthService implements the OAuth2 grants served by the auth service.
+type OAuthService interface {
+	IssueToken(ctx context.Context, request model.TokenRequest) (*model.TokenResponse, AppError)
+	AuthorizeService(ctx context.Context, token string) (*model.ServiceIdentity, AppError)
+	ValidateAuthorizeRequest(ctx context.Context, request model.AuthorizeRequest) (*entity.OAuthClient, AppError)
+	CompleteAuthorization(ctx context.Context, request model.AuthorizeRequest, username, password string) (string, AppError)
+	UserInfo(ctx context.Context, token string) (*model.UserInfo, AppError)
+	Introspect(ctx context.Context, clientID, clientSecret, token string) (*model.IntrospectionResponse, AppError)
+	Revoke(ctx context.Context, clientID, clientSecret, token string) AppError
+	Discovery() model.OpenIDConfiguration
+	JWKS() model.JSONWebKeySet
+}
+
+// oauthServiceImpl implements the OAuthService interface
+type oauthServiceImpl struct {
+	oauthRepository      repository.OAuthRepository
+	authService          AuthService
+	signer               *TokenSigner
+	issuer               string
+	accessTokenTTL       time.Duration
+	refreshTokenTTL      time.Duration
+	authorizationCodeTTL time.Duration
+}
+
+// NewOAuthService creates a new OAuthService instance.
+func NewOAuthService(oauthRepository repository.OAuthRepository, authService AuthService, signer *TokenSigner, cfg config.OAuthConfig) OAuthService {
+	return &oauthServiceImpl{
+		oauthRepository:      oauthRepository,
+		authService:          authService,
+		signer:               signer,
+		issuer:               strings.TrimSuffix(cfg.Issuer, "/"),
+		accessTokenTTL:       durationOrDefault(cfg.AccessTokenTTL, defaultAccessTokenTTL),
+		refreshTokenTTL:      durationOrDefault(cfg.RefreshTokenTTL, defaultRefreshTokenTTL),
+		authorizationCodeTTL: durationOrDefault(cfg.AuthorizationCodeTTL, defaultAuthorizationCodeTTL),
+	}
+}
+
+// IssueToken dispatches a token request to the handler of its grant type.
+func (o *oauthServiceImpl) IssueToken(ctx context.Context, request model.TokenRequest) (*model.TokenResponse, AppError) {
+	switch request.GrantType {
+	case entity.GrantTypeClientCredentials:
+		return o.clientCredentials(ctx, request)
+	case entity.GrantTypeAuthorizationCode:
+		return o.authorizationCode(ctx, request)
+	case entity.GrantTypeRefreshToken:
+		return o.refreshToken(ctx, request)
+	case "":
+		return nil, *NewInvalidRequestError("grant_type is required")
+	default:
+		return nil, *NewUnsupportedGrantTypeError()
+	}
+}
+
+// clientCredentials issues a service token for a registered service principal.
+func (o *oauthServiceImpl) clientCredentials(ctx context.Context, request model.TokenRequest) (*model.TokenResponse, AppError) {
+	client, appError := o.authenticateClient(ctx, request.ClientID, request.ClientSecret, entity.GrantTypeClientCredentials)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	if client.IsPublic() {
+		return nil, *NewUnauthorizedClientError()
+	}
+
+	allowed := splitScope(client.Scopes)
+	scopes := splitScope(request.Scope)
+	if len(scopes) == 0 {
+		scopes = allowed
+	}
+	for _, scope := range scopes {
+		if !containsScope(allowed, scope) {
+			return nil, *NewInvalidScopeError()
+		}
+	}
+
+	token, appError := o.createToken(ctx, entity.TokenTypeAccess, client.ClientID, 0, strings.Join(scopes, " "), o.accessTokenTTL)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	return &model.TokenResponse{
+		AccessToken: token.Token,
+		TokenType:   bearerTokenType,
+		ExpiresIn:   int64(o.accessTokenTTL.Seconds()),
+		Scope:       token.Scope,
+	}, *NewSuccessError()
+}
+
+// authorizationCode exchanges an authorization code and its PKCE verifier for user tokens.
+func (o *oauthServiceImpl) authorizationCode(ctx context.Context, request model.TokenRequest) (*model.TokenResponse, AppError) {
+	client, appError := o.authenticateClient(ctx, request.ClientID, request.ClientSecret, entity.GrantTypeAuthorizationCode)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	if request.Code == "" || request.CodeVerifier == "" {
+		return nil, *NewInvalidRequestError("code and code_verifier are required")
+	}
+
+	code, err := o.oauthRepository.GetAuthorizationCode(ctx, request.Code)
+	if err != nil {
+		if err.Error() != "record not found" {
+			return nil, *NewQueryDBError()
+		}
+		return nil, *NewInvalidGrantError()
+	}
+
+	if code.ClientID != client.ClientID || code.RedirectURI != request.RedirectURI ||
+		code.UsedAt != nil || code.ExpiresAt.Before(time.Now()) {
+		return nil, *NewInvalidGrantError()
+	}
+
+	if !verifyCodeChallenge(code.CodeChallenge, request.CodeVerifier) {
+		return nil, *NewInvalidGrantError()
+	}
+
+	redeemed, err := o.oauthRepository.MarkAuthorizationCodeUsed(ctx, code.ID)
+	if err != nil {
+		return nil, *NewUpdateQueryDBError()
+	}
+	if !redeemed {
+		return nil, *NewInvalidGrantError()
+	}
+
+	return o.issueUserTokens(ctx, client.ClientID, code.UserID, code.Scope, code.Nonce)
+}
+
+// refreshToken issues a new access token from a refresh token. Refresh tokens are rotated: the one
+// presented is revoked and replaced by a new one expiring at the same time, so the lifetime of the
+// grant is not extended. Presenting a revoked refresh token again means it leaked, so every token
+// the client holds for the user is then revoked. The user must still exist and be active.
+func (o *oauthServiceImpl) refreshToken(ctx context.Context, request model.TokenRequest) (*model.TokenResponse, AppError) {
+	client, appError := o.authenticateClient(ctx, request.ClientID, request.ClientSecret, entity.GrantTypeRefreshToken)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	if request.RefreshToken == "" {
+		return nil, *NewInvalidRequestError("refresh_token is required")
+	}
+
+	refresh, err := o.oauthRepository.GetToken(ctx, request.RefreshToken)
+	if err != nil {
+		if err.Error() != "record not found" {
+			return nil, *NewQueryDBError()
+		}
+		return nil, *NewInvalidGrantError()
+	}
+
+	if refresh.TokenType != entity.TokenTypeRefresh || refresh.ClientID != client.ClientID {
+		return nil, *NewInvalidGrantError()
+	}
+
+	if !refresh.IsActive() {
+		if refresh.RevokedAt != nil {
+			o.revokeReusedRefreshToken(ctx, refresh)
+		}
+		return nil, *NewInvalidGrantError()
+	}
+
+	// The grant ends with the user: deactivated or deleted users cannot refresh
+	if _, appError := o.authService.AuthorizeUser(ctx, refresh.UserID); appError.Code != SuccessError {
+		if appError.Code == QueryError {
+			return nil, appError
+		}
+		return nil, *NewInvalidGrantError()
+	}
+
+	consumed, err := o.oauthRepository.ConsumeToken(ctx, refresh.ID)
+	if err != nil {
+		return nil, *NewUpdateQueryDBError()
+	}
+	if !consumed {
+		o.revokeReusedRefreshToken(ctx, refresh)
+		return nil, *NewInvalidGrantError()
+	}
+
+	token, appError := o.createToken(ctx, entity.TokenTypeAccess, client.ClientID, refresh.UserID, refresh.Scope, o.accessTokenTTL)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	rotated, appError := o.createToken(ctx, entity.TokenTypeRefresh, client.ClientID, refresh.UserID, refresh.Scope, time.Until(refresh.ExpiresAt))
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	return &model.TokenResponse{
+		AccessToken:  token.Token,
+		TokenType:    bearerTokenType,
+		ExpiresIn:    int64(o.accessTokenTTL.Seconds()),
+		RefreshToken: rotated.Token,
+		Scope:        token.Scope,
+	}, *NewSuccessError()
+}
+
+// revokeReusedRefreshToken revokes every token the client of a reused refresh token holds for its user.
+func (o *oauthServiceImpl) revokeReusedRefreshToken(ctx context.Context, refresh *entity.OAuthToken) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	logging.Log.WithFields(logrus.Fields{"request_id": logID, "client_id": refresh.ClientID, "user_id": refresh.UserID}).
+		Warn("Revoked refresh token reused, revoking the tokens of the grant")
+	// The error is logged by the repository
+	_ = o.oauthRepository.RevokeUserTokens(ctx, refresh.ClientID, refresh.UserID)
+}
+
+// issueUserTokens issues an access token and a refresh token on behalf of a user,
+// plus a signed ID token when the openid scope was granted.
+func (o *oauthServiceImpl) issueUserTokens(ctx context.Context, clientID string, userID uint, scope, nonce string) (*model.TokenResponse, AppError) {
+	access, appError := o.createToken(ctx, entity.TokenTypeAccess, clientID, userID, scope, o.accessTokenTTL)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	refresh, appError := o.createToken(ctx, entity.TokenTypeRefresh, clientID, userID, scope, o.refreshTokenTTL)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	response := &model.TokenResponse{
+		AccessToken:  access.Token,
+		TokenType:    bearerTokenType,
+		ExpiresIn:    int64(o.accessTokenTTL.Seconds()),
+		RefreshToken: refresh.Token,
+		Scope:        scope,
+	}
+
+	if containsScope(splitScope(scope), scopeOpenID) {
+		idToken, appError := o.signIDToken(ctx, access.Token, clientID, nonce)
+		if appError.Code != SuccessError {
+			return nil, appError
+		}
+		response.IDToken = idToken
+	}
+
+	return response, *NewSuccessError()
+}
+
+// signIDToken builds and signs the ID token describing the user behind an access token.
+func (o *oauthServiceImpl) signIDToken(ctx context.Context, accessToken, clientID, nonce string) (string, AppError) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	userInfo, appError := o.UserInfo(ctx, accessToken)
+	if appError.Code != SuccessError {
+		return "", appError
+	}
+
+	now := time.Now()
+	claims := model.IDTokenClaims{
+		Issuer:   o.issuer,
+		Audience: clientID,
+		IssuedAt: now.Unix(),
+		Expiry:   now.Add(o.accessTokenTTL).Unix(),
+		Nonce:    nonce,
+		UserInfo: *userInfo,
+	}
+
+	idToken, err := o.signer.Sign(claims)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error Sign  %s", err.Error())
+		return "", *NewGeneralSystemError()
+	}
+	return idToken, *NewSuccessError()
+}
+
+// UserInfo returns the OpenID Connect claims of the user owning the token.
+func (o *oauthServiceImpl) UserInfo(ctx context.Context, token string) (*model.UserInfo, AppError) {
+	user, appError := o.authService.Authorize(ctx, token)
+	if appError.Code == InvalidUsername {
+		return nil, *NewInvalidTokenError()
+	}
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	if !user.IsLogin {
+		return nil, *NewInvalidTokenError()
+	}
+
+	return &model.UserInfo{
+		Subject:           strconv.FormatUint(uint64(user.ID), 10),
+		Name:              user.FullName,
+		PreferredUsername: user.Username,
+		TenantID:          user.ClientID,
+		Role:              user.Role,
+		IsAdmin:           user.IsAdmin,
+	}, *NewSuccessError()
+}
+
+// Introspect reports whether a token is active and describes it (RFC 7662). The caller must be a
+// service principal holding the token.introspect scope. User tokens are resolved through
+// AuthService.Authorize, refresh tokens through AuthService.AuthorizeUser and service tokens
+// through AuthorizeService, so the answer always matches what the token would be granted elsewhere.
+func (o *oauthServiceImpl) Introspect(ctx context.Context, clientID, clientSecret, token string) (*model.IntrospectionResponse, AppError) {
+	caller, appError := o.authenticateClient(ctx, clientID, clientSecret, entity.GrantTypeClientCredentials)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	if caller.IsPublic() || !containsScope(splitScope(caller.Scopes), scopeTokenIntrospect) {
+		return nil, *NewUnauthorizedClientError()
+	}
+
+	inactive := &model.IntrospectionResponse{Active: false}
+	if token == "" {
+		return inactive, *NewSuccessError()
+	}
+
+	oauthToken, err := o.oauthRepository.GetToken(ctx, token)
+	if err != nil && err.Error() != "record not found" {
+		return nil, *NewQueryDBError()
+	}
+
+	// Login tokens issued by /login are only known to Authorize
+	if oauthToken == nil {
+		return o.introspectUser(ctx, token, inactive)
+	}
+
+	if !oauthToken.IsActive() {
+		return inactive, *NewSuccessError()
+	}
+
+	response := inactive
+	switch {
+	case oauthToken.TokenType == entity.TokenTypeRefresh:
+		user, appError := o.authService.AuthorizeUser(ctx, oauthToken.UserID)
+		if appError.Code == QueryError {
+			return nil, appError
+		}
+		if appError.Code == SuccessError {
+			response = &model.IntrospectionResponse{
+				Active:   true,
+				Subject:  strconv.FormatUint(uint64(user.ID), 10),
+				Username: user.Username,
+				TenantID: user.ClientID,
+				Role:     user.Role,
+				IsAdmin:  user.IsAdmin,
+			}
+		}
+	case oauthToken.UserID == 0:
+		identity, appError := o.AuthorizeService(ctx, token)
+		if appError.Code == QueryError {
+			return nil, appError
+		}
+		if appError.Code == SuccessError {
+			response = &model.IntrospectionResponse{
+				Active:  true,
+				Subject: identity.ClientID,
+			}
+		}
+	default:
+		var appError AppError
+		response, appError = o.introspectUser(ctx, token, inactive)
+		if appError.Code != SuccessError {
+			return nil, appError
+		}
+	}
+
+	if response.Active {
+		response.ClientID = oauthToken.ClientID
+		response.Scope = oauthToken.Scope
+		response.TokenType = oauthToken.TokenType
+		response.Expiry = oauthToken.ExpiresAt.Unix()
+	}
+	return response, *NewSuccessError()
+}
+
+// Revoke revokes an access or refresh token (RFC 7009). Any authenticated client may revoke the
+// tokens issued to it; revoking tokens of other clients or login tokens requires the token.revoke
+// scope. Unknown tokens are not an error, so callers cannot probe which tokens exist.
+func (o *oauthServiceImpl) Revoke(ctx context.Context, clientID, clientSecret, token string) AppError {
+	caller, appError := o.authenticateClient(ctx, clientID, clientSecret, "")
+	if appError.Code != SuccessError {
+		return appError
+	}
+
+	if token == "" {
+		return *NewInvalidRequestError("token is required")
+	}
+
+	privileged := !caller.IsPublic() && containsScope(splitScope(caller.Scopes), scopeTokenRevoke)
+
+	oauthToken, err := o.oauthRepository.GetToken(ctx, token)
+	if err != nil {
+		if err.Error() != "record not found" {
+			return *NewQueryDBError()
+		}
+		if !privileged {
+			return *NewSuccessError()
+		}
+	}
+
+	if oauthToken != nil && oauthToken.ClientID != caller.ClientID && !privileged {
+		return *NewUnauthorizedClientError()
+	}
+
+	return o.authService.RevokeToken(ctx, token)
+}
+
+// introspectUser describes a user token with the claims resolved by Authorize.
+func (o *oauthServiceImpl) introspectUser(ctx context.Context, token string, inactive *model.IntrospectionResponse) (*model.IntrospectionResponse, AppError) {
+	user, appError := o.authService.Authorize(ctx, token)
+	if appError.Code == QueryError {
+		return nil, appError
+	}
+	if appError.Code != SuccessError || !user.IsLogin {
+		return inactive, *NewSuccessError()
+	}
+
+	response := &model.IntrospectionResponse{
+		Active:    true,
+		Subject:   strconv.FormatUint(uint64(user.ID), 10),
+		Username:  user.Username,
+		TokenType: entity.TokenTypeAccess,
+		TenantID:  user.ClientID,
+		Role:      user.Role,
+		IsAdmin:   user.IsAdmin,
+	}
+	if user.TokenExpired != nil {
+		response.Expiry = user.TokenExpired.Unix()
+	}
+	return response, *NewSuccessError()
+}
+
+// Discovery returns the OpenID Connect discovery document.
+func (o *oauthServiceImpl) Discovery() model.OpenIDConfiguration {
+	return model.OpenIDConfiguration{
+		Issuer:                            o.issuer,
+		AuthorizationEndpoint:             o.issuer + "/oauth/authorize",
+		TokenEndpoint:                     o.issuer + "/oauth/token",
+		UserInfoEndpoint:                  o.issuer + "/userinfo",
+		JWKSURI:                           o.issuer + "/.well-known/jwks.json",
+		IntrospectionEndpoint:             o.issuer + "/oauth/introspect",
+		RevocationEndpoint:                o.issuer + "/oauth/revoke",
+		ScopesSupported:                   []string{scopeOpenID, "profile"},
+		ResponseTypesSupported:            []string{responseTypeCode},
+		GrantTypesSupported:               []string{entity.GrantTypeAuthorizationCode, entity.GrantTypeRefreshToken, entity.GrantTypeClientCredentials},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{signingAlgorithm},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{entity.CodeChallengeMethodS256},
+		ClaimsSupported:                   []string{"sub", "name", "preferred_username", "tenant_id", "role", "is_admin"},
+	}
+}
+
+// JWKS returns the public keys used to verify ID tokens.
+func (o *oauthServiceImpl) JWKS() model.JSONWebKeySet {
+	return model.JSONWebKeySet{
+		Keys: []model.JSONWebKey{o.signer.PublicKey()},
+	}
+}
+
+// ValidateAuthorizeRequest checks an authorization request against the registered client.
+// Errors about the client or redirect URI must be shown to the user instead of being redirected.
+func (o *oauthServiceImpl) ValidateAuthorizeRequest(ctx context.Context, request model.AuthorizeRequest) (*entity.OAuthClient, AppError) {
+	client, err := o.oauthRepository.GetClientByClientID(ctx, request.ClientID)
+	if err != nil {
+		if err.Error() != "record not found" {
+			return nil, *NewQueryDBError()
+		}
+		return nil, *NewInvalidClientError()
+	}
+
+	if !client.IsActive {
+		return nil, *NewInvalidClientError()
+	}
+
+	if request.RedirectURI == "" || !containsScope(splitScope(client.RedirectURIs), request.RedirectURI) {
+		return nil, *NewInvalidRedirectURIError()
+	}
+
+	if !containsScope(splitScope(client.GrantTypes), entity.GrantTypeAuthorizationCode) {
+		return client, *NewUnauthorizedClientError()
+	}
+
+	if request.ResponseType != responseTypeCode {
+		return client, *NewUnsupportedResponseTypeError()
+	}
+
+	if request.CodeChallenge == "" || request.CodeChallengeMethod != entity.CodeChallengeMethodS256 {
+		return client, *NewInvalidRequestError("code_challenge with code_challenge_method S256 is required")
+	}
+
+	for _, scope := range splitScope(request.Scope) {
+		if !containsScope(splitScope(client.Scopes), scope) {
+			return client, *NewInvalidScopeError()
+		}
+	}
+
+	return client, *NewSuccessError()
+}
+
+// CompleteAuthorization authenticates the user and returns the redirect URI carrying the authorization code.
+// Only the credentials are checked: the login session of the user is left alone.
+func (o *oauthServiceImpl) CompleteAuthorization(ctx context.Context, request model.AuthorizeRequest, username, password string) (string, AppError) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	client, appError := o.ValidateAuthorizeRequest(ctx, request)
+	if appError.Code != SuccessError {
+		return "", appError
+	}
+
+	user, appError := o.authService.VerifyCredentials(ctx, "", username, password)
+	if appError.Code != SuccessError {
+		return "", appError
+	}
+
+	value, err := helper.GenerateRandomString(oauthTokenLength)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GenerateRandomString  %s", err.Error())
+		return "", *NewGeneralSystemError()
+	}
+
+	scope := request.Scope
+	if scope == "" {
+		scope = client.Scopes
+	}
+
+	code := &entity.OAuthAuthorizationCode{
+		Code:                value,
+		ClientID:            client.ClientID,
+		UserID:              user.ID,
+		RedirectURI:         request.RedirectURI,
+		Scope:               scope,
+		CodeChallenge:       request.CodeChallenge,
+		CodeChallengeMethod: request.CodeChallengeMethod,
+		Nonce:               request.Nonce,
+		ExpiresAt:           time.Now().Add(o.authorizationCodeTTL),
+	}
+	if err := o.oauthRepository.CreateAuthorizationCode(ctx, code); err != nil {
+		return "", *NewUpdateQueryDBError()
+	}
+
+	params := url.Values{}
+	params.Set("code", code.Code)
+	if request.State != "" {
+		params.Set("state", request.State)
+	}
+	return AppendQuery(request.RedirectURI, params), *NewSuccessError()
+}
+
+// createToken generates and stores a new token.
+func (o *oauthServiceImpl) createToken(ctx context.Context, tokenType, clientID string, userID uint, scope string, ttl time.Duration) (*entity.OAuthToken, AppError) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	value, err := helper.GenerateRandomString(oauthTokenLength)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GenerateRandomString  %s", err.Error())
+		return nil, *NewGeneralSystemError()
+	}
+
+	token := &entity.OAuthToken{
+		Token:     value,
+		TokenType: tokenType,
+		ClientID:  clientID,
+		UserID:    userID,
+		Scope:     scope,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := o.oauthRepository.CreateToken(ctx, token); err != nil {
+		return nil, *NewUpdateQueryDBError()
+	}
+	return token, *NewSuccessError()
+}
+
+// AuthorizeService validates a service token and returns the service principal that owns it.
+func (o *oauthServiceImpl) AuthorizeService(ctx context.Context, token string) (*model.ServiceIdentity, AppError) {
+	result, err := o.oauthRepository.GetToken(ctx, token)
+	if err != nil {
+		if err.Error() != "record not found" {
+			return nil, *NewQueryDBError()
+		}
+		return nil, *NewInvalidTokenError()
+	}
+
+	if result.TokenType != entity.TokenTypeAccess || result.UserID != 0 || !result.IsActive() {
+		return nil, *NewInvalidTokenError()
+	}
+
+	client, err := o.oauthRepository.GetClientByClientID(ctx, result.ClientID)
+	if err != nil {
+		if err.Error() != "record not found" {
+			return nil, *NewQueryDBError()
+		}
+		return nil, *NewInvalidClientError()
+	}
+
+	if !client.IsActive {
+		return nil, *NewInvalidClientError()
+	}
+
+	return &model.ServiceIdentity{
+		ClientID: client.ClientID,
+		Name:     client.Name,
+		Scopes:   splitScope(result.Scope),
+	}, *NewSuccessError()
+}
+
+// authenticateClient verifies a registered client and checks that it may use the grant type.
+// The grant type check is skipped when grantType is empty. Public clients are identified by client_id only; confidential clients must present their secret.
+func (o *oauthServiceImpl) authenticateClient(ctx context.Context, clientID, clientSecret, grantType string) (*entity.OAuthClient, AppError) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if clientID == "" {
+		return nil, *NewInvalidClientError()
+	}
+
+	client, err := o.oauthRepository.GetClientByClientID(ctx, clientID)
+	if err != nil {
+		if err.Error() != "record not found" {
+			return nil, *NewQueryDBError()
+		}
+		return nil, *NewInvalidClientError()
+	}
+
+	if !client.IsActive {
+		return nil, *NewInvalidClientError()
+	}
+
+	if !client.IsPublic() {
+		if err := helper.CompareHashAndPassword(client.ClientSecret, clientSecret); err != nil {
+			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error CompareHashAndPassword  %s", err.Error())
+			return nil, *NewInvalidClientError()
+		}
+	}
+
+	if grantType != "" && !containsScope(splitScope(client.GrantTypes), grantType) {
+		return nil, *NewUnauthorizedClientError()
+	}
+
+	return client, *NewSuccessError()
+}
+
+// verifyCodeChallenge checks a PKCE code_verifier against the stored S256 code_challenge.
+func verifyCodeChallenge(challenge, verifier string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// AppendQuery adds query parameters to a redirect URI, keeping any query it already has.
+func AppendQuery(redirectURI string, params url.Values) string {
+	separator := "?"
+	if strings.Contains(redirectURI, "?") {
+		separator = "&"
+	}
+	return redirectURI + separator + params.Encode()
+}
+
+func durationOrDefault(value, fallback time.Duration) time.Duration {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// splitScope splits a space-delimited scope string into its scopes.
+func splitScope(scope string) []string {
+	return strings.Fields(scope)
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+// internal/service/password_policy.go
+
+package service
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+
+	"maqhaa/library/helper"
+)
+
+const (
+	minPasswordLength       = 8
+	generatedPasswordLength = 12
+)
+
+var (
+	errPasswordTooShort   = errors.New("password must be at least 8 characters")
+	errPasswordTooSimple  = errors.New("password must contain a letter and a digit")
+	errPasswordIsUsername = errors.New("password must differ from the username")
+)
+
+// validatePassword checks a password against the password policy: at least minPasswordLength
+// characters, a letter and a digit, and different from the username.
+func validatePassword(username, password string) error {
+	if len(password) < minPasswordLength {
+		return errPasswordTooShort
+	}
+	if strings.IndexFunc(password, unicode.IsLetter) < 0 || strings.IndexFunc(password, unicode.IsDigit) < 0 {
+		return errPasswordTooSimple
+	}
+	if strings.EqualFold(password, username) {
+		return errPasswordIsUsername
+	}
+	return nil
+}
+
+// generatePassword returns a random password meeting the password policy.
+func generatePassword() (string, error) {
+	for {
+		password, err := helper.GenerateRandomString(generatedPasswordLength)
+		if err != nil {
+			return "", err
+		}
+		if validatePassword("", password) == nil {
+			return password, nil
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// internal/service/revocation_bus.go
+
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// EventSessionRevoked is published when a login or OAuth2 token of a user is revoked.
+	EventSessionRevoked = "session_revoked"
+	// EventUserDeactivated is published when a user is deactivated.
+	EventUserDeactivated = "user_deactivated"
+	// EventRoleChanged is published when the role of a user changes.
+	EventRoleChanged = "role_changed"
+
+	revocationBacklog    = 1024
+	revocationSubscriber = 64
+)
+
+// ErrCursorExpired is returned when events after a cursor are no longer buffered.
+var ErrCursorExpired = errors.New("cursor expired")
+
+// RevocationEvent tells token caches which cached users or sessions are no longer valid.
+type RevocationEvent struct {
+	Cursor     string
+	Type       string
+	UserID     uint
+	ClientID   uint
+	TokenHash  string
+	OccurredAt time.Time
+}
+
+// RevocationBus is an in-process publish/subscribe bus for revocation events. It keeps the most
+// recent events so a subscriber reconnecting with its last cursor can catch up on what it missed.
+// Cursors are only valid for the lifetime of the process.
+type RevocationBus struct {
+	mu          sync.Mutex
+	epoch       string
+	seq         uint64
+	backlog     []RevocationEvent
+	subscribers map[chan RevocationEvent]struct{}
+}
+
+// NewRevocationBus creates an empty RevocationBus.
+func NewRevocationBus() *RevocationBus {
+	return &RevocationBus{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		subscribers: make(map[chan RevocationEvent]struct{}),
+	}
+}
+
+// Publish assigns the next cursor to an event and delivers it to every subscriber. A subscriber
+// that cannot keep up is disconnected, it has to resubscribe from its last cursor.
+func (b *RevocationBus) Publish(event RevocationEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	event.Cursor = fmt.Sprintf("%s.%d", b.epoch, b.seq)
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	b.backlog = append(b.backlog, event)
+	if len(b.backlog) > revocationBacklog {
+		b.backlog = b.backlog[len(b.backlog)-revocationBacklog:]
+	}
+
+	for events := range b.subscribers {
+		select {
+		case events <- event:
+		default:
+			delete(b.subscribers, events)
+			close(events)
+		}
+	}
+}
+
+// Subscribe returns the buffered events after cursor and a channel receiving the events published
+// from now on. An empty cursor only subscribes to new events. The returned function unsubscribes.
+func (b *RevocationBus) Subscribe(cursor string) ([]RevocationEvent, <-chan RevocationEvent, func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var missed []RevocationEvent
+	if cursor != "" {
+		seq, err := b.parseCursor(cursor)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		oldest := b.seq - uint64(len(b.backlog))
+		if seq < oldest {
+			return nil, nil, nil, ErrCursorExpired
+		}
+		missed = append(missed, b.backlog[len(b.backlog)-int(b.seq-seq):]...)
+	}
+
+	events := make(chan RevocationEvent, revocationSubscriber)
+	b.subscribers[events] = struct{}{}
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[events]; ok {
+			delete(b.subscribers, events)
+			close(events)
+		}
+	}
+	return missed, events, cancel, nil
+}
+
+// parseCursor returns the sequence number of a cursor issued by this process.
+func (b *RevocationBus) parseCursor(cursor string) (uint64, error) {
+	parts := strings.SplitN(cursor, ".", 2)
+	if len(parts) != 2 || parts[0] != b.epoch {
+		return 0, ErrCursorExpired
+	}
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil || seq > b.seq {
+		return 0, ErrCursorExpired
+	}
+	return seq, nil
+}
+
+// hashToken identifies a token in revocation events without disclosing it.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
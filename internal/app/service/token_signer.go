@@ -0,0 +1,119 @@
+// internal/service/token_signer.go
+
+package service
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"maqhaa/auth_service/internal/app/model"
+	"math/big"
+	"os"
+)
+
+const (
+	signingAlgorithm = "RS256"
+	signingKeyBits   = 2048
+)
+
+// TokenSigner signs JSON Web Tokens, such as OpenID Connect ID tokens, with an RSA key.
+type TokenSigner struct {
+	key   *rsa.PrivateKey
+	keyID string
+}
+
+// NewTokenSigner loads the PEM encoded RSA private key at keyFile. When keyFile is empty an
+// ephemeral key is generated, so tokens signed by a previous process can no longer be verified.
+func NewTokenSigner(keyFile string) (*TokenSigner, error) {
+	var key *rsa.PrivateKey
+	if keyFile == "" {
+		generated, err := rsa.GenerateKey(rand.Reader, signingKeyBits)
+		if err != nil {
+			return nil, fmt.Errorf("error generating signing key: %v", err)
+		}
+		key = generated
+	} else {
+		loaded, err := loadSigningKey(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		key = loaded
+	}
+
+	sum := sha256.Sum256(key.PublicKey.N.Bytes())
+	return &TokenSigner{
+		key:   key,
+		keyID: base64.RawURLEncoding.EncodeToString(sum[:])[:16],
+	}, nil
+}
+
+// Sign encodes the claims as a compact JWS.
+func (s *TokenSigner) Sign(claims interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{
+		"alg": signingAlgorithm,
+		"typ": "JWT",
+		"kid": s.keyID,
+	})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// PublicKey returns the verification key in JWK format.
+func (s *TokenSigner) PublicKey() model.JSONWebKey {
+	return model.JSONWebKey{
+		KeyType:   "RSA",
+		Use:       "sig",
+		Algorithm: signingAlgorithm,
+		KeyID:     s.keyID,
+		Modulus:   base64.RawURLEncoding.EncodeToString(s.key.PublicKey.N.Bytes()),
+		Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.PublicKey.E)).Bytes()),
+	}
+}
+
+// loadSigningKey reads a PKCS#1 or PKCS#8 RSA private key from a PEM file.
+func loadSigningKey(keyFile string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading signing key: %v", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("error decoding signing key: no PEM block found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing signing key: %v", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("error parsing signing key: not an RSA key")
+	}
+	return key, nil
+}
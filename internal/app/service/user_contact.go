@@ -0,0 +1,241 @@
+// internal/service/user_contact.go
+
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"maqhaa/auth_service/internal/app/entity"
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/repository"
+	"maqhaa/auth_service/internal/notification"
+	"maqhaa/library/logging"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"maqhaa/library/middleware"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// verificationTTL is how long a verification code can be confirmed.
+	verificationTTL = 10 * time.Minute
+	// verificationMaxAttempts is the number of attempts after which a code is no longer accepted.
+	verificationMaxAttempts = 5
+	// verificationResendDelay is how long a new code cannot be requested for the same address.
+	verificationResendDelay = time.Minute
+)
+
+// phonePattern is a phone number once separators are removed: 8 to 15 digits, optionally in
+// international format.
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{8,15}$`)
+
+// normalizeEmail validates an email address and returns it lower case. An empty email stays empty.
+func normalizeEmail(email string) (string, AppError) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return "", *NewSuccessError()
+	}
+	if err := validator.New().Var(email, "email,max=254"); err != nil {
+		return "", *NewInvalidRequestError("Invalid email")
+	}
+	return email, *NewSuccessError()
+}
+
+// normalizePhone validates a phone number and returns it without spaces, dashes, dots or
+// parentheses. An empty phone stays empty.
+func normalizePhone(phone string) (string, AppError) {
+	phone = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, phone)
+	if phone == "" {
+		return "", *NewSuccessError()
+	}
+	if !phonePattern.MatchString(phone) {
+		return "", *NewInvalidRequestError("Invalid phone")
+	}
+	return phone, *NewSuccessError()
+}
+
+// checkContact rejects an email or phone, depending on channel, already used by another user of the
+// client of the context than exceptID.
+func (a *authServiceImpl) checkContact(ctx context.Context, channel, value string, exceptID uint) AppError {
+	if value == "" {
+		return *NewSuccessError()
+	}
+	inUse, err := a.userRepository.ContactInUse(ctx, channel, value, exceptID)
+	if err != nil {
+		return *NewQueryDBError()
+	}
+	if inUse {
+		return *NewDuplicateContactError()
+	}
+	return *NewSuccessError()
+}
+
+// RequestVerification sends a one-time code to the email or phone of the caller, replacing the code
+// sent before. The code is confirmed with ConfirmVerification.
+func (a *authServiceImpl) RequestVerification(ctx context.Context, request model.VerificationRequest, token string) (*model.Verification, AppError) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	user, appError := a.Authorize(ctx, token)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	if !user.IsLogin {
+		return nil, *NewInvalidTokenError()
+	}
+
+	ctx = repository.WithTenant(ctx, user.ClientID)
+
+	validate := validator.New()
+	if err := validate.Struct(request); err != nil {
+		return nil, *NewInvalidRequestError(err.Error())
+	}
+
+	target, verified := user.Email, user.EmailVerified
+	if request.Channel == entity.VerificationChannelPhone {
+		target, verified = user.Phone, user.PhoneVerified
+	}
+	if target == "" {
+		return nil, *NewInvalidRequestError("No " + request.Channel + " to verify")
+	}
+	if verified {
+		return nil, *NewInvalidRequestError(request.Channel + " already verified")
+	}
+
+	open, err := a.userRepository.GetOpenVerification(ctx, user.ID, request.Channel)
+	if err != nil && err.Error() != "record not found" {
+		return nil, *NewQueryDBError()
+	}
+	if open != nil && open.Target == target && time.Since(open.CreatedAt) < verificationResendDelay {
+		return nil, *NewInvalidRequestError("Verification code already sent, retry later")
+	}
+
+	code, err := generateVerificationCode()
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error generateVerificationCode  %s", err.Error())
+		return nil, *NewGeneralSystemError()
+	}
+
+	verification := &entity.UserVerification{
+		ClientID:  user.ClientID,
+		UserID:    user.ID,
+		Channel:   request.Channel,
+		Target:    target,
+		CodeHash:  hashToken(code),
+		ExpiresAt: time.Now().Add(verificationTTL),
+	}
+	err = a.userRepository.ReplaceVerification(ctx, verification)
+	if err != nil {
+		return nil, *NewUpdateQueryDBError()
+	}
+
+	recipient := notification.Recipient{Name: user.FullName}
+	if request.Channel == entity.VerificationChannelEmail {
+		recipient.Email = target
+	} else {
+		recipient.Phone = target
+	}
+	a.notifier.Notify(ctx, notification.Notification{
+		Event:     notification.EventVerificationCode,
+		ClientID:  user.ClientID,
+		UserID:    user.ID,
+		Recipient: recipient,
+		Data: map[string]string{
+			"code":               code,
+			"channel":            request.Channel,
+			"expires_in_minutes": strconv.Itoa(int(verificationTTL / time.Minute)),
+		},
+	})
+
+	a.recordAudit(ctx, auditEntry{Event: AuditEventVerificationRequested, Actor: user, TargetID: user.ID})
+
+	return &model.Verification{
+		Channel:   verification.Channel,
+		Target:    verification.Target,
+		ExpiresAt: verification.ExpiresAt,
+	}, *NewSuccessError()
+}
+
+// ConfirmVerification verifies the email or phone of the caller with the last code sent to it. A code
+// is rejected once expired, used, after too many attempts, or when the address changed since
+// it was sent.
+func (a *authServiceImpl) ConfirmVerification(ctx context.Context, request model.ConfirmVerificationRequest, token string) AppError {
+	user, appError := a.Authorize(ctx, token)
+	if appError.Code != SuccessError {
+		return appError
+	}
+
+	if !user.IsLogin {
+		return *NewInvalidTokenError()
+	}
+
+	ctx = repository.WithTenant(ctx, user.ClientID)
+
+	validate := validator.New()
+	if err := validate.Struct(request); err != nil {
+		return *NewInvalidRequestError(err.Error())
+	}
+
+	verification, err := a.userRepository.GetOpenVerification(ctx, user.ID, request.Channel)
+	if err != nil {
+		if err.Error() != "record not found" {
+			return *NewQueryDBError()
+		}
+		return *NewInvalidVerificationError()
+	}
+
+	target := user.Email
+	if request.Channel == entity.VerificationChannelPhone {
+		target = user.Phone
+	}
+	if verification.Target != target || !verification.ExpiresAt.After(time.Now()) {
+		return *NewInvalidVerificationError()
+	}
+
+	// The attempt is counted before the code is compared, so parallel guesses share the same limit
+	allowed, err := a.userRepository.UseVerificationAttempt(ctx, verification.ID, verificationMaxAttempts)
+	if err != nil {
+		return *NewUpdateQueryDBError()
+	}
+	if !allowed {
+		return *NewInvalidVerificationError()
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(strings.TrimSpace(request.Code))), []byte(verification.CodeHash)) != 1 {
+		return *NewInvalidVerificationError()
+	}
+
+	err = a.userRepository.ConfirmVerification(ctx, verification)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return *NewInvalidVerificationError()
+		}
+		return *NewUpdateQueryDBError()
+	}
+
+	a.recordAudit(ctx, auditEntry{Event: request.Channel + "_verified", Actor: user, TargetID: user.ID})
+
+	return *NewSuccessError()
+}
+
+// generateVerificationCode returns a random 6 digit code.
+func generateVerificationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
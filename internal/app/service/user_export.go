@@ -0,0 +1,65 @@
+// internal/service/user_export.go
+
+package service
+
+import (
+	"context"
+	"maqhaa/auth_service/internal/app/entity"
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/repository"
+)
+
+// exportBatchSize is the number of users read from the database and written at a time.
+const exportBatchSize = 200
+
+// ExportUsers writes every user of the caller's client (admin only), deleted users excluded, a batch
+// at a time through write. Nothing is written when the caller is not allowed to export. An error
+// returned by write stops the export. Each export is recorded as an audit event.
+func (a *authServiceImpl) ExportUsers(ctx context.Context, token string, write func(rows []*model.UserExportRow) error) AppError {
+	user, appError := a.Authorize(ctx, token)
+	if appError.Code != SuccessError {
+		return appError
+	}
+
+	if !user.IsLogin {
+		return *NewInvalidTokenError()
+	}
+
+	if !user.IsAdmin {
+		return *NewUserNotAllowError()
+	}
+
+	ctx = repository.WithTenant(ctx, user.ClientID)
+
+	a.recordAudit(ctx, auditEntry{Event: AuditEventUsersExported, Actor: user})
+
+	err := a.userRepository.EachUser(ctx, exportBatchSize, func(users []*entity.User) error {
+		rows := make([]*model.UserExportRow, 0, len(users))
+		for _, u := range users {
+			rows = append(rows, toUserExportRow(u))
+		}
+		return write(rows)
+	})
+	if err != nil {
+		return *NewQueryDBError()
+	}
+
+	return *NewSuccessError()
+}
+
+// toUserExportRow converts a stored user to its export representation.
+func toUserExportRow(u *entity.User) *model.UserExportRow {
+	return &model.UserExportRow{
+		ID:          u.ID,
+		Username:    u.Username,
+		FullName:    u.FullName,
+		Role:        u.Role,
+		RoleName:    entity.RoleName(u.Role),
+		IsActive:    u.IsActive,
+		OutletID:    u.OutletID,
+		CreatedAt:   u.CreatedAt,
+		LastLoginAt: u.LastLoginAt,
+		Email:       u.Email,
+		Phone:       u.Phone,
+	}
+}
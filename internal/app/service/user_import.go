@@ -0,0 +1,239 @@
+// internal/service/user_import.go
+
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"maqhaa/auth_service/internal/app/entity"
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/repository"
+	"maqhaa/library/helper"
+	"maqhaa/library/logging"
+	"strconv"
+	"strings"
+
+	"maqhaa/library/middleware"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxImportRows bounds the rows of one import, each row costing a password hash.
+const maxImportRows = 500
+
+const (
+	importStatusOK    = "ok"
+	importStatusError = "error"
+)
+
+// requiredImportColumns are the columns an import file must have. outlet_id and password are optional.
+var requiredImportColumns = []string{"username", "full_name", "role"}
+
+// importCandidate is a parsed row of an import file.
+type importCandidate struct {
+	result   model.UserImportRow
+	user     entity.User
+	password string
+}
+
+// ImportUsers creates the users listed in a CSV file in the caller's client (admin only). The file
+// starts with a header naming the columns username, full_name, role and optionally outlet_id and
+// password; rows without a password get a generated one, returned in the report. Users are only
+// created when every row is valid, all in one transaction, and never in a dry run.
+func (a *authServiceImpl) ImportUsers(ctx context.Context, data io.Reader, dryRun bool, token string) (*model.UserImportReport, AppError) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	user, appError := a.Authorize(ctx, token)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	if !user.IsLogin {
+		return nil, *NewInvalidTokenError()
+	}
+
+	if !user.IsAdmin {
+		return nil, *NewUserNotAllowError()
+	}
+
+	ctx = repository.WithTenant(ctx, user.ClientID)
+
+	candidates, appError := readImportFile(data)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	usernames := []string{}
+	for _, c := range candidates {
+		if c.result.Status == importStatusOK {
+			usernames = append(usernames, c.user.Username)
+		}
+	}
+	existing, err := a.userRepository.ExistingUsernames(ctx, usernames)
+	if err != nil {
+		return nil, *NewQueryDBError()
+	}
+	taken := map[string]bool{}
+	for _, username := range existing {
+		taken[username] = true
+	}
+
+	report := &model.UserImportReport{DryRun: dryRun, Rows: []model.UserImportRow{}}
+	for _, c := range candidates {
+		if c.result.Status == importStatusOK && taken[c.user.Username] {
+			c.result.Status, c.result.Error = importStatusError, "username is taken"
+		}
+		if c.result.Status == importStatusError {
+			report.Failed++
+		}
+	}
+
+	if report.Failed == 0 && !dryRun {
+		users := make([]*entity.User, 0, len(candidates))
+		for _, c := range candidates {
+			password := c.password
+			if password == "" {
+				password, err = generatePassword()
+				if err != nil {
+					logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error generatePassword  %s", err.Error())
+					return nil, *NewGeneralSystemError()
+				}
+				c.result.Password = password
+			}
+			hashedPassword, err := helper.HashPassword(password)
+			if err != nil {
+				logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error HashPassword  %s", err.Error())
+				return nil, *NewGeneralSystemError()
+			}
+			c.user.ClientID = user.ClientID
+			c.user.Password = hashedPassword
+			c.user.IsActive = true
+			users = append(users, &c.user)
+		}
+
+		err = a.userRepository.CreateUsers(ctx, users)
+		if err != nil {
+			if isDuplicateKeyError(err) {
+				return nil, *NewDuplicateUserError()
+			}
+			return nil, *NewUpdateQueryDBError()
+		}
+		report.Imported = len(users)
+
+		for _, created := range users {
+			a.recordAudit(ctx, auditEntry{
+				Event:    AuditEventUserCreated,
+				Actor:    user,
+				TargetID: created.ID,
+				Changes:  createdUserChanges(created),
+				Reason:   "import",
+			})
+		}
+	}
+
+	for _, c := range candidates {
+		report.Rows = append(report.Rows, c.result)
+	}
+
+	return report, *NewSuccessError()
+}
+
+// readImportFile parses an import file and validates each row on its own. Rows using a username
+// already used by a previous row are rejected.
+func readImportFile(data io.Reader) ([]*importCandidate, AppError) {
+	reader := csv.NewReader(data)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, *NewInvalidRequestError("Invalid CSV header")
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range requiredImportColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, *NewInvalidRequestError("Missing column " + name)
+		}
+	}
+
+	candidates := []*importCandidate{}
+	seen := map[string]bool{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, *NewInvalidRequestError("Invalid CSV " + err.Error())
+		}
+		if len(candidates) == maxImportRows {
+			return nil, *NewInvalidRequestError("More than " + strconv.Itoa(maxImportRows) + " rows")
+		}
+
+		line, _ := reader.FieldPos(0)
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		c := &importCandidate{
+			result:   model.UserImportRow{Row: line, Username: field("username"), Status: importStatusOK},
+			password: field("password"),
+		}
+		c.user.Username = c.result.Username
+		c.user.FullName = field("full_name")
+		if message := validateImportRow(c, field("role"), field("outlet_id")); message != "" {
+			c.result.Status, c.result.Error = importStatusError, message
+		} else if seen[c.user.Username] {
+			c.result.Status, c.result.Error = importStatusError, "duplicate username in file"
+		}
+		seen[c.user.Username] = true
+		candidates = append(candidates, c)
+	}
+
+	if len(candidates) == 0 {
+		return nil, *NewInvalidRequestError("No rows to import")
+	}
+	return candidates, *NewSuccessError()
+}
+
+// validateImportRow checks the fields of a row and sets its role and outlet. It returns the
+// problem found, if any.
+func validateImportRow(c *importCandidate, role, outletID string) string {
+	if c.user.Username == "" {
+		return "username is required"
+	}
+	if c.user.FullName == "" {
+		return "full_name is required"
+	}
+
+	value, err := strconv.ParseUint(role, 10, 32)
+	if err != nil || !entity.IsValidRole(uint(value)) {
+		return "invalid role"
+	}
+	c.user.Role = uint(value)
+
+	if outletID != "" {
+		value, err := strconv.ParseUint(outletID, 10, 32)
+		if err != nil {
+			return "invalid outlet_id"
+		}
+		if value != 0 {
+			outlet := uint(value)
+			c.user.OutletID = &outlet
+		}
+	}
+
+	if c.password != "" {
+		if err := validatePassword(c.user.Username, c.password); err != nil {
+			return err.Error()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,262 @@
+// internal/service/user_invitation.go
+
+package service
+
+import (
+	"context"
+	"maqhaa/auth_service/internal/app/entity"
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/repository"
+	"maqhaa/auth_service/internal/notification"
+	"maqhaa/library/helper"
+	"maqhaa/library/logging"
+	"time"
+
+	"maqhaa/library/middleware"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// invitationTTL is how long an invitation can be accepted.
+	invitationTTL = 72 * time.Hour
+	// invitationTokenLength is the length of an invitation token.
+	invitationTokenLength = 32
+)
+
+// inviteUser creates newUser pending, inactive and without a password, together with an invitation
+// to activate it. The invitation is returned with its token.
+func (a *authServiceImpl) inviteUser(ctx context.Context, actor *model.User, newUser *entity.User) (*model.UserInvitation, AppError) {
+	invitation, token, appError := newInvitation(ctx, actor)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	newUser.IsActive = false
+	err := a.userRepository.CreateInvitedUser(ctx, newUser, invitation)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return nil, *NewDuplicateUserError()
+		}
+		return nil, *NewUpdateQueryDBError()
+	}
+	invitation.User = *newUser
+
+	a.recordAudit(ctx, auditEntry{Event: AuditEventUserCreated, Actor: actor, TargetID: newUser.ID, Changes: createdUserChanges(newUser)})
+	a.recordAudit(ctx, auditEntry{Event: AuditEventUserInvited, Actor: actor, TargetID: newUser.ID})
+	a.notifyInvitation(ctx, invitation, token)
+
+	result := toUserInvitation(invitation, time.Now())
+	result.Token = token
+	return result, *NewSuccessError()
+}
+
+// ListInvitations returns the invitations of the caller's client (admin only), newest first.
+func (a *authServiceImpl) ListInvitations(ctx context.Context, token string) ([]*model.UserInvitation, AppError) {
+	user, appError := a.Authorize(ctx, token)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	if !user.IsLogin {
+		return nil, *NewInvalidTokenError()
+	}
+
+	if !user.IsAdmin {
+		return nil, *NewUserNotAllowError()
+	}
+
+	ctx = repository.WithTenant(ctx, user.ClientID)
+
+	invitations, err := a.userRepository.ListInvitations(ctx)
+	if err != nil {
+		return nil, *NewQueryDBError()
+	}
+
+	now := time.Now()
+	result := make([]*model.UserInvitation, 0, len(invitations))
+	for _, invitation := range invitations {
+		result = append(result, toUserInvitation(invitation, now))
+	}
+	return result, *NewSuccessError()
+}
+
+// RevokeInvitation revokes an open invitation of the caller's client (admin only). The invited user
+// stays pending and can be invited again with ResendInvitation.
+func (a *authServiceImpl) RevokeInvitation(ctx context.Context, ID uint, token string) AppError {
+	user, appError := a.Authorize(ctx, token)
+	if appError.Code != SuccessError {
+		return appError
+	}
+
+	if !user.IsLogin {
+		return *NewInvalidTokenError()
+	}
+
+	if !user.IsAdmin {
+		return *NewUserNotAllowError()
+	}
+
+	ctx = repository.WithTenant(ctx, user.ClientID)
+
+	err := a.userRepository.RevokeInvitation(ctx, ID)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return *NewInvalidInvitationError()
+		}
+		return *NewUpdateQueryDBError()
+	}
+
+	a.recordAudit(ctx, auditEntry{Event: AuditEventInvitationRevoked, Actor: user, TargetID: ID})
+
+	return *NewSuccessError()
+}
+
+// ResendInvitation issues a new invitation to a pending user of the caller's client (admin only),
+// revoking the previous ones. It is how an expired or revoked invitation is replaced.
+func (a *authServiceImpl) ResendInvitation(ctx context.Context, userID uint, token string) (*model.UserInvitation, AppError) {
+	user, appError := a.Authorize(ctx, token)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	if !user.IsLogin {
+		return nil, *NewInvalidTokenError()
+	}
+
+	if !user.IsAdmin {
+		return nil, *NewUserNotAllowError()
+	}
+
+	ctx = repository.WithTenant(ctx, user.ClientID)
+
+	target, err := a.userRepository.GetUserByID(ctx, userID)
+	if err != nil {
+		if err.Error() != "record not found" {
+			return nil, *NewQueryDBError()
+		}
+		return nil, *NewUserIDNotFoundError()
+	}
+
+	invitation, token, appError := newInvitation(ctx, user)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+	invitation.UserID = target.ID
+
+	err = a.userRepository.ReplaceInvitation(ctx, invitation)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return nil, *NewInvalidRequestError("User is not pending")
+		}
+		return nil, *NewUpdateQueryDBError()
+	}
+	invitation.User = *target
+
+	a.recordAudit(ctx, auditEntry{Event: AuditEventUserInvited, Actor: user, TargetID: target.ID})
+	a.notifyInvitation(ctx, invitation, token)
+
+	result := toUserInvitation(invitation, time.Now())
+	result.Token = token
+	return result, *NewSuccessError()
+}
+
+// AcceptInvitation lets an invited user set its password, which activates it. The token can only be
+// used once, before it expires, and the password must follow the password policy.
+func (a *authServiceImpl) AcceptInvitation(ctx context.Context, request model.AcceptInvitationRequest) AppError {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	validate := validator.New()
+	if err := validate.Struct(request); err != nil {
+		return *NewInvalidRequestError(err.Error())
+	}
+
+	invitation, err := a.userRepository.GetInvitationByTokenHash(ctx, hashToken(request.Token))
+	if err != nil {
+		if err.Error() != "record not found" {
+			return *NewQueryDBError()
+		}
+		return *NewInvalidInvitationError()
+	}
+
+	if invitation.Status(time.Now()) != entity.InvitationStatusPending || invitation.User.ID == 0 {
+		return *NewInvalidInvitationError()
+	}
+
+	if err := validatePassword(invitation.User.Username, request.Password); err != nil {
+		return *NewInvalidRequestError(err.Error())
+	}
+
+	hashedPassword, err := helper.HashPassword(request.Password)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error HashPassword  %s", err.Error())
+		return *NewGeneralSystemError()
+	}
+
+	err = a.userRepository.AcceptInvitation(ctx, invitation, hashedPassword)
+	if err != nil {
+		if err.Error() == "record not found" {
+			return *NewInvalidInvitationError()
+		}
+		return *NewUpdateQueryDBError()
+	}
+
+	a.recordAudit(ctx, auditEntry{Event: AuditEventInvitationAccepted, Actor: &model.User{ID: invitation.UserID, ClientID: invitation.ClientID}, TargetID: invitation.UserID})
+
+	return *NewSuccessError()
+}
+
+// notifyInvitation sends the invitation token to the invited user.
+func (a *authServiceImpl) notifyInvitation(ctx context.Context, invitation *entity.UserInvitation, token string) {
+	data := map[string]string{
+		"username":   invitation.User.Username,
+		"token":      token,
+		"expires_at": invitation.ExpiresAt.Format(time.RFC1123),
+	}
+	// The client only names the sender, the invitation is sent without it
+	if client, err := a.userRepository.GetClientByID(ctx, invitation.ClientID); err == nil {
+		data["client_name"] = client.CompanyName
+		data["client_code"] = client.Code
+	}
+
+	a.notifier.Notify(ctx, notification.Notification{
+		Event:     notification.EventInvitation,
+		ClientID:  invitation.ClientID,
+		UserID:    invitation.UserID,
+		Recipient: notification.Recipient{Name: invitation.User.FullName, Email: invitation.User.Email, Phone: invitation.User.Phone},
+		Data:      data,
+	})
+}
+
+// newInvitation returns an invitation issued by actor in its client, with its token. Only the hash of
+// the token is kept on the invitation.
+func newInvitation(ctx context.Context, actor *model.User) (*entity.UserInvitation, string, AppError) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	token, err := helper.GenerateRandomString(invitationTokenLength)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GenerateRandomString  %s", err.Error())
+		return nil, "", *NewGeneralSystemError()
+	}
+
+	invitation := &entity.UserInvitation{
+		ClientID:  actor.ClientID,
+		TokenHash: hashToken(token),
+		InvitedBy: actor.ID,
+		ExpiresAt: time.Now().Add(invitationTTL),
+	}
+	return invitation, token, *NewSuccessError()
+}
+
+func toUserInvitation(invitation *entity.UserInvitation, now time.Time) *model.UserInvitation {
+	return &model.UserInvitation{
+		ID:         invitation.ID,
+		UserID:     invitation.UserID,
+		Username:   invitation.User.Username,
+		Status:     invitation.Status(now),
+		InvitedBy:  invitation.InvitedBy,
+		ExpiresAt:  invitation.ExpiresAt,
+		AcceptedAt: invitation.AcceptedAt,
+		CreatedAt:  invitation.CreatedAt,
+	}
+}
@@ -0,0 +1,167 @@
+// internal/service/user_list.go
+
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"maqhaa/auth_service/internal/app/entity"
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/repository"
+	"strings"
+	"time"
+)
+
+const (
+	defaultUserPageSize = 50
+	maxUserPageSize     = 200
+)
+
+// errInvalidUserCursor is returned for a cursor issued for another sort order.
+var errInvalidUserCursor = errors.New("invalid user cursor")
+
+// userSortColumns maps the accepted sort keys to their column.
+var userSortColumns = map[string]string{
+	"id":         "id",
+	"username":   "username",
+	"full_name":  "full_name",
+	"created_at": "created_at",
+}
+
+// userCursor is the decoded form of a next-cursor. Sort ties the cursor to the order it was issued for.
+type userCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v,omitempty"`
+	ID    uint   `json:"id"`
+}
+
+// ListUsers returns a page of the users of the caller's client (admin only).
+func (a *authServiceImpl) ListUsers(ctx context.Context, request model.ListUsersRequest, token string) (*model.UserPage, AppError) {
+	user, appError := a.Authorize(ctx, token)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	if !user.IsLogin {
+		return nil, *NewInvalidTokenError()
+	}
+
+	if !user.IsAdmin {
+		return nil, *NewUserNotAllowError()
+	}
+
+	ctx = repository.WithTenant(ctx, user.ClientID)
+
+	if request.PageSize < 0 || request.PageSize > maxUserPageSize {
+		return nil, *NewInvalidRequestError("Invalid page_size")
+	}
+	pageSize := request.PageSize
+	if pageSize == 0 {
+		pageSize = defaultUserPageSize
+	}
+
+	sort := request.Sort
+	if sort == "" {
+		sort = "id"
+	}
+	column, ok := userSortColumns[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return nil, *NewInvalidRequestError("Invalid sort")
+	}
+
+	query := repository.UserQuery{
+		Role:       request.Role,
+		IsActive:   request.IsActive,
+		OutletID:   request.OutletID,
+		Search:     strings.TrimSpace(request.Search),
+		Deleted:    request.Deleted,
+		SortColumn: column,
+		Descending: strings.HasPrefix(sort, "-"),
+		Limit:      pageSize + 1,
+	}
+
+	if request.Cursor != "" {
+		after, err := decodeUserCursor(request.Cursor, sort, column)
+		if err != nil {
+			return nil, *NewInvalidRequestError("Invalid cursor")
+		}
+		query.After = after
+	}
+
+	users, total, err := a.userRepository.ListUsers(ctx, query)
+	if err != nil {
+		return nil, *NewQueryDBError()
+	}
+
+	page := &model.UserPage{Users: []*model.User{}, Total: total}
+	if len(users) > pageSize {
+		users = users[:pageSize]
+		page.NextCursor = encodeUserCursor(users[len(users)-1], sort, column)
+	}
+	for _, u := range users {
+		page.Users = append(page.Users, toUserModel(u))
+	}
+
+	return page, *NewSuccessError()
+}
+
+// encodeUserCursor returns the cursor continuing after user in the given order.
+func encodeUserCursor(user *entity.User, sort, column string) string {
+	cursor := userCursor{Sort: sort, ID: user.ID}
+	switch column {
+	case "username":
+		cursor.Value = user.Username
+	case "full_name":
+		cursor.Value = user.FullName
+	case "created_at":
+		cursor.Value = user.CreatedAt.Format(time.RFC3339Nano)
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeUserCursor parses a cursor issued for the same order.
+func decodeUserCursor(value, sort, column string) (*repository.UserCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	var cursor userCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	if cursor.Sort != sort || cursor.ID == 0 {
+		return nil, errInvalidUserCursor
+	}
+
+	after := &repository.UserCursor{Value: cursor.Value, ID: cursor.ID}
+	if column == "created_at" {
+		createdAt, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return nil, err
+		}
+		after.Value = createdAt
+	}
+	return after, nil
+}
+
+// toUserModel converts a stored user to its API representation.
+func toUserModel(u *entity.User) *model.User {
+	return &model.User{
+		ID:            u.ID,
+		ClientID:      u.ClientID,
+		Username:      u.Username,
+		FullName:      u.FullName,
+		Email:         u.Email,
+		Phone:         u.Phone,
+		EmailVerified: u.EmailVerifiedAt != nil,
+		PhoneVerified: u.PhoneVerifiedAt != nil,
+		Role:          u.Role,
+		OutletID:      u.OutletID,
+		IsAdmin:       u.Role == entity.RoleAdminCode,
+		IsActive:      u.IsActive,
+		StatusReason:  u.StatusReason,
+	}
+}
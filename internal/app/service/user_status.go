@@ -0,0 +1,135 @@
+// internal/service/user_status.go
+
+package service
+
+import (
+	"context"
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/repository"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// erasedReason is the status reason left on erased users. The reason given for an erasure is not
+// kept since it may name the person.
+const erasedReason = "erased"
+
+// ActivateUser activates a user of the caller's client (admin only).
+func (a *authServiceImpl) ActivateUser(ctx context.Context, ID uint, request model.UserStatusRequest, token string) AppError {
+	_, appError := a.changeUserStatus(ctx, ID, AuditEventUserActivated, request, token, func(ctx context.Context, change repository.UserStatusChange) error {
+		return a.userRepository.SetUserActive(ctx, ID, true, change)
+	})
+	return appError
+}
+
+// DeactivateUser deactivates a user of the caller's client (admin only), ends its login session and
+// revokes its open invitations. The user keeps existing and can be activated again.
+func (a *authServiceImpl) DeactivateUser(ctx context.Context, ID uint, request model.UserStatusRequest, token string) AppError {
+	user, appError := a.changeUserStatus(ctx, ID, AuditEventUserDeactivated, request, token, func(ctx context.Context, change repository.UserStatusChange) error {
+		return a.userRepository.SetUserActive(ctx, ID, false, change)
+	})
+	if appError.Code != SuccessError {
+		return appError
+	}
+
+	a.revocations.Publish(RevocationEvent{
+		Type:     EventUserDeactivated,
+		UserID:   ID,
+		ClientID: user.ClientID,
+	})
+
+	return appError
+}
+
+// DeleteUser soft deletes a user of the caller's client (admin only). Deleted users are hidden from
+// listings, cannot log in or accept an invitation and can be brought back with RestoreUser.
+func (a *authServiceImpl) DeleteUser(ctx context.Context, ID uint, request model.UserStatusRequest, token string) AppError {
+	user, appError := a.changeUserStatus(ctx, ID, AuditEventUserDeleted, request, token, func(ctx context.Context, change repository.UserStatusChange) error {
+		return a.userRepository.DeleteUser(ctx, ID, change)
+	})
+	if appError.Code != SuccessError {
+		return appError
+	}
+
+	a.revocations.Publish(RevocationEvent{
+		Type:     EventUserDeactivated,
+		UserID:   ID,
+		ClientID: user.ClientID,
+	})
+
+	return appError
+}
+
+// RestoreUser restores a deleted user of the caller's client (admin only). The user comes back
+// with the active state it had when it was deleted.
+func (a *authServiceImpl) RestoreUser(ctx context.Context, ID uint, token string) AppError {
+	_, appError := a.changeUserStatus(ctx, ID, AuditEventUserRestored, model.UserStatusRequest{}, token, func(ctx context.Context, change repository.UserStatusChange) error {
+		return a.userRepository.RestoreUser(ctx, ID, change)
+	})
+	return appError
+}
+
+// EraseUser removes the personal data of a user of the caller's client for good (admin only). The
+// user, deleted or not, is left as an anonymized, deleted row so references to its id stay valid.
+// Erasure cannot be undone.
+func (a *authServiceImpl) EraseUser(ctx context.Context, ID uint, token string) AppError {
+	user, appError := a.changeUserStatus(ctx, ID, AuditEventUserErased, model.UserStatusRequest{Reason: erasedReason}, token, func(ctx context.Context, change repository.UserStatusChange) error {
+		return a.userRepository.EraseUser(ctx, ID, change)
+	})
+	if appError.Code != SuccessError {
+		return appError
+	}
+
+	a.revocations.Publish(RevocationEvent{
+		Type:     EventUserDeactivated,
+		UserID:   ID,
+		ClientID: user.ClientID,
+	})
+
+	return appError
+}
+
+// changeUserStatus authorizes an admin of the target's client and applies a status change recorded
+// with the caller as actor, in the user and as event in the audit log. It returns the caller.
+func (a *authServiceImpl) changeUserStatus(ctx context.Context, ID uint, event string, request model.UserStatusRequest, token string,
+	apply func(ctx context.Context, change repository.UserStatusChange) error) (*model.User, AppError) {
+	user, appError := a.Authorize(ctx, token)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	if !user.IsLogin {
+		return nil, *NewInvalidTokenError()
+	}
+
+	if !user.IsAdmin {
+		return nil, *NewUserNotAllowError()
+	}
+
+	ctx = repository.WithTenant(ctx, user.ClientID)
+
+	if ID == 0 {
+		return nil, *NewInvalidRequestError("Invalid UserID")
+	}
+
+	if ID == user.ID {
+		return nil, *NewInvalidRequestError("Cannot change own status")
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(request); err != nil {
+		return nil, *NewInvalidRequestError(err.Error())
+	}
+
+	err := apply(ctx, repository.UserStatusChange{ActorID: user.ID, Reason: request.Reason})
+	if err != nil {
+		if err.Error() == "record not found" {
+			return nil, *NewUserIDNotFoundError()
+		}
+		return nil, *NewUpdateQueryDBError()
+	}
+
+	a.recordAudit(ctx, auditEntry{Event: event, Actor: user, TargetID: ID, Reason: request.Reason})
+
+	return user, *NewSuccessError()
+}
@@ -4,7 +4,10 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -19,11 +22,154 @@ type DatabaseConfig struct {
 	Debug    bool
 }
 
+// OAuthConfig holds the OAuth2 token issuance configuration.
+type OAuthConfig struct {
+	AccessTokenTTL       time.Duration
+	RefreshTokenTTL      time.Duration
+	AuthorizationCodeTTL time.Duration
+	RequireServiceToken  bool
+	Issuer               string
+	SigningKeyFile       string
+	// EphemeralSigningKey lets the service start without SigningKeyFile, signing tokens with a key
+	// generated at every start. Tokens then stop verifying after a restart and differ between
+	// replicas, so it is meant for development only.
+	EphemeralSigningKey bool
+}
+
+// Validate checks that the tokens can be verified by everyone, across restarts and replicas: unless
+// EphemeralSigningKey is set, a signing key file and an HTTPS issuer are required.
+func (c OAuthConfig) Validate() error {
+	if c.EphemeralSigningKey {
+		return nil
+	}
+	if c.SigningKeyFile == "" {
+		return fmt.Errorf("oauth.signingkeyfile is required unless oauth.ephemeralsigningkey is set")
+	}
+	issuer, err := url.Parse(c.Issuer)
+	if err != nil || issuer.Scheme != "https" || issuer.Host == "" {
+		return fmt.Errorf("oauth.issuer must be the public https URL of the service, got %q", c.Issuer)
+	}
+	return nil
+}
+
+// GrpcConfig holds the gRPC server configuration.
+type GrpcConfig struct {
+	// LegacyErrors keeps reporting failures as an OK response carrying the AppError code,
+	// for callers that have not migrated to gRPC status codes yet.
+	LegacyErrors bool
+	// DedicatedPort serves gRPC on GrpcPort instead of sharing AppPort with HTTP.
+	DedicatedPort bool
+	// Reflection registers the server reflection service used by tools such as grpcurl.
+	Reflection bool
+	// HealthCheckInterval is how often the database is checked for the health service.
+	HealthCheckInterval time.Duration
+	// Gateway serves the JSON mapping of the gRPC API under /api on the HTTP server.
+	Gateway bool
+}
+
+// HTTPConfig holds the REST API configuration.
+type HTTPConfig struct {
+	// LegacyDeprecation is the date (YYYY-MM-DD) the unversioned routes were deprecated in favour of /v1.
+	LegacyDeprecation string
+	// LegacySunset is the date (YYYY-MM-DD) after which the unversioned routes may be removed.
+	LegacySunset string
+}
+
+// LegacySchedule parses the deprecation and sunset dates of the unversioned routes.
+// A date left empty is returned as the zero time.
+func (c HTTPConfig) LegacySchedule() (time.Time, time.Time, error) {
+	var dates [2]time.Time
+	for i, value := range []string{c.LegacyDeprecation, c.LegacySunset} {
+		if value == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid http legacy date %q: %v", value, err)
+		}
+		dates[i] = date
+	}
+	return dates[0], dates[1], nil
+}
+
+// NotificationConfig holds the configuration of the notifications sent to users.
+type NotificationConfig struct {
+	// Channels lists the senders every notification goes through: "log", "smtp" and/or "webhook".
+	Channels []string
+	// DefaultLocale is the template locale used when a notification has none or an unknown one.
+	DefaultLocale string
+	// TemplateDir replaces the built-in templates with <locale>/<event>.tmpl files from a directory.
+	TemplateDir string
+	// AppURL is the base URL of the user facing application, used by links in templates.
+	AppURL string
+	// LocalAppURL lets AppURL be a plain http or loopback URL, which recipients cannot open from
+	// their own device, so it is meant for development only.
+	LocalAppURL bool
+	// Workers and QueueSize size the delivery pool; notifications are dropped when the queue is full.
+	Workers   int
+	QueueSize int
+	// MaxAttempts and RetryDelay control retries, the delay doubling after each failed attempt.
+	MaxAttempts int
+	RetryDelay  time.Duration
+	// LogFile is where the log channel appends notifications, one JSON object per line. Empty writes
+	// them to the service log.
+	LogFile string
+	SMTP    SMTPConfig
+	Webhook WebhookConfig
+}
+
+// Validate checks that the links sent to users can be opened by them: unless LocalAppURL is set,
+// AppURL must be a public HTTPS URL.
+func (c NotificationConfig) Validate() error {
+	if c.LocalAppURL {
+		return nil
+	}
+	appURL, err := url.Parse(c.AppURL)
+	if err != nil || appURL.Scheme != "https" || appURL.Hostname() == "" || isLoopbackHost(appURL.Hostname()) {
+		return fmt.Errorf("notification.appurl must be the public https URL of the application, got %q", c.AppURL)
+	}
+	return nil
+}
+
+// isLoopbackHost reports whether host names the local machine.
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified())
+}
+
+// SMTPConfig holds the mail server of the smtp notification channel.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// WebhookConfig holds the endpoint of the webhook notification channel.
+type WebhookConfig struct {
+	URL string
+	// Secret signs the body with HMAC-SHA256 in the X-Signature header. Empty sends no signature.
+	Secret  string
+	Timeout time.Duration
+}
+
 // Config holds the application configuration.
 type Config struct {
-	Database DatabaseConfig
-	AppPort  string
-	GrpcPort string
+	Database     DatabaseConfig
+	OAuth        OAuthConfig
+	Grpc         GrpcConfig
+	HTTP         HTTPConfig
+	Notification NotificationConfig
+	// TrustedProxies lists the IP addresses and CIDR ranges of the proxies whose X-Forwarded-For is
+	// believed for the caller address of the audit log, over HTTP and gRPC. Include the loopback
+	// address when the gateway is served.
+	TrustedProxies []string
+	AppPort        string
+	GrpcPort       string
 }
 
 // LoadConfig loads configuration from a specified file path, environment variables, and/or config files.
@@ -0,0 +1,69 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"maqhaa/auth_service/internal/interface/grpc/interceptor"
+	pb "maqhaa/auth_service/internal/interface/grpc/model"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// PathPrefix is the path under which the gateway serves the JSON mapping of the gRPC API,
+// as declared by the google.api.http options in user.proto.
+const PathPrefix = "/api/"
+
+// NewHandler returns an HTTP handler translating JSON requests into calls to the gRPC server
+// listening on endpoint. Calls go through the gRPC server, so interceptors apply to them as well.
+// The Authorization and X-Request-Id headers are forwarded as metadata.
+func NewHandler(ctx context.Context, endpoint string) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(incomingHeader),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeader),
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{
+				UseProtoNames:   true,
+				EmitUnpopulated: true,
+			},
+			UnmarshalOptions: protojson.UnmarshalOptions{
+				DiscardUnknown: true,
+			},
+		}),
+	)
+
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if err := pb.RegisterUserHandlerFromEndpoint(ctx, mux, dialTarget(endpoint), opts); err != nil {
+		return nil, err
+	}
+	return mux, nil
+}
+
+// dialTarget turns a listen address such as ":8080" into an address that can be dialed.
+func dialTarget(address string) string {
+	if strings.HasPrefix(address, ":") {
+		return "localhost" + address
+	}
+	return address
+}
+
+// incomingHeader forwards the request id header in addition to the gateway defaults.
+func incomingHeader(key string) (string, bool) {
+	if textproto.CanonicalMIMEHeaderKey(key) == textproto.CanonicalMIMEHeaderKey(interceptor.RequestIDMetadataKey) {
+		return interceptor.RequestIDMetadataKey, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// outgoingHeader returns the request id header as is, other response metadata keeps the gateway prefix.
+func outgoingHeader(key string) (string, bool) {
+	if key == interceptor.RequestIDMetadataKey {
+		return textproto.CanonicalMIMEHeaderKey(key), true
+	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"maqhaa/auth_service/internal/app/service"
+	"strconv"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ErrorDomain identifies the auth service in the ErrorInfo detail of failed calls.
+	ErrorDomain = "auth.maqhaa"
+	// ErrorReason is the ErrorInfo reason of failures raised from an AppError.
+	ErrorReason = "APP_ERROR"
+	// ErrorCodeKey is the ErrorInfo metadata key holding the legacy AppError code.
+	ErrorCodeKey = "code"
+)
+
+// grpcCodes maps AppError codes to gRPC status codes.
+var grpcCodes = map[int]codes.Code{
+	service.GenaralSystemError:       codes.Internal,
+	service.InvalidUsername:          codes.Unauthenticated,
+	service.InvalidPassword:          codes.Unauthenticated,
+	service.InvalidClient:            codes.Unauthenticated,
+	service.UnauthorizedClient:       codes.PermissionDenied,
+	service.InvalidFormatError:       codes.InvalidArgument,
+	service.InvalidToken:             codes.Unauthenticated,
+	service.InvalidRequestError:      codes.InvalidArgument,
+	service.InvalidScope:             codes.InvalidArgument,
+	service.UnsupportedGrantType:     codes.InvalidArgument,
+	service.InvalidGrant:             codes.InvalidArgument,
+	service.InvalidRedirectURI:       codes.InvalidArgument,
+	service.UnsupportedResponseType:  codes.InvalidArgument,
+	service.UserNotAllowError:        codes.PermissionDenied,
+	service.UserNotActiveError:       codes.PermissionDenied,
+	service.DuplicateUserError:       codes.AlreadyExists,
+	service.UserIDNotFoundError:      codes.NotFound,
+	service.InvalidCursorError:       codes.OutOfRange,
+	service.ClientCodeRequiredError:  codes.InvalidArgument,
+	service.InvalidInvitationError:   codes.FailedPrecondition,
+	service.DuplicateContactError:    codes.AlreadyExists,
+	service.InvalidVerificationError: codes.FailedPrecondition,
+	service.QueryError:               codes.Unavailable,
+	service.UpdateQueryError:         codes.Unavailable,
+	service.DateCategoryNotFound:     codes.NotFound,
+}
+
+// GRPCCode returns the gRPC status code of an AppError code.
+func GRPCCode(code int) codes.Code {
+	if code == service.SuccessError {
+		return codes.OK
+	}
+	if grpcCode, ok := grpcCodes[code]; ok {
+		return grpcCode
+	}
+	return codes.Unknown
+}
+
+// statusError converts an AppError into a gRPC status error with an ErrorInfo detail
+// carrying the legacy numeric code.
+func statusError(grpcCode codes.Code, appError service.AppError) error {
+	st := status.New(grpcCode, appError.Message)
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: ErrorReason,
+		Domain: ErrorDomain,
+		Metadata: map[string]string{
+			ErrorCodeKey: strconv.Itoa(appError.Code),
+		},
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"maqhaa/library/logging"
+	"time"
+
+	pb "maqhaa/auth_service/internal/interface/grpc/model"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	defaultHealthCheckInterval = 10 * time.Second
+	healthCheckTimeout         = 2 * time.Second
+)
+
+// WatchDatabaseHealth reports SERVING on the health service while the database answers a ping and
+// NOT_SERVING otherwise, for the whole server and the User service. It blocks until ctx is done.
+func WatchDatabaseHealth(ctx context.Context, healthServer *health.Server, db *sql.DB, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	current := healthpb.HealthCheckResponse_UNKNOWN
+	for {
+		status := healthpb.HealthCheckResponse_SERVING
+		pingCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		if err := db.PingContext(pingCtx); err != nil {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+			if current != status {
+				logging.Log.Errorf("Health check database ping failed: %v", err)
+			}
+		}
+		cancel()
+
+		if current != status {
+			healthServer.SetServingStatus("", status)
+			healthServer.SetServingStatus(pb.User_ServiceDesc.ServiceName, status)
+			current = status
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
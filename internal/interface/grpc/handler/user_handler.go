@@ -1,25 +1,40 @@
 package handler
 
 import (
+	"bytes"
 	"context"
+	"maqhaa/auth_service/internal/app/model"
 	"maqhaa/auth_service/internal/app/service"
+	"maqhaa/auth_service/internal/interface/grpc/interceptor"
 	pb "maqhaa/auth_service/internal/interface/grpc/model" // Update with your actual package name
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type UserHandler struct {
-	userService service.AuthService
+	userService  service.AuthService
+	legacyErrors bool
 }
 
-func NewUserGRPCHandler(UserService service.AuthService) *UserHandler {
+// NewUserGRPCHandler creates a new UserHandler. With legacyErrors failures are returned as an OK
+// response carrying the AppError code instead of a gRPC status error.
+func NewUserGRPCHandler(UserService service.AuthService, legacyErrors bool) *UserHandler {
 	return &UserHandler{
-		userService: UserService,
+		userService:  UserService,
+		legacyErrors: legacyErrors,
 	}
 }
 func (h *UserHandler) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.GetUserResponse, error) {
-	User, appError := h.userService.Authorize(ctx, req.Token)
+	User, appError := h.userService.Authorize(ctx, userToken(ctx, req.Token))
 	var response *pb.GetUserResponse
 
 	if appError.Code != service.SuccessError {
+		if err := h.callError(appError); err != nil {
+			return nil, err
+		}
 		response = &pb.GetUserResponse{
 			Code:    int32(appError.Code),
 			Message: appError.Message,
@@ -31,12 +46,438 @@ func (h *UserHandler) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.
 	response = &pb.GetUserResponse{
 		Code:    int32(appError.Code),
 		Message: appError.Message,
-		Data: &pb.UserData{
-			Id:       uint32(User.ID),
-			ClientId: uint32(User.ClientID),
-			IsLogin:  User.IsLogin,
-			IsAdmin:  User.IsAdmin,
+		Data:    toUserData(User),
+	}
+	return response, nil
+}
+
+func (h *UserHandler) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	user, appError := h.userService.Authenticate(ctx, req.ClientCode, req.Username, req.Password)
+	response := &pb.LoginResponse{
+		Code:    int32(appError.Code),
+		Message: appError.Message,
+	}
+
+	if appError.Code != service.SuccessError {
+		if err := h.callError(appError); err != nil {
+			return nil, err
+		}
+		return response, nil
+	}
+
+	response.Data = &pb.LoginData{
+		Token:        user.Token,
+		TokenExpired: timestamppb.New(user.TokenExpired),
+	}
+	return response, nil
+}
+
+func (h *UserHandler) Logout(ctx context.Context, req *pb.LogoutRequest) (*pb.BaseResponse, error) {
+	appError := h.userService.Logout(ctx, userToken(ctx, req.Token))
+	return h.baseResponse(appError)
+}
+
+func (h *UserHandler) AddUser(ctx context.Context, req *pb.AddUserRequest) (*pb.InvitationResponse, error) {
+	request := model.AddUserRequest{
+		Username: req.Username,
+		Password: req.Password,
+		FullName: req.FullName,
+		Role:     uint(req.Role),
+		OutletID: outletID(req.OutletId),
+		Invite:   req.Invite,
+		Email:    req.Email,
+		Phone:    req.Phone,
+	}
+
+	invitation, appError := h.userService.AddUser(ctx, request, userToken(ctx, req.Token))
+	return h.invitationResponse(invitation, appError)
+}
+
+func (h *UserHandler) ImportUsers(ctx context.Context, req *pb.ImportUsersRequest) (*pb.ImportUsersResponse, error) {
+	report, appError := h.userService.ImportUsers(ctx, bytes.NewReader(req.Csv), req.DryRun, userToken(ctx, req.Token))
+	response := &pb.ImportUsersResponse{
+		Code:    int32(appError.Code),
+		Message: appError.Message,
+	}
+
+	if appError.Code != service.SuccessError {
+		if err := h.callError(appError); err != nil {
+			return nil, err
+		}
+		return response, nil
+	}
+
+	response.Data = &pb.ImportUsersReport{
+		DryRun:   report.DryRun,
+		Imported: uint32(report.Imported),
+		Failed:   uint32(report.Failed),
+	}
+	for _, row := range report.Rows {
+		response.Data.Rows = append(response.Data.Rows, &pb.ImportUsersRow{
+			Row:      uint32(row.Row),
+			Username: row.Username,
+			Status:   row.Status,
+			Error:    row.Error,
+			Password: row.Password,
+		})
+	}
+	return response, nil
+}
+
+func (h *UserHandler) EditUser(ctx context.Context, req *pb.EditUserRequest) (*pb.BaseResponse, error) {
+	request := model.EditUserRequest{
+		ID: uint(req.Id),
+		AddUserRequest: model.AddUserRequest{
+			Username: req.Username,
+			Password: req.Password,
+			FullName: req.FullName,
+			Role:     uint(req.Role),
+			OutletID: outletID(req.OutletId),
+			Email:    req.Email,
+			Phone:    req.Phone,
 		},
 	}
+
+	appError := h.userService.EditUser(ctx, request, userToken(ctx, req.Token))
+	return h.baseResponse(appError)
+}
+
+func (h *UserHandler) PatchUser(ctx context.Context, req *pb.PatchUserRequest) (*pb.BaseResponse, error) {
+	request := model.PatchUserRequest{
+		Username: req.Username,
+		Password: req.Password,
+		FullName: req.FullName,
+		Email:    req.Email,
+		Phone:    req.Phone,
+	}
+	if req.Role != nil {
+		role := uint(*req.Role)
+		request.Role = &role
+	}
+	if req.OutletId != nil {
+		outlet := uint(*req.OutletId)
+		request.OutletID = &outlet
+	}
+
+	appError := h.userService.PatchUser(ctx, uint(req.Id), request, userToken(ctx, req.Token))
+	return h.baseResponse(appError)
+}
+
+func (h *UserHandler) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	request := model.ListUsersRequest{
+		Cursor:   req.Cursor,
+		PageSize: int(req.PageSize),
+		Role:     uint(req.Role),
+		IsActive: req.IsActive,
+		OutletID: uint(req.OutletId),
+		Search:   req.Search,
+		Sort:     req.Sort,
+		Deleted:  req.Deleted,
+	}
+
+	page, appError := h.userService.ListUsers(ctx, request, userToken(ctx, req.Token))
+	response := &pb.ListUsersResponse{
+		Code:    int32(appError.Code),
+		Message: appError.Message,
+	}
+
+	if appError.Code != service.SuccessError {
+		if err := h.callError(appError); err != nil {
+			return nil, err
+		}
+		return response, nil
+	}
+
+	for _, user := range page.Users {
+		response.Data = append(response.Data, toUserData(user))
+	}
+	response.Total = page.Total
+	response.NextCursor = page.NextCursor
+	return response, nil
+}
+
+func (h *UserHandler) GetUserByID(ctx context.Context, req *pb.GetUserByIDRequest) (*pb.GetUserResponse, error) {
+	user, appError := h.userService.GetUserByID(ctx, uint(req.Id), userToken(ctx, req.Token))
+	response := &pb.GetUserResponse{
+		Code:    int32(appError.Code),
+		Message: appError.Message,
+	}
+
+	if appError.Code != service.SuccessError {
+		if err := h.callError(appError); err != nil {
+			return nil, err
+		}
+		return response, nil
+	}
+
+	response.Data = toUserData(user)
+	return response, nil
+}
+
+func (h *UserHandler) GetMe(ctx context.Context, req *pb.GetMeRequest) (*pb.GetMeResponse, error) {
+	profile, appError := h.userService.GetProfile(ctx, userToken(ctx, req.Token))
+	response := &pb.GetMeResponse{
+		Code:    int32(appError.Code),
+		Message: appError.Message,
+	}
+
+	if appError.Code != service.SuccessError {
+		if err := h.callError(appError); err != nil {
+			return nil, err
+		}
+		return response, nil
+	}
+
+	response.Data = &pb.ProfileData{
+		User:        toUserData(&profile.User),
+		ClientName:  profile.ClientName,
+		Permissions: profile.Permissions,
+	}
+	return response, nil
+}
+
+func (h *UserHandler) RequestVerification(ctx context.Context, req *pb.RequestVerificationRequest) (*pb.VerificationResponse, error) {
+	request := model.VerificationRequest{Channel: req.Channel}
+	verification, appError := h.userService.RequestVerification(ctx, request, userToken(ctx, req.Token))
+	response := &pb.VerificationResponse{
+		Code:    int32(appError.Code),
+		Message: appError.Message,
+	}
+
+	if appError.Code != service.SuccessError {
+		if err := h.callError(appError); err != nil {
+			return nil, err
+		}
+		return response, nil
+	}
+
+	response.Data = &pb.Verification{
+		Channel:   verification.Channel,
+		Target:    verification.Target,
+		ExpiresAt: timestamppb.New(verification.ExpiresAt),
+	}
+	return response, nil
+}
+
+func (h *UserHandler) ConfirmVerification(ctx context.Context, req *pb.ConfirmVerificationRequest) (*pb.BaseResponse, error) {
+	request := model.ConfirmVerificationRequest{Channel: req.Channel, Code: req.VerificationCode}
+	appError := h.userService.ConfirmVerification(ctx, request, userToken(ctx, req.Token))
+	return h.baseResponse(appError)
+}
+
+func (h *UserHandler) ActivateUser(ctx context.Context, req *pb.ActivateUserRequest) (*pb.BaseResponse, error) {
+	appError := h.userService.ActivateUser(ctx, uint(req.Id), model.UserStatusRequest{Reason: req.Reason}, userToken(ctx, req.Token))
+	return h.baseResponse(appError)
+}
+
+func (h *UserHandler) DeactivateUser(ctx context.Context, req *pb.DeactivateUserRequest) (*pb.BaseResponse, error) {
+	appError := h.userService.DeactivateUser(ctx, uint(req.Id), model.UserStatusRequest{Reason: req.Reason}, userToken(ctx, req.Token))
+	return h.baseResponse(appError)
+}
+
+func (h *UserHandler) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*pb.BaseResponse, error) {
+	appError := h.userService.DeleteUser(ctx, uint(req.Id), model.UserStatusRequest{Reason: req.Reason}, userToken(ctx, req.Token))
+	return h.baseResponse(appError)
+}
+
+func (h *UserHandler) RestoreUser(ctx context.Context, req *pb.RestoreUserRequest) (*pb.BaseResponse, error) {
+	appError := h.userService.RestoreUser(ctx, uint(req.Id), userToken(ctx, req.Token))
+	return h.baseResponse(appError)
+}
+
+func (h *UserHandler) EraseUser(ctx context.Context, req *pb.EraseUserRequest) (*pb.BaseResponse, error) {
+	appError := h.userService.EraseUser(ctx, uint(req.Id), userToken(ctx, req.Token))
+	return h.baseResponse(appError)
+}
+
+func (h *UserHandler) ListInvitations(ctx context.Context, req *pb.ListInvitationsRequest) (*pb.ListInvitationsResponse, error) {
+	invitations, appError := h.userService.ListInvitations(ctx, userToken(ctx, req.Token))
+	response := &pb.ListInvitationsResponse{
+		Code:    int32(appError.Code),
+		Message: appError.Message,
+	}
+
+	if appError.Code != service.SuccessError {
+		if err := h.callError(appError); err != nil {
+			return nil, err
+		}
+		return response, nil
+	}
+
+	for _, invitation := range invitations {
+		response.Data = append(response.Data, toInvitation(invitation))
+	}
 	return response, nil
 }
+
+func (h *UserHandler) RevokeInvitation(ctx context.Context, req *pb.RevokeInvitationRequest) (*pb.BaseResponse, error) {
+	appError := h.userService.RevokeInvitation(ctx, uint(req.Id), userToken(ctx, req.Token))
+	return h.baseResponse(appError)
+}
+
+func (h *UserHandler) ResendInvitation(ctx context.Context, req *pb.ResendInvitationRequest) (*pb.InvitationResponse, error) {
+	invitation, appError := h.userService.ResendInvitation(ctx, uint(req.Id), userToken(ctx, req.Token))
+	return h.invitationResponse(invitation, appError)
+}
+
+func (h *UserHandler) AcceptInvitation(ctx context.Context, req *pb.AcceptInvitationRequest) (*pb.BaseResponse, error) {
+	appError := h.userService.AcceptInvitation(ctx, model.AcceptInvitationRequest{
+		Token:    req.InvitationToken,
+		Password: req.Password,
+	})
+	return h.baseResponse(appError)
+}
+
+// invitationResponse builds the response of a call that may issue an invitation.
+func (h *UserHandler) invitationResponse(invitation *model.UserInvitation, appError service.AppError) (*pb.InvitationResponse, error) {
+	response := &pb.InvitationResponse{
+		Code:    int32(appError.Code),
+		Message: appError.Message,
+	}
+
+	if appError.Code != service.SuccessError {
+		if err := h.callError(appError); err != nil {
+			return nil, err
+		}
+		return response, nil
+	}
+
+	if invitation != nil {
+		response.Data = toInvitation(invitation)
+	}
+	return response, nil
+}
+
+func toInvitation(invitation *model.UserInvitation) *pb.Invitation {
+	data := &pb.Invitation{
+		Id:        uint32(invitation.ID),
+		UserId:    uint32(invitation.UserID),
+		Username:  invitation.Username,
+		Status:    invitation.Status,
+		InvitedBy: uint32(invitation.InvitedBy),
+		ExpiresAt: timestamppb.New(invitation.ExpiresAt),
+		CreatedAt: timestamppb.New(invitation.CreatedAt),
+		Token:     invitation.Token,
+	}
+	if invitation.AcceptedAt != nil {
+		data.AcceptedAt = timestamppb.New(*invitation.AcceptedAt)
+	}
+	return data
+}
+
+// WatchRevocations streams revocation events so callers can invalidate cached GetUser results.
+// A caller reconnecting with the cursor of the last event it received gets the events it missed;
+// OUT_OF_RANGE means they are no longer buffered and the whole cache must be dropped.
+func (h *UserHandler) WatchRevocations(req *pb.WatchRevocationsRequest, stream pb.User_WatchRevocationsServer) error {
+	missed, events, cancel, appError := h.userService.WatchRevocations(req.Cursor)
+	if appError.Code != service.SuccessError {
+		return statusError(GRPCCode(appError.Code), appError)
+	}
+	defer cancel()
+
+	// Headers tell the caller the subscription is in place, events published from now on are delivered
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for _, event := range missed {
+		if err := stream.Send(toRevocationEvent(event)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "subscriber too slow, reconnect with the last cursor")
+			}
+			if err := stream.Send(toRevocationEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// revocationEventTypes maps service event types to their gRPC enum.
+var revocationEventTypes = map[string]pb.RevocationEventType{
+	service.EventSessionRevoked:  pb.RevocationEventType_REVOCATION_EVENT_TYPE_SESSION_REVOKED,
+	service.EventUserDeactivated: pb.RevocationEventType_REVOCATION_EVENT_TYPE_USER_DEACTIVATED,
+	service.EventRoleChanged:     pb.RevocationEventType_REVOCATION_EVENT_TYPE_ROLE_CHANGED,
+}
+
+func toRevocationEvent(event service.RevocationEvent) *pb.RevocationEvent {
+	return &pb.RevocationEvent{
+		Cursor:     event.Cursor,
+		Type:       revocationEventTypes[event.Type],
+		UserId:     uint32(event.UserID),
+		ClientId:   uint32(event.ClientID),
+		TokenHash:  event.TokenHash,
+		OccurredAt: timestamppb.New(event.OccurredAt),
+	}
+}
+
+// userToken returns the token sent in the message, falling back to the user token the call was
+// authenticated with through the authorization metadata.
+func userToken(ctx context.Context, token string) string {
+	if token != "" {
+		return token
+	}
+	return interceptor.UserTokenFromContext(ctx)
+}
+
+// outletID converts an optional outlet id, 0 meaning none.
+func outletID(id uint32) *uint {
+	if id == 0 {
+		return nil
+	}
+	outlet := uint(id)
+	return &outlet
+}
+
+// toUserData converts a user to its gRPC representation.
+func toUserData(user *model.User) *pb.UserData {
+	data := &pb.UserData{
+		Id:            uint32(user.ID),
+		ClientId:      uint32(user.ClientID),
+		IsLogin:       user.IsLogin,
+		IsAdmin:       user.IsAdmin,
+		Username:      user.Username,
+		FullName:      user.FullName,
+		Email:         user.Email,
+		Phone:         user.Phone,
+		EmailVerified: user.EmailVerified,
+		PhoneVerified: user.PhoneVerified,
+		Role:          uint32(user.Role),
+		IsActive:      user.IsActive,
+		StatusReason:  user.StatusReason,
+	}
+	if user.OutletID != nil {
+		data.OutletId = uint32(*user.OutletID)
+	}
+	if user.TokenExpired != nil {
+		data.TokenExpired = timestamppb.New(*user.TokenExpired)
+	}
+	return data
+}
+
+// baseResponse builds the response of an RPC that returns no data.
+func (h *UserHandler) baseResponse(appError service.AppError) (*pb.BaseResponse, error) {
+	if err := h.callError(appError); err != nil {
+		return nil, err
+	}
+	return &pb.BaseResponse{
+		Code:    int32(appError.Code),
+		Message: appError.Message,
+	}, nil
+}
+
+// callError returns the gRPC status error of a failed call, or nil on success and in legacy mode.
+func (h *UserHandler) callError(appError service.AppError) error {
+	if appError.Code == service.SuccessError || h.legacyErrors {
+		return nil
+	}
+	return statusError(GRPCCode(appError.Code), appError)
+}
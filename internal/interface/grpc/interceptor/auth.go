@@ -0,0 +1,175 @@
+package interceptor
+
+import (
+	"context"
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/service"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type contextKey int
+
+const (
+	// CallerKey is the context key holding the *Caller of an authenticated call.
+	CallerKey contextKey = iota
+)
+
+// Caller is the identity behind the authorization metadata of a call: either a service
+// authenticated with a service token or a user authenticated with a user token.
+type Caller struct {
+	Service *model.ServiceIdentity
+	User    *model.User
+	Token   string
+}
+
+// AuthInterceptor authenticates the token sent in the authorization metadata, checks it against
+// the permission declared for the RPC and puts the Caller in the context. When required is false,
+// calls without a token are let through so callers sending the token in the message keep working.
+func AuthInterceptor(authService service.AuthService, oauthService service.OAuthService, required bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authorizeCall(ctx, authService, oauthService, info.FullMethod, required)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// AuthStreamInterceptor is the streaming counterpart of AuthInterceptor. Streams always require a token.
+func AuthStreamInterceptor(authService service.AuthService, oauthService service.OAuthService) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorizeCall(ss.Context(), authService, oauthService, info.FullMethod, true)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authorizeCall resolves the caller of an RPC and checks its declared permission.
+func authorizeCall(ctx context.Context, authService service.AuthService, oauthService service.OAuthService, fullMethod string, required bool) (context.Context, error) {
+	permission, ok := PermissionFor(fullMethod)
+	if !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "no permission declared for %s", fullMethod)
+	}
+	if permission.Public {
+		return ctx, nil
+	}
+
+	token := bearerToken(ctx)
+	if token == "" {
+		if required {
+			return nil, status.Error(codes.Unauthenticated, "missing authorization token")
+		}
+		return ctx, nil
+	}
+
+	caller, err := resolveCaller(ctx, authService, oauthService, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkPermission(permission, caller); err != nil {
+		return nil, err
+	}
+
+	return context.WithValue(ctx, CallerKey, caller), nil
+}
+
+// resolveCaller looks the token up as a service token first, then as a user token.
+func resolveCaller(ctx context.Context, authService service.AuthService, oauthService service.OAuthService, token string) (*Caller, error) {
+	identity, appError := oauthService.AuthorizeService(ctx, token)
+	switch appError.Code {
+	case service.SuccessError:
+		return &Caller{Service: identity, Token: token}, nil
+	case service.QueryError:
+		return nil, status.Error(codes.Unavailable, appError.Message)
+	}
+
+	user, appError := authService.Authorize(ctx, token)
+	if appError.Code == service.QueryError {
+		return nil, status.Error(codes.Unavailable, appError.Message)
+	}
+	if appError.Code != service.SuccessError {
+		return nil, status.Error(codes.Unauthenticated, appError.Message)
+	}
+	if !user.IsLogin {
+		return nil, status.Error(codes.Unauthenticated, service.InvalidTokendMessage)
+	}
+
+	return &Caller{User: user, Token: token}, nil
+}
+
+// checkPermission checks a resolved caller against the permission of an RPC.
+func checkPermission(permission Permission, caller *Caller) error {
+	if caller.Service != nil {
+		if permission.Scope != "" && !caller.Service.HasScope(permission.Scope) {
+			return status.Errorf(codes.PermissionDenied, "scope %s required", permission.Scope)
+		}
+		return nil
+	}
+
+	if !permission.Users {
+		return status.Error(codes.PermissionDenied, "a service token is required")
+	}
+	if permission.Admin && !caller.User.IsAdmin {
+		return status.Error(codes.PermissionDenied, "admin role required")
+	}
+	return nil
+}
+
+// contextStream overrides the context of a server stream.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// CallerFromContext returns the authenticated caller, if the call carried a token.
+func CallerFromContext(ctx context.Context) (*Caller, bool) {
+	caller, ok := ctx.Value(CallerKey).(*Caller)
+	return caller, ok
+}
+
+// ServiceIdentityFromContext returns the calling service, if the call carried a service token.
+func ServiceIdentityFromContext(ctx context.Context) (*model.ServiceIdentity, bool) {
+	caller, ok := CallerFromContext(ctx)
+	if !ok || caller.Service == nil {
+		return nil, false
+	}
+	return caller.Service, true
+}
+
+// UserTokenFromContext returns the user token of the call, if it was authenticated with one.
+func UserTokenFromContext(ctx context.Context) string {
+	caller, ok := CallerFromContext(ctx)
+	if !ok || caller.User == nil {
+		return ""
+	}
+	return caller.Token
+}
+
+// bearerToken extracts the token from an "authorization: Bearer <token>" metadata entry.
+func bearerToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ""
+	}
+	parts := strings.SplitN(values[0], " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(parts[1])
+}
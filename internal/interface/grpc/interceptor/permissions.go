@@ -0,0 +1,66 @@
+package interceptor
+
+import "sync"
+
+// Permission declares who may call an RPC.
+type Permission struct {
+	// Public methods need no credentials.
+	Public bool
+	// Scope is the scope a calling service must hold. Empty lets any service call the method.
+	Scope string
+	// Users allows calls authenticated with a user token.
+	Users bool
+	// Admin restricts user callers to administrators.
+	Admin bool
+}
+
+var (
+	permissionsMu sync.RWMutex
+	// permissions holds the declared permission of every RPC by full method name.
+	// Methods without a declared permission are rejected.
+	permissions = map[string]Permission{
+		"/model.User/GetUser":             {Scope: "user.read", Users: true},
+		"/model.User/Login":               {Public: true},
+		"/model.User/Logout":              {Users: true},
+		"/model.User/AddUser":             {Scope: "user.write", Users: true, Admin: true},
+		"/model.User/ImportUsers":         {Scope: "user.write", Users: true, Admin: true},
+		"/model.User/EditUser":            {Scope: "user.write", Users: true, Admin: true},
+		"/model.User/PatchUser":           {Scope: "user.write", Users: true, Admin: true},
+		"/model.User/ListUsers":           {Scope: "user.read", Users: true, Admin: true},
+		"/model.User/GetUserByID":         {Scope: "user.read", Users: true, Admin: true},
+		"/model.User/GetMe":               {Scope: "user.read", Users: true},
+		"/model.User/RequestVerification": {Scope: "user.write", Users: true},
+		"/model.User/ConfirmVerification": {Scope: "user.write", Users: true},
+		"/model.User/ActivateUser":        {Scope: "user.write", Users: true, Admin: true},
+		"/model.User/DeactivateUser":      {Scope: "user.write", Users: true, Admin: true},
+		"/model.User/DeleteUser":          {Scope: "user.write", Users: true, Admin: true},
+		"/model.User/RestoreUser":         {Scope: "user.write", Users: true, Admin: true},
+		"/model.User/EraseUser":           {Scope: "user.write", Users: true, Admin: true},
+		"/model.User/ListInvitations":     {Scope: "user.read", Users: true, Admin: true},
+		"/model.User/RevokeInvitation":    {Scope: "user.write", Users: true, Admin: true},
+		"/model.User/ResendInvitation":    {Scope: "user.write", Users: true, Admin: true},
+		"/model.User/AcceptInvitation":    {Public: true},
+		"/model.User/WatchRevocations":    {Scope: "user.read"},
+
+		// Infrastructure services stay reachable for probes and tools such as grpcurl
+		"/grpc.health.v1.Health/Check":                                   {Public: true},
+		"/grpc.health.v1.Health/Watch":                                   {Public: true},
+		"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo":      {Public: true},
+		"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo": {Public: true},
+	}
+)
+
+// RegisterPermission declares the permission of an RPC, replacing any previous declaration.
+func RegisterPermission(fullMethod string, permission Permission) {
+	permissionsMu.Lock()
+	defer permissionsMu.Unlock()
+	permissions[fullMethod] = permission
+}
+
+// PermissionFor returns the declared permission of an RPC.
+func PermissionFor(fullMethod string) (Permission, bool) {
+	permissionsMu.RLock()
+	defer permissionsMu.RUnlock()
+	permission, ok := permissions[fullMethod]
+	return permission, ok
+}
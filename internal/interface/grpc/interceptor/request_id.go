@@ -0,0 +1,93 @@
+package interceptor
+
+import (
+	"context"
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDMetadataKey is the metadata key carrying the request id of a call in both directions.
+const RequestIDMetadataKey = "x-request-id"
+
+// maxRequestIDLength bounds the caller supplied request ids written to the logs.
+const maxRequestIDLength = 128
+
+// RequestIDInterceptor takes the request id from the incoming metadata, or generates one, stores it
+// in the context under middleware.RequestIDKey, returns it in the response header and logs the call.
+// Only the method, status and duration are logged: messages carry passwords and tokens.
+func RequestIDInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	requestID := incomingRequestID(ctx)
+	startTime := time.Now()
+	ctx = context.WithValue(ctx, middleware.RequestIDKey, requestID)
+	grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadataKey, requestID))
+
+	logging.Log.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"method":     info.FullMethod,
+		"proto":      "proto-buffer",
+	}).Info("Incoming request")
+
+	resp, err := handler(ctx, req)
+
+	logging.Log.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"method":     info.FullMethod,
+		"status":     status.Code(err).String(),
+		"error":      err,
+		"duration":   time.Since(startTime).String(),
+	}).Info("Outgoing response")
+
+	return resp, err
+}
+
+// RequestIDStreamInterceptor is the streaming counterpart of RequestIDInterceptor.
+func RequestIDStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	requestID := incomingRequestID(ss.Context())
+	startTime := time.Now()
+	ctx := context.WithValue(ss.Context(), middleware.RequestIDKey, requestID)
+	ss.SetHeader(metadata.Pairs(RequestIDMetadataKey, requestID))
+
+	logging.Log.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"method":     info.FullMethod,
+		"proto":      "proto-buffer",
+	}).Info("Incoming stream")
+
+	err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+
+	logging.Log.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"method":     info.FullMethod,
+		"status":     status.Code(err).String(),
+		"error":      err,
+		"duration":   time.Since(startTime).String(),
+	}).Info("Stream closed")
+
+	return err
+}
+
+// RequestIDClientInterceptor forwards the request id of the context to the called service, so a
+// call made while serving a request is logged under the same id downstream.
+func RequestIDClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if requestID, ok := ctx.Value(middleware.RequestIDKey).(string); ok && requestID != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, RequestIDMetadataKey, requestID)
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// incomingRequestID returns the request id sent by the caller, or a new one.
+func incomingRequestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDMetadataKey); len(values) > 0 && values[0] != "" && len(values[0]) <= maxRequestIDLength {
+			return values[0]
+		}
+	}
+	return uuid.New().String()
+}
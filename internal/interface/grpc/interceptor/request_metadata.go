@@ -0,0 +1,55 @@
+package interceptor
+
+import (
+	"context"
+	"maqhaa/auth_service/internal/app/service"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// RequestMetadataInterceptor stores the address and user agent of the caller in the context for the
+// audit log (see service.WithRequestMetadata). Calls through the HTTP gateway are described by the
+// headers the gateway forwards; x-forwarded-for is only followed for calls coming from one of the
+// trusted proxies, which must include the loopback address when the gateway is served.
+func RequestMetadataInterceptor(trusted service.TrustedProxies) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(service.WithRequestMetadata(ctx, incomingRequestMetadata(ctx, trusted)), req)
+	}
+}
+
+// RequestMetadataStreamInterceptor is the streaming counterpart of RequestMetadataInterceptor.
+func RequestMetadataStreamInterceptor(trusted service.TrustedProxies) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := service.WithRequestMetadata(ss.Context(), incomingRequestMetadata(ss.Context(), trusted))
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// incomingRequestMetadata returns the metadata of the caller: its address, from x-forwarded-for
+// when the peer is a trusted proxy, else the peer address, and the user agent of the gateway
+// caller, else of the gRPC client.
+func incomingRequestMetadata(ctx context.Context, trusted service.TrustedProxies) service.RequestMetadata {
+	var result service.RequestMetadata
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var peerIP string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		peerIP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(peerIP); err == nil {
+			peerIP = host
+		}
+	}
+	result.IP = trusted.ClientIP(peerIP, md.Get("x-forwarded-for"))
+
+	for _, key := range []string{"grpcgateway-user-agent", "user-agent"} {
+		if values := md.Get(key); len(values) > 0 && values[0] != "" {
+			result.UserAgent = values[0]
+			break
+		}
+	}
+
+	return result
+}
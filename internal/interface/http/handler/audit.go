@@ -0,0 +1,129 @@
+// internal/handler/audit.go
+
+package handler
+
+import (
+	"context"
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/service"
+	"net"
+	"net/http"
+	"time"
+
+	"maqhaa/library/middleware"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// RequestIDHeader carries the request id of a call in both directions.
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLength bounds the caller supplied request ids written to the logs and the audit log.
+const maxRequestIDLength = 128
+
+// RequestMetadataMiddleware stores the request id, taken from the X-Request-Id header or generated,
+// and the address and user agent of the caller in the request context, for the logs and the audit
+// log. The request id is returned in the X-Request-Id response header. X-Forwarded-For is only
+// followed for requests coming through one of the trusted proxies.
+func RequestMetadataMiddleware(trusted service.TrustedProxies) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(RequestIDHeader)
+			if requestID == "" || len(requestID) > maxRequestIDLength {
+				requestID = uuid.New().String()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
+			ctx := context.WithValue(r.Context(), middleware.RequestIDKey, requestID)
+			ctx = service.WithRequestMetadata(ctx, service.RequestMetadata{
+				IP:        trusted.ClientIP(remoteIP(r), r.Header.Values("X-Forwarded-For")),
+				UserAgent: r.UserAgent(),
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// remoteIP returns the address of the connection of a request.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ListAuditEventsHandler handles GET /audit, a page of the audit log of the caller's client (admin only).
+func (h *AuthHandler) ListAuditEventsHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError := *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	request, appError := parseListAuditEventsRequest(r)
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	page, appError := h.authService.ListAuditEvents(r.Context(), request, token)
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, page)
+	sendJSONResponse(w, response, appError.Code)
+}
+
+// parseListAuditEventsRequest reads the paging and filter parameters of GET /audit. from and to are
+// RFC 3339 times.
+func parseListAuditEventsRequest(r *http.Request) (model.ListAuditEventsRequest, service.AppError) {
+	query := r.URL.Query()
+	request := model.ListAuditEventsRequest{
+		Cursor:  query.Get("cursor"),
+		Event:   query.Get("event"),
+		Outcome: query.Get("outcome"),
+	}
+
+	pageSize, ok := queryNumber(query, "page_size")
+	if !ok {
+		return request, *service.NewInvalidRequestError("Invalid page_size")
+	}
+	actorID, ok := queryNumber(query, "actor_id")
+	if !ok {
+		return request, *service.NewInvalidRequestError("Invalid actor_id")
+	}
+	targetID, ok := queryNumber(query, "target_id")
+	if !ok {
+		return request, *service.NewInvalidRequestError("Invalid target_id")
+	}
+	request.PageSize = pageSize
+	request.ActorID = uint(actorID)
+	request.TargetID = uint(targetID)
+
+	if value := query.Get("from"); value != "" {
+		from, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return request, *service.NewInvalidRequestError("Invalid from")
+		}
+		request.From = from
+	}
+
+	if value := query.Get("to"); value != "" {
+		to, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return request, *service.NewInvalidRequestError("Invalid to")
+		}
+		request.To = to
+	}
+
+	return request, *service.NewSuccessError()
+}
@@ -4,10 +4,12 @@ package handler
 
 import (
 	"encoding/json"
+	"io"
 	"maqhaa/auth_service/internal/app/model"
 	"maqhaa/auth_service/internal/app/service"
 	"maqhaa/library/logging"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"maqhaa/library/middleware"
@@ -48,7 +50,7 @@ func (h *AuthHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Perform user authentication
-	user, appError := h.authService.Authenticate(r.Context(), loginRequest.Username, loginRequest.Password)
+	user, appError := h.authService.Authenticate(r.Context(), loginRequest.ClientCode, loginRequest.Username, loginRequest.Password)
 	loginResponse = model.LoginResponse{
 		HTTPResponse: *model.NewHTTPResponse(appError.Code, appError.Message, nil),
 	}
@@ -96,8 +98,50 @@ func (h *AuthHandler) AddUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Perform user authentication
-	appError = h.authService.AddUser(r.Context(), addUserRequest, token)
-	response = model.NewHTTPResponse(appError.Code, appError.Message, nil)
+	invitation, appError := h.authService.AddUser(r.Context(), addUserRequest, token)
+	if invitation != nil {
+		response = model.NewHTTPResponse(appError.Code, appError.Message, invitation)
+	} else {
+		response = model.NewHTTPResponse(appError.Code, appError.Message, nil)
+	}
+	sendJSONResponse(w, response, appError.Code)
+}
+
+// maxImportSize bounds the body of a user import.
+const maxImportSize = 1 << 20
+
+// ImportUsersHandler handles POST /user/import: the body is a CSV file (header username, full_name,
+// role and optionally outlet_id, password). With dry_run=true the file is only validated.
+func (h *AuthHandler) ImportUsersHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError := *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			appError := *service.NewInvalidRequestError("Invalid dry_run")
+			response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+			sendJSONResponse(w, response, appError.Code)
+			return
+		}
+	}
+
+	report, appError := h.authService.ImportUsers(r.Context(), http.MaxBytesReader(w, r.Body, maxImportSize), dryRun, token)
+
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+	response := model.NewHTTPResponse(appError.Code, appError.Message, report)
 	sendJSONResponse(w, response, appError.Code)
 }
 
@@ -134,10 +178,12 @@ func (h *AuthHandler) EditUserHandler(w http.ResponseWriter, r *http.Request) {
 	sendJSONResponse(w, response, appError.Code)
 }
 
-func (h *AuthHandler) DeactivateUserHandler(w http.ResponseWriter, r *http.Request) {
-	// Parse the request body
-	var response *model.HTTPResponse
+// PatchUserHandler handles the HTTP request for a partial update of a user: only the fields
+// present in the body are changed.
+func (h *AuthHandler) PatchUserHandler(w http.ResponseWriter, r *http.Request) {
+	var patchUserRequest model.PatchUserRequest
 	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
 	vars := mux.Vars(r)
 	userID, err := strconv.Atoi(vars["userID"])
 
@@ -148,6 +194,15 @@ func (h *AuthHandler) DeactivateUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	err = json.NewDecoder(r.Body).Decode(&patchUserRequest)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload")
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
 	token := r.Header.Get("Token")
 
 	if token == "" {
@@ -157,12 +212,88 @@ func (h *AuthHandler) DeactivateUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Perform user authentication
-	appError = h.authService.DeactivateUser(r.Context(), uint(userID), token)
-	response = model.NewHTTPResponse(appError.Code, appError.Message, nil)
+	appError = h.authService.PatchUser(r.Context(), uint(userID), patchUserRequest, token)
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+	sendJSONResponse(w, response, appError.Code)
+}
+
+// ActivateUserHandler handles POST /user/{userID}/activate, body {"reason": "..."} (optional).
+func (h *AuthHandler) ActivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, request, token, appError := parseUserStatusRequest(r)
+	if appError.Code == service.SuccessError {
+		appError = h.authService.ActivateUser(r.Context(), userID, request, token)
+	}
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
 	sendJSONResponse(w, response, appError.Code)
 }
 
+// DeactivateUserHandler handles POST /user/{userID}/deactivate and DELETE /user/{userID}, body
+// {"reason": "..."} (optional).
+func (h *AuthHandler) DeactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, request, token, appError := parseUserStatusRequest(r)
+	if appError.Code == service.SuccessError {
+		appError = h.authService.DeactivateUser(r.Context(), userID, request, token)
+	}
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+	sendJSONResponse(w, response, appError.Code)
+}
+
+// DeleteUserHandler handles POST /user/{userID}/delete, body {"reason": "..."} (optional).
+func (h *AuthHandler) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, request, token, appError := parseUserStatusRequest(r)
+	if appError.Code == service.SuccessError {
+		appError = h.authService.DeleteUser(r.Context(), userID, request, token)
+	}
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+	sendJSONResponse(w, response, appError.Code)
+}
+
+// RestoreUserHandler handles POST /user/{userID}/restore.
+func (h *AuthHandler) RestoreUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _, token, appError := parseUserStatusRequest(r)
+	if appError.Code == service.SuccessError {
+		appError = h.authService.RestoreUser(r.Context(), userID, token)
+	}
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+	sendJSONResponse(w, response, appError.Code)
+}
+
+// EraseUserHandler handles POST /user/{userID}/erase.
+func (h *AuthHandler) EraseUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _, token, appError := parseUserStatusRequest(r)
+	if appError.Code == service.SuccessError {
+		appError = h.authService.EraseUser(r.Context(), userID, token)
+	}
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+	sendJSONResponse(w, response, appError.Code)
+}
+
+// parseUserStatusRequest reads the user id, the optional reason body and the token of a user
+// status action.
+func parseUserStatusRequest(r *http.Request) (uint, model.UserStatusRequest, string, service.AppError) {
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+	var request model.UserStatusRequest
+
+	userID, err := strconv.Atoi(mux.Vars(r)["userID"])
+	if err != nil {
+		return 0, request, "", *service.NewInvalidRequestError("Invalid userID")
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil && err != io.EOF {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload")
+		return 0, request, "", *service.NewInvalidFormatError()
+	}
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		return 0, request, "", *service.NewInvalidTokenError()
+	}
+
+	return uint(userID), request, token, *service.NewSuccessError()
+}
+
 func (h *AuthHandler) GetAllUserHandler(w http.ResponseWriter, r *http.Request) {
 	// Parse the request body
 	var response *model.HTTPResponse
@@ -189,6 +320,192 @@ func (h *AuthHandler) GetAllUserHandler(w http.ResponseWriter, r *http.Request)
 	sendJSONResponse(w, response, appError.Code)
 }
 
+// ListUsersHandler handles the HTTP request for a page of the users of the caller's client (admin only).
+// Query parameters: cursor, page_size, role, is_active, outlet_id, q (username/full name prefix), sort
+// and deleted.
+func (h *AuthHandler) ListUsersHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError := *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	request, appError := parseListUsersRequest(r)
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	page, appError := h.authService.ListUsers(r.Context(), request, token)
+
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+	response := model.NewHTTPResponse(appError.Code, appError.Message, page)
+	sendJSONResponse(w, response, appError.Code)
+}
+
+// ExportUsersHandler handles GET /user/export?format=csv|json (csv by default): every user of the
+// caller's client, streamed as a file download. Credentials are never exported.
+func (h *AuthHandler) ExportUsersHandler(w http.ResponseWriter, r *http.Request) {
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError := *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "json" {
+		appError := *service.NewInvalidRequestError("Invalid format")
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	export := newUserExportWriter(w, format)
+	appError := h.authService.ExportUsers(r.Context(), token, export.write)
+
+	if appError.Code != service.SuccessError {
+		if export.started {
+			// The file is left truncated, the status code has already been sent
+			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ExportUsers  %s", appError.Message)
+			return
+		}
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	if err := export.finish(); err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ExportUsers  %s", err.Error())
+	}
+}
+
+// parseListUsersRequest reads the paging, filter and sort query parameters of a user listing.
+func parseListUsersRequest(r *http.Request) (model.ListUsersRequest, service.AppError) {
+	query := r.URL.Query()
+	request := model.ListUsersRequest{
+		Cursor: query.Get("cursor"),
+		Search: query.Get("q"),
+		Sort:   query.Get("sort"),
+	}
+
+	pageSize, ok := queryNumber(query, "page_size")
+	if !ok {
+		return request, *service.NewInvalidRequestError("Invalid page_size")
+	}
+	role, ok := queryNumber(query, "role")
+	if !ok {
+		return request, *service.NewInvalidRequestError("Invalid role")
+	}
+	outletID, ok := queryNumber(query, "outlet_id")
+	if !ok {
+		return request, *service.NewInvalidRequestError("Invalid outlet_id")
+	}
+	request.PageSize = pageSize
+	request.Role = uint(role)
+	request.OutletID = uint(outletID)
+
+	if value := query.Get("is_active"); value != "" {
+		isActive, err := strconv.ParseBool(value)
+		if err != nil {
+			return request, *service.NewInvalidRequestError("Invalid is_active")
+		}
+		request.IsActive = &isActive
+	}
+
+	if value := query.Get("deleted"); value != "" {
+		deleted, err := strconv.ParseBool(value)
+		if err != nil {
+			return request, *service.NewInvalidRequestError("Invalid deleted")
+		}
+		request.Deleted = deleted
+	}
+
+	return request, *service.NewSuccessError()
+}
+
+// queryNumber reads an optional non-negative number from the query string, 0 when absent.
+func queryNumber(query url.Values, name string) (int, bool) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, true
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		return 0, false
+	}
+	return number, true
+}
+
+// GetUserHandler handles the HTTP request for a single user of the caller's client (admin only).
+func (h *AuthHandler) GetUserHandler(w http.ResponseWriter, r *http.Request) {
+	var appError service.AppError
+	vars := mux.Vars(r)
+	userID, err := strconv.Atoi(vars["userID"])
+
+	if err != nil {
+		appError = *service.NewInvalidRequestError("Invalid userID")
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	user, appError := h.authService.GetUserByID(r.Context(), uint(userID), token)
+
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+	response := model.NewHTTPResponse(appError.Code, appError.Message, user)
+	sendJSONResponse(w, response, appError.Code)
+}
+
+// MeHandler handles the HTTP request for the profile of the caller.
+func (h *AuthHandler) MeHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError := *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	profile, appError := h.authService.GetProfile(r.Context(), token)
+
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+	response := model.NewHTTPResponse(appError.Code, appError.Message, profile)
+	sendJSONResponse(w, response, appError.Code)
+}
+
 func (h *AuthHandler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
 	// Get the token from the request header
 	token := r.Header.Get("Token")
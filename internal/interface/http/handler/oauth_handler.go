@@ -0,0 +1,316 @@
+// internal/handler/oauth_handler.go
+
+package handler
+
+import (
+	"encoding/json"
+	"html/template"
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/service"
+	"maqhaa/library/logging"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"maqhaa/library/middleware"
+
+	"github.com/sirupsen/logrus"
+)
+
+// OAuthHandler handles HTTP requests for the OAuth2 endpoints.
+type OAuthHandler struct {
+	oauthService service.OAuthService
+}
+
+// NewOAuthHandler creates a new OAuthHandler instance.
+func NewOAuthHandler(oauthService service.OAuthService) *OAuthHandler {
+	return &OAuthHandler{
+		oauthService: oauthService,
+	}
+}
+
+// TokenHandler handles the OAuth2 token endpoint.
+func (h *OAuthHandler) TokenHandler(w http.ResponseWriter, r *http.Request) {
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+	if err := r.ParseForm(); err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload")
+		sendOAuthError(w, *service.NewInvalidFormatError())
+		return
+	}
+
+	tokenRequest := model.TokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		ClientID:     r.PostForm.Get("client_id"),
+		ClientSecret: r.PostForm.Get("client_secret"),
+		Scope:        r.PostForm.Get("scope"),
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+		RefreshToken: r.PostForm.Get("refresh_token"),
+	}
+
+	// Client authentication through HTTP Basic takes precedence over form parameters
+	if clientID, clientSecret, ok := r.BasicAuth(); ok {
+		tokenRequest.ClientID = clientID
+		tokenRequest.ClientSecret = clientSecret
+	}
+
+	tokenResponse, appError := h.oauthService.IssueToken(r.Context(), tokenRequest)
+	if appError.Code != service.SuccessError {
+		sendOAuthError(w, appError)
+		return
+	}
+
+	sendOAuthResponse(w, tokenResponse, http.StatusOK)
+}
+
+// AuthorizeHandler handles the OAuth2 authorization endpoint. GET renders the login page,
+// POST authenticates the user and redirects back to the client with an authorization code.
+func (h *OAuthHandler) AuthorizeHandler(w http.ResponseWriter, r *http.Request) {
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+	if err := r.ParseForm(); err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload")
+		renderAuthorizePage(w, http.StatusBadRequest, authorizePage{Error: service.InvalidFormatErrorMessage})
+		return
+	}
+
+	authorizeRequest := model.AuthorizeRequest{
+		ResponseType:        r.Form.Get("response_type"),
+		ClientID:            r.Form.Get("client_id"),
+		RedirectURI:         r.Form.Get("redirect_uri"),
+		Scope:               r.Form.Get("scope"),
+		State:               r.Form.Get("state"),
+		CodeChallenge:       r.Form.Get("code_challenge"),
+		CodeChallengeMethod: r.Form.Get("code_challenge_method"),
+		Nonce:               r.Form.Get("nonce"),
+	}
+	page := authorizePage{Request: authorizeRequest}
+
+	if r.Method != http.MethodPost {
+		client, appError := h.oauthService.ValidateAuthorizeRequest(r.Context(), authorizeRequest)
+		if appError.Code != service.SuccessError {
+			h.authorizeError(w, r, authorizeRequest, appError)
+			return
+		}
+		page.ClientName = client.Name
+		renderAuthorizePage(w, http.StatusOK, page)
+		return
+	}
+
+	redirectURL, appError := h.oauthService.CompleteAuthorization(r.Context(), authorizeRequest, r.PostForm.Get("username"), r.PostForm.Get("password"))
+	switch appError.Code {
+	case service.SuccessError:
+		http.Redirect(w, r, redirectURL, http.StatusFound)
+	case service.InvalidUsername, service.InvalidPassword, service.UserNotActiveError:
+		page.Error = "Invalid username or password"
+		renderAuthorizePage(w, http.StatusUnauthorized, page)
+	case service.ClientCodeRequiredError:
+		page.Error = "Enter your username as username@clientcode"
+		renderAuthorizePage(w, http.StatusUnauthorized, page)
+	default:
+		h.authorizeError(w, r, authorizeRequest, appError)
+	}
+}
+
+// IntrospectHandler handles the token introspection endpoint (RFC 7662).
+func (h *OAuthHandler) IntrospectHandler(w http.ResponseWriter, r *http.Request) {
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+	if err := r.ParseForm(); err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload")
+		sendOAuthError(w, *service.NewInvalidFormatError())
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostForm.Get("client_id")
+		clientSecret = r.PostForm.Get("client_secret")
+	}
+
+	response, appError := h.oauthService.Introspect(r.Context(), clientID, clientSecret, r.PostForm.Get("token"))
+	if appError.Code != service.SuccessError {
+		sendOAuthError(w, appError)
+		return
+	}
+
+	sendOAuthResponse(w, response, http.StatusOK)
+}
+
+// RevokeHandler handles the token revocation endpoint (RFC 7009). The token_type_hint parameter
+// is ignored, every kind of token is looked up by its value.
+func (h *OAuthHandler) RevokeHandler(w http.ResponseWriter, r *http.Request) {
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+	if err := r.ParseForm(); err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload")
+		sendOAuthError(w, *service.NewInvalidFormatError())
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostForm.Get("client_id")
+		clientSecret = r.PostForm.Get("client_secret")
+	}
+
+	appError := h.oauthService.Revoke(r.Context(), clientID, clientSecret, r.PostForm.Get("token"))
+	if appError.Code != service.SuccessError {
+		sendOAuthError(w, appError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(http.StatusOK)
+}
+
+// OpenIDConfigurationHandler serves the OpenID Connect discovery document.
+func (h *OAuthHandler) OpenIDConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.oauthService.Discovery())
+}
+
+// JWKSHandler serves the public keys used to verify ID tokens.
+func (h *OAuthHandler) JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.oauthService.JWKS())
+}
+
+// UserInfoHandler returns the claims of the user owning the bearer access token.
+func (h *OAuthHandler) UserInfoHandler(w http.ResponseWriter, r *http.Request) {
+	token := bearerToken(r)
+	if token == "" && r.Method == http.MethodPost {
+		token = r.PostFormValue("access_token")
+	}
+
+	if token == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="userinfo"`)
+		sendOAuthResponse(w, model.OAuthErrorResponse{Error: "invalid_request", ErrorDescription: service.InvalidTokendMessage}, http.StatusUnauthorized)
+		return
+	}
+
+	userInfo, appError := h.oauthService.UserInfo(r.Context(), token)
+	if appError.Code == service.InvalidToken || appError.Code == service.UserNotActiveError {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="userinfo", error="invalid_token"`)
+		sendOAuthResponse(w, model.OAuthErrorResponse{Error: "invalid_token", ErrorDescription: appError.Message}, http.StatusUnauthorized)
+		return
+	}
+	if appError.Code != service.SuccessError {
+		sendOAuthError(w, appError)
+		return
+	}
+
+	sendOAuthResponse(w, userInfo, http.StatusOK)
+}
+
+// authorizeError reports an authorization error. Errors about the client or its redirect URI are
+// rendered, as redirecting to an unverified URI would make the endpoint an open redirector.
+func (h *OAuthHandler) authorizeError(w http.ResponseWriter, r *http.Request, request model.AuthorizeRequest, appError service.AppError) {
+	code, statusCode := oauthErrorCode(appError)
+	if appError.Code == service.InvalidClient || appError.Code == service.InvalidRedirectURI ||
+		statusCode >= http.StatusInternalServerError {
+		renderAuthorizePage(w, statusCode, authorizePage{Error: appError.Message})
+		return
+	}
+
+	params := url.Values{}
+	params.Set("error", code)
+	params.Set("error_description", appError.Message)
+	if request.State != "" {
+		params.Set("state", request.State)
+	}
+	http.Redirect(w, r, service.AppendQuery(request.RedirectURI, params), http.StatusFound)
+}
+
+// sendOAuthError translates an AppError into an OAuth2 error response.
+func sendOAuthError(w http.ResponseWriter, appError service.AppError) {
+	code, statusCode := oauthErrorCode(appError)
+	if statusCode == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+
+	response := model.OAuthErrorResponse{
+		Error:            code,
+		ErrorDescription: appError.Message,
+	}
+	sendOAuthResponse(w, response, statusCode)
+}
+
+// oauthErrorCode maps an AppError to an OAuth2 error code and HTTP status.
+func oauthErrorCode(appError service.AppError) (string, int) {
+	switch appError.Code {
+	case service.InvalidClient:
+		return "invalid_client", http.StatusUnauthorized
+	case service.UnauthorizedClient:
+		return "unauthorized_client", http.StatusBadRequest
+	case service.InvalidGrant:
+		return "invalid_grant", http.StatusBadRequest
+	case service.InvalidScope:
+		return "invalid_scope", http.StatusBadRequest
+	case service.UnsupportedGrantType:
+		return "unsupported_grant_type", http.StatusBadRequest
+	case service.UnsupportedResponseType:
+		return "unsupported_response_type", http.StatusBadRequest
+	case service.InvalidFormatError, service.InvalidRequestError, service.InvalidRedirectURI:
+		return "invalid_request", http.StatusBadRequest
+	case service.QueryError, service.UpdateQueryError:
+		return "temporarily_unavailable", http.StatusServiceUnavailable
+	}
+	return "server_error", http.StatusInternalServerError
+}
+
+// authorizePage is the data rendered by the login page of the authorization endpoint.
+type authorizePage struct {
+	Request    model.AuthorizeRequest
+	ClientName string
+	Error      string
+}
+
+var authorizeTemplate = template.Must(template.New("authorize").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Maqha - Sign in</title>
+</head>
+<body>
+<h1>Sign in</h1>
+{{if .ClientName}}<p>to continue to {{.ClientName}}</p>{{end}}
+{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+{{if .Request.ClientID}}
+<form method="POST" action="/oauth/authorize">
+<input type="hidden" name="response_type" value="{{.Request.ResponseType}}">
+<input type="hidden" name="client_id" value="{{.Request.ClientID}}">
+<input type="hidden" name="redirect_uri" value="{{.Request.RedirectURI}}">
+<input type="hidden" name="scope" value="{{.Request.Scope}}">
+<input type="hidden" name="state" value="{{.Request.State}}">
+<input type="hidden" name="code_challenge" value="{{.Request.CodeChallenge}}">
+<input type="hidden" name="code_challenge_method" value="{{.Request.CodeChallengeMethod}}">
+<input type="hidden" name="nonce" value="{{.Request.Nonce}}">
+<label>Username <input type="text" name="username" autocomplete="username" required></label>
+<label>Password <input type="password" name="password" autocomplete="current-password" required></label>
+<button type="submit">Sign in</button>
+</form>
+{{end}}
+</body>
+</html>
+`))
+
+// renderAuthorizePage renders the login page of the authorization endpoint.
+func renderAuthorizePage(w http.ResponseWriter, statusCode int, page authorizePage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(statusCode)
+	authorizeTemplate.Execute(w, page)
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header.
+func bearerToken(r *http.Request) string {
+	parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(parts[1])
+}
@@ -34,3 +34,12 @@ func sendJSONResponse(w http.ResponseWriter, response interface{}, errorCode int
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(response)
 }
+
+// sendOAuthResponse sends an OAuth2 JSON response that must not be cached (RFC 6749 section 5.1).
+func sendOAuthResponse(w http.ResponseWriter, response interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,45 @@
+// internal/handler/routes.go
+
+package handler
+
+import "maqhaa/auth_service/internal/interface/http/router"
+
+// RegisterRoutesV1 registers the user and session routes of API version 1 on a router
+// returned by VERSION("v1").
+func (h *AuthHandler) RegisterRoutesV1(r router.Router) {
+	r.POST("/login", h.LoginHandler)
+	r.GET("/user", h.ListUsersHandler)
+	r.POST("/user", h.AddUserHandler)
+	r.POST("/user/import", h.ImportUsersHandler)
+	r.GET("/user/export", h.ExportUsersHandler)
+	r.GET("/user/invitations", h.ListInvitationsHandler)
+	r.POST("/user/invitations/{invitationID}/revoke", h.RevokeInvitationHandler)
+	r.POST("/invite/accept", h.AcceptInvitationHandler)
+	r.PUT("/user", h.EditUserHandler)
+	r.GET("/user/{userID}", h.GetUserHandler)
+	r.PATCH("/user/{userID}", h.PatchUserHandler)
+	r.DELETE("/user/{userID}", h.DeactivateUserHandler)
+	r.POST("/user/{userID}/activate", h.ActivateUserHandler)
+	r.POST("/user/{userID}/deactivate", h.DeactivateUserHandler)
+	r.POST("/user/{userID}/delete", h.DeleteUserHandler)
+	r.POST("/user/{userID}/restore", h.RestoreUserHandler)
+	r.POST("/user/{userID}/erase", h.EraseUserHandler)
+	r.POST("/user/{userID}/invite", h.ResendInvitationHandler)
+	r.GET("/me", h.MeHandler)
+	r.POST("/me/verification", h.RequestVerificationHandler)
+	r.POST("/me/verification/confirm", h.ConfirmVerificationHandler)
+	r.GET("/audit", h.ListAuditEventsHandler)
+	r.DELETE("/logout", h.LogoutHandler)
+}
+
+// RegisterLegacyRoutes registers the unversioned routes that predate /v1 on a router returned by
+// DEPRECATED("v1", ...). The list is frozen: new routes are only added to a version. GET /user keeps
+// returning every user of the client, unpaged, as it did before /v1.
+func (h *AuthHandler) RegisterLegacyRoutes(r router.Router) {
+	r.POST("/login", h.LoginHandler)
+	r.GET("/user", h.GetAllUserHandler)
+	r.POST("/user", h.AddUserHandler)
+	r.PUT("/user", h.EditUserHandler)
+	r.DELETE("/user/{userID}", h.DeactivateUserHandler)
+	r.DELETE("/logout", h.LogoutHandler)
+}
@@ -0,0 +1,144 @@
+// internal/handler/user_export.go
+
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"maqhaa/auth_service/internal/app/model"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// userExportColumns is the header of a CSV user export.
+var userExportColumns = []string{"id", "username", "full_name", "role", "role_name", "is_active", "outlet_id", "created_at", "last_login_at", "email", "phone"}
+
+// userExportWriter streams a user export as a CSV file or a JSON array. The response starts with
+// the first batch of users, so a failure before it can still be reported as an error response.
+type userExportWriter struct {
+	w       http.ResponseWriter
+	format  string
+	csv     *csv.Writer
+	started bool
+	rows    int
+}
+
+func newUserExportWriter(w http.ResponseWriter, format string) *userExportWriter {
+	return &userExportWriter{w: w, format: format, csv: csv.NewWriter(w)}
+}
+
+// start sends the headers and the beginning of the file.
+func (e *userExportWriter) start() error {
+	e.started = true
+	contentType := "text/csv; charset=utf-8"
+	if e.format == "json" {
+		contentType = "application/json"
+	}
+	e.w.Header().Set("Content-Type", contentType)
+	e.w.Header().Set("Content-Disposition", `attachment; filename="users.`+e.format+`"`)
+	e.w.WriteHeader(http.StatusOK)
+
+	if e.format == "json" {
+		_, err := e.w.Write([]byte("["))
+		return err
+	}
+	return e.csv.Write(userExportColumns)
+}
+
+// write appends a batch of users to the file.
+func (e *userExportWriter) write(rows []*model.UserExportRow) error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+
+	for _, row := range rows {
+		if e.format == "json" {
+			data, err := json.Marshal(row)
+			if err != nil {
+				return err
+			}
+			if e.rows > 0 {
+				data = append([]byte(","), data...)
+			}
+			if _, err := e.w.Write(data); err != nil {
+				return err
+			}
+		} else if err := e.csv.Write(userExportRecord(row)); err != nil {
+			return err
+		}
+		e.rows++
+	}
+
+	e.csv.Flush()
+	if err := e.csv.Error(); err != nil {
+		return err
+	}
+	if flusher, ok := e.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// finish ends the file, starting it first for an export without users.
+func (e *userExportWriter) finish() error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+	if e.format == "json" {
+		_, err := e.w.Write([]byte("]\n"))
+		return err
+	}
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+// userExportRecord returns the CSV record of a user.
+func userExportRecord(row *model.UserExportRow) []string {
+	outletID, lastLoginAt := "", ""
+	if row.OutletID != nil {
+		outletID = strconv.FormatUint(uint64(*row.OutletID), 10)
+	}
+	if row.LastLoginAt != nil {
+		lastLoginAt = row.LastLoginAt.Format(time.RFC3339)
+	}
+	return []string{
+		strconv.FormatUint(uint64(row.ID), 10),
+		csvText(row.Username),
+		csvText(row.FullName),
+		strconv.FormatUint(uint64(row.Role), 10),
+		row.RoleName,
+		strconv.FormatBool(row.IsActive),
+		outletID,
+		row.CreatedAt.Format(time.RFC3339),
+		lastLoginAt,
+		csvText(row.Email),
+		csvPhone(row.Phone),
+	}
+}
+
+// csvPhonePattern matches the phone numbers stored by the service, digits with an optional leading +.
+var csvPhonePattern = regexp.MustCompile(`^\+?[0-9]+$`)
+
+// csvPhone exports a valid phone number as is, so E.164 numbers are not corrupted by the formula
+// escape of csvText and can be imported again. Anything else is escaped like free text.
+func csvPhone(value string) string {
+	if csvPhonePattern.MatchString(value) {
+		return value
+	}
+	return csvText(value)
+}
+
+// csvText keeps spreadsheet applications from evaluating a user supplied value as a formula.
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
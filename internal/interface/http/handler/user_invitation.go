@@ -0,0 +1,114 @@
+// internal/handler/user_invitation.go
+
+package handler
+
+import (
+	"encoding/json"
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/service"
+	"maqhaa/library/logging"
+	"net/http"
+	"strconv"
+
+	"maqhaa/library/middleware"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// ListInvitationsHandler handles GET /user/invitations.
+func (h *AuthHandler) ListInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError := *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	invitations, appError := h.authService.ListInvitations(r.Context(), token)
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, invitations)
+	sendJSONResponse(w, response, appError.Code)
+}
+
+// RevokeInvitationHandler handles POST /user/invitations/{invitationID}/revoke.
+func (h *AuthHandler) RevokeInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError := *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	invitationID, err := strconv.Atoi(mux.Vars(r)["invitationID"])
+	if err != nil || invitationID <= 0 {
+		appError := *service.NewInvalidRequestError("Invalid invitationID")
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	appError := h.authService.RevokeInvitation(r.Context(), uint(invitationID), token)
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+	sendJSONResponse(w, response, appError.Code)
+}
+
+// ResendInvitationHandler handles POST /user/{userID}/invite: a new invitation for a pending user.
+func (h *AuthHandler) ResendInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError := *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	userID, err := strconv.Atoi(mux.Vars(r)["userID"])
+	if err != nil || userID <= 0 {
+		appError := *service.NewInvalidRequestError("Invalid userID")
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	invitation, appError := h.authService.ResendInvitation(r.Context(), uint(userID), token)
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, invitation)
+	sendJSONResponse(w, response, appError.Code)
+}
+
+// AcceptInvitationHandler handles POST /invite/accept. It needs no login: the invitation token in
+// the body is the credential.
+func (h *AuthHandler) AcceptInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	var request model.AcceptInvitationRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload")
+
+		appError := *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	appError := h.authService.AcceptInvitation(r.Context(), request)
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+	sendJSONResponse(w, response, appError.Code)
+}
@@ -0,0 +1,78 @@
+// internal/handler/user_verification.go
+
+package handler
+
+import (
+	"encoding/json"
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/service"
+	"maqhaa/library/logging"
+	"net/http"
+
+	"maqhaa/library/middleware"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RequestVerificationHandler handles POST /me/verification: a code sent to the email or phone of
+// the caller.
+func (h *AuthHandler) RequestVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError := *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	var request model.VerificationRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload")
+
+		appError := *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	verification, appError := h.authService.RequestVerification(r.Context(), request, token)
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, verification)
+	sendJSONResponse(w, response, appError.Code)
+}
+
+// ConfirmVerificationHandler handles POST /me/verification/confirm.
+func (h *AuthHandler) ConfirmVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError := *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	var request model.ConfirmVerificationRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload")
+
+		appError := *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, response, appError.Code)
+		return
+	}
+
+	appError := h.authService.ConfirmVerification(r.Context(), request, token)
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+	sendJSONResponse(w, response, appError.Code)
+}
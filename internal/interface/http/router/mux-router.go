@@ -1,46 +1,94 @@
 package router
 
 import (
+	"fmt"
 	"maqhaa/library/logging"
 	"maqhaa/library/middleware"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	//"github.com/gorilla/mux"
 )
 
-var (
-	muxDispatcher = mux.NewRouter()
-)
-
-type muxRouter struct{}
+type muxRouter struct {
+	dispatcher *mux.Router
+	// wrap decorates every handler registered on the router, used for deprecated routes
+	wrap func(f http.HandlerFunc) http.HandlerFunc
+}
 
 func NewMuxRouter() Router {
-	return &muxRouter{}
+	return &muxRouter{dispatcher: mux.NewRouter()}
 }
 
-func (*muxRouter) GET(uri string, f func(w http.ResponseWriter, r *http.Request)) {
-	muxDispatcher.HandleFunc(uri, f).Methods("GET")
+func (m *muxRouter) GET(uri string, f func(w http.ResponseWriter, r *http.Request)) {
+	m.dispatcher.HandleFunc(uri, m.handler(f)).Methods("GET")
+}
+func (m *muxRouter) POST(uri string, f func(w http.ResponseWriter, r *http.Request)) {
+	m.dispatcher.HandleFunc(uri, m.handler(f)).Methods("POST")
 }
-func (*muxRouter) POST(uri string, f func(w http.ResponseWriter, r *http.Request)) {
-	muxDispatcher.HandleFunc(uri, f).Methods("POST")
+
+func (m *muxRouter) PUT(uri string, f func(w http.ResponseWriter, r *http.Request)) {
+	m.dispatcher.HandleFunc(uri, m.handler(f)).Methods("PUT")
+}
+
+func (m *muxRouter) PATCH(uri string, f func(w http.ResponseWriter, r *http.Request)) {
+	m.dispatcher.HandleFunc(uri, m.handler(f)).Methods("PATCH")
+}
+
+func (m *muxRouter) DELETE(uri string, f func(w http.ResponseWriter, r *http.Request)) {
+	m.dispatcher.HandleFunc(uri, m.handler(f)).Methods("DELETE")
+}
+
+// PREFIX hands every request under prefix, whatever its method, to h.
+func (m *muxRouter) PREFIX(prefix string, h http.Handler) {
+	m.dispatcher.PathPrefix(prefix).Handler(h)
+}
+
+// VERSION returns a router whose routes are served under /<version>, e.g. VERSION("v1").
+func (m *muxRouter) VERSION(version string) Router {
+	return &muxRouter{
+		dispatcher: m.dispatcher.PathPrefix("/" + version).Subrouter(),
+		wrap:       m.wrap,
+	}
 }
 
-func (*muxRouter) PUT(uri string, f func(w http.ResponseWriter, r *http.Request)) {
-	muxDispatcher.HandleFunc(uri, f).Methods("PUT")
+// DEPRECATED returns a router whose routes are served at the root of this router as aliases of
+// the routes of successor. Responses carry the Deprecation (RFC 9745) and Sunset (RFC 8594) headers
+// and a Link to the same path under the successor version. Zero times leave their header out.
+func (m *muxRouter) DEPRECATED(successor string, deprecatedAt, sunset time.Time) Router {
+	return &muxRouter{
+		dispatcher: m.dispatcher,
+		wrap: func(f http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				if !deprecatedAt.IsZero() {
+					w.Header().Set("Deprecation", fmt.Sprintf("@%d", deprecatedAt.Unix()))
+				}
+				if !sunset.IsZero() {
+					w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+				}
+				w.Header().Set("Link", fmt.Sprintf("</%s/%s>; rel=\"successor-version\"", successor, strings.TrimPrefix(r.URL.Path, "/")))
+				f(w, r)
+			}
+		},
+	}
 }
 
-func (*muxRouter) DELETE(uri string, f func(w http.ResponseWriter, r *http.Request)) {
-	muxDispatcher.HandleFunc(uri, f).Methods("DELETE")
+func (m *muxRouter) handler(f func(w http.ResponseWriter, r *http.Request)) http.HandlerFunc {
+	if m.wrap == nil {
+		return f
+	}
+	return m.wrap(f)
 }
 
-func (*muxRouter) GetRouter() *mux.Router {
-	return muxDispatcher
+func (m *muxRouter) GetRouter() *mux.Router {
+	return m.dispatcher
 }
 
-func (*muxRouter) SERVE(port string) {
+func (m *muxRouter) SERVE(port string) {
 	logging.Log.Infof("Http server listen in port %s", port)
 	//muxDispatcher.Use(apmgorilla.Middleware())
-	muxDispatcher.Use(middleware.LoggingMiddleware)
-	http.ListenAndServe(port, muxDispatcher)
+	m.dispatcher.Use(middleware.LoggingMiddleware)
+	http.ListenAndServe(port, m.dispatcher)
 }
@@ -3,13 +3,18 @@ package router
 import (
 	"github.com/gorilla/mux"
 	"net/http"
+	"time"
 )
 
 type Router interface {
 	GET(uri string, f func(w http.ResponseWriter, r *http.Request))
 	POST(uri string, f func(w http.ResponseWriter, r *http.Request))
 	PUT(uri string, f func(w http.ResponseWriter, r *http.Request))
+	PATCH(uri string, f func(w http.ResponseWriter, r *http.Request))
 	DELETE(uri string, f func(w http.ResponseWriter, r *http.Request))
+	PREFIX(prefix string, h http.Handler)
+	VERSION(version string) Router
+	DEPRECATED(successor string, deprecatedAt, sunset time.Time) Router
 	SERVE(port string)
 	GetRouter() *mux.Router
 }
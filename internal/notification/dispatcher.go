@@ -0,0 +1,172 @@
+// internal/notification/dispatcher.go
+
+package notification
+
+import (
+	"context"
+	"fmt"
+	"maqhaa/auth_service/internal/config"
+	"maqhaa/library/logging"
+	"sync"
+	"time"
+
+	"maqhaa/library/middleware"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultWorkers     = 2
+	defaultQueueSize   = 100
+	defaultMaxAttempts = 3
+	defaultRetryDelay  = time.Second
+	defaultLocale      = "id"
+	// sendTimeout bounds one delivery attempt.
+	sendTimeout = 30 * time.Second
+)
+
+// Dispatcher is the Notifier delivering notifications in the background: a pool of workers renders
+// each queued notification and hands it to every sender, retrying failed attempts.
+type Dispatcher struct {
+	senders     []Sender
+	templates   *Templates
+	queue       chan Notification
+	maxAttempts int
+	retryDelay  time.Duration
+	closeOnce   sync.Once
+	wg          sync.WaitGroup
+}
+
+// New creates the dispatcher of the configured channels and starts its workers.
+func New(cfg config.NotificationConfig) (*Dispatcher, error) {
+	locale := cfg.DefaultLocale
+	if locale == "" {
+		locale = defaultLocale
+	}
+	templates, err := LoadTemplates(cfg.TemplateDir, locale, cfg.AppURL)
+	if err != nil {
+		return nil, err
+	}
+
+	senders := []Sender{}
+	for _, channel := range cfg.Channels {
+		switch channel {
+		case "log":
+			senders = append(senders, NewLogSender(cfg.LogFile))
+		case "smtp":
+			if cfg.SMTP.Host == "" || cfg.SMTP.From == "" {
+				return nil, fmt.Errorf("notification channel smtp needs smtp.host and smtp.from")
+			}
+			senders = append(senders, NewSMTPSender(cfg.SMTP))
+		case "webhook":
+			if cfg.Webhook.URL == "" {
+				return nil, fmt.Errorf("notification channel webhook needs webhook.url")
+			}
+			senders = append(senders, NewWebhookSender(cfg.Webhook))
+		default:
+			return nil, fmt.Errorf("unknown notification channel %q", channel)
+		}
+	}
+
+	return NewDispatcher(senders, templates, cfg), nil
+}
+
+// NewDispatcher creates a dispatcher delivering through senders and starts its workers. Sizes and
+// retries left at zero in cfg get defaults.
+func NewDispatcher(senders []Sender, templates *Templates, cfg config.NotificationConfig) *Dispatcher {
+	workers, queueSize := cfg.Workers, cfg.QueueSize
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	d := &Dispatcher{
+		senders:     senders,
+		templates:   templates,
+		queue:       make(chan Notification, queueSize),
+		maxAttempts: cfg.MaxAttempts,
+		retryDelay:  cfg.RetryDelay,
+	}
+	if d.maxAttempts <= 0 {
+		d.maxAttempts = defaultMaxAttempts
+	}
+	if d.retryDelay <= 0 {
+		d.retryDelay = defaultRetryDelay
+	}
+
+	for i := 0; i < workers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+	return d
+}
+
+// Notify queues a notification. When the queue is full the notification is dropped and logged
+// rather than slowing the caller down.
+func (d *Dispatcher) Notify(ctx context.Context, notification Notification) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	notification.RequestID = logID
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = time.Now()
+	}
+
+	select {
+	case d.queue <- notification:
+	default:
+		logging.Log.WithFields(logrus.Fields{"request_id": logID, "event": notification.Event, "user_id": notification.UserID}).
+			Error("Error Notify  queue full, notification dropped")
+	}
+}
+
+// Close stops accepting notifications and waits for the queued ones to be delivered. Notify must
+// not be called after Close.
+func (d *Dispatcher) Close() {
+	d.closeOnce.Do(func() {
+		close(d.queue)
+	})
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for notification := range d.queue {
+		message, err := d.templates.Render(notification)
+		if err != nil {
+			logging.Log.WithFields(logrus.Fields{"request_id": notification.RequestID, "event": notification.Event}).
+				Errorf("Error Render  %s", err.Error())
+			continue
+		}
+		for _, sender := range d.senders {
+			d.deliver(sender, message)
+		}
+	}
+}
+
+// deliver sends a message through a sender, retrying with a doubling delay.
+func (d *Dispatcher) deliver(sender Sender, message Message) {
+	delay := d.retryDelay
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		err := sender.Send(ctx, message)
+		cancel()
+		if err == nil {
+			return
+		}
+
+		fields := logrus.Fields{
+			"request_id": message.RequestID,
+			"event":      message.Event,
+			"user_id":    message.UserID,
+			"channel":    sender.Name(),
+			"attempt":    attempt,
+		}
+		if attempt == d.maxAttempts {
+			logging.Log.WithFields(fields).Errorf("Error Send  %s, notification dropped", err.Error())
+			return
+		}
+		logging.Log.WithFields(fields).Warnf("Error Send  %s, retrying", err.Error())
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
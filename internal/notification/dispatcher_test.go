@@ -0,0 +1,127 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"maqhaa/auth_service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSender fails its first `failures` attempts and records the time of every attempt. While block is
+// set, Send waits for it to be closed.
+type fakeSender struct {
+	mu       sync.Mutex
+	failures int
+	attempts []time.Time
+	block    chan struct{}
+}
+
+func (s *fakeSender) Name() string {
+	return "fake"
+}
+
+func (s *fakeSender) Send(ctx context.Context, message Message) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts = append(s.attempts, time.Now())
+	if len(s.attempts) <= s.failures {
+		return errors.New("send failed")
+	}
+	return nil
+}
+
+func (s *fakeSender) sent() []time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]time.Time{}, s.attempts...)
+}
+
+func testDispatcher(t *testing.T, sender Sender, cfg config.NotificationConfig) *Dispatcher {
+	templates, err := LoadTemplates("", defaultLocale, "https://app.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewDispatcher([]Sender{sender}, templates, cfg)
+}
+
+func testNotification() Notification {
+	return Notification{
+		Event:     EventVerificationCode,
+		UserID:    1,
+		Recipient: Recipient{Name: "Kasir", Email: "kasir@example.com"},
+		Data:      map[string]string{"code": "123456", "channel": "email", "expires_in_minutes": "10"},
+	}
+}
+
+func TestDispatcher_RetriesWithBackoff(t *testing.T) {
+	sender := &fakeSender{failures: 2}
+	retryDelay := 20 * time.Millisecond
+	dispatcher := testDispatcher(t, sender, config.NotificationConfig{Workers: 1, MaxAttempts: 3, RetryDelay: retryDelay})
+
+	dispatcher.Notify(context.Background(), testNotification())
+	dispatcher.Close()
+
+	attempts := sender.sent()
+	if assert.Len(t, attempts, 3) {
+		// The delay doubles after each failed attempt
+		assert.GreaterOrEqual(t, attempts[1].Sub(attempts[0]), retryDelay)
+		assert.GreaterOrEqual(t, attempts[2].Sub(attempts[1]), 2*retryDelay)
+	}
+}
+
+func TestDispatcher_DropsAfterMaxAttempts(t *testing.T) {
+	sender := &fakeSender{failures: 10}
+	dispatcher := testDispatcher(t, sender, config.NotificationConfig{Workers: 1, MaxAttempts: 3, RetryDelay: time.Millisecond})
+
+	dispatcher.Notify(context.Background(), testNotification())
+	dispatcher.Close()
+
+	assert.Len(t, sender.sent(), 3)
+}
+
+func TestDispatcher_NotifyDoesNotBlockWhenQueueFull(t *testing.T) {
+	sender := &fakeSender{block: make(chan struct{})}
+	dispatcher := testDispatcher(t, sender, config.NotificationConfig{Workers: 1, QueueSize: 1, MaxAttempts: 1})
+
+	// The worker holds the first notification and the queue the second, the others are dropped
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			dispatcher.Notify(context.Background(), testNotification())
+			if i == 0 {
+				// Let the worker take the first one off the queue
+				time.Sleep(50 * time.Millisecond)
+			}
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Notify blocked on a full queue")
+	}
+
+	close(sender.block)
+	dispatcher.Close()
+	assert.Len(t, sender.sent(), 2)
+}
+
+func TestDispatcher_CloseDeliversQueued(t *testing.T) {
+	sender := &fakeSender{}
+	dispatcher := testDispatcher(t, sender, config.NotificationConfig{Workers: 1, QueueSize: 10, MaxAttempts: 1})
+
+	for i := 0; i < 5; i++ {
+		dispatcher.Notify(context.Background(), testNotification())
+	}
+	dispatcher.Close()
+
+	assert.Len(t, sender.sent(), 5)
+}
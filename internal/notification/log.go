@@ -0,0 +1,57 @@
+// internal/notification/log.go
+
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"maqhaa/library/logging"
+	"os"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogSender is the channel for local development and tests: messages, secrets such as invitation
+// tokens included, are appended to a file as JSON lines. Without a file only the subject is written
+// to the service log.
+type LogSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewLogSender creates a log channel appending to path, or writing to the service log when empty.
+func NewLogSender(path string) *LogSender {
+	return &LogSender{path: path}
+}
+
+func (s *LogSender) Name() string {
+	return "log"
+}
+
+func (s *LogSender) Send(ctx context.Context, message Message) error {
+	if s.path == "" {
+		logging.Log.WithFields(logrus.Fields{
+			"request_id": message.RequestID,
+			"event":      message.Event,
+			"user_id":    message.UserID,
+			"subject":    message.Subject,
+		}).Info("Notification")
+		return nil
+	}
+
+	line, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,53 @@
+// internal/notification/notification.go
+
+package notification
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// EventInvitation is sent when a user is invited and must set its password.
+	EventInvitation = "invitation"
+	// EventVerificationCode sends the code verifying the email or phone of a user.
+	EventVerificationCode = "verification_code"
+)
+
+// Notification is a security event to tell a user about. Event and Locale select the template,
+// Data holds the values the template may use.
+type Notification struct {
+	Event     string            `json:"event"`
+	Locale    string            `json:"locale"`
+	ClientID  uint              `json:"client_id"`
+	UserID    uint              `json:"user_id"`
+	Recipient Recipient         `json:"recipient"`
+	Data      map[string]string `json:"data,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// Recipient is who a notification is for. Channels needing an address the recipient lacks skip it.
+type Recipient struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+}
+
+// Message is a notification rendered from its template, ready to be delivered.
+type Message struct {
+	Notification
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Notifier queues notifications for delivery. Notify never waits for the delivery.
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification)
+}
+
+// Sender delivers messages through one channel. A failed Send is retried.
+type Sender interface {
+	Name() string
+	Send(ctx context.Context, message Message) error
+}
@@ -0,0 +1,71 @@
+// internal/notification/smtp.go
+
+package notification
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maqhaa/auth_service/internal/config"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPSender is the email channel. Recipients without an email address are skipped.
+type SMTPSender struct {
+	cfg config.SMTPConfig
+}
+
+func NewSMTPSender(cfg config.SMTPConfig) *SMTPSender {
+	return &SMTPSender{cfg: cfg}
+}
+
+func (s *SMTPSender) Name() string {
+	return "smtp"
+}
+
+func (s *SMTPSender) Send(ctx context.Context, message Message) error {
+	if message.Recipient.Email == "" {
+		return nil
+	}
+
+	port := s.cfg.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(port))
+
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+
+	// net/smtp has no context support, the delivery runs in its own goroutine instead
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, s.cfg.From, []string{message.Recipient.Email}, s.mail(message))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// mail formats a message as a plain text email.
+func (s *SMTPSender) mail(message Message) []byte {
+	var mail bytes.Buffer
+	fmt.Fprintf(&mail, "From: %s\r\n", s.cfg.From)
+	fmt.Fprintf(&mail, "To: %s\r\n", message.Recipient.Email)
+	fmt.Fprintf(&mail, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&mail, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	mail.WriteString("MIME-Version: 1.0\r\n")
+	mail.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	mail.WriteString("\r\n")
+	mail.WriteString(message.Body)
+	return mail.Bytes()
+}
@@ -0,0 +1,98 @@
+// internal/notification/templates.go
+
+package notification
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates
+var builtinTemplates embed.FS
+
+// Templates renders notifications from <locale>/<event>.tmpl files. Each file defines a "subject"
+// and a "body" template.
+type Templates struct {
+	templates     map[string]*template.Template
+	defaultLocale string
+	appURL        string
+}
+
+// templateData is what a template is executed with.
+type templateData struct {
+	Notification
+	AppURL string
+}
+
+// LoadTemplates loads the templates of dir, or the built-in ones when dir is empty. defaultLocale
+// must have a template for every event.
+func LoadTemplates(dir, defaultLocale, appURL string) (*Templates, error) {
+	var fsys fs.FS
+	if dir != "" {
+		fsys = os.DirFS(dir)
+	} else {
+		sub, err := fs.Sub(builtinTemplates, "templates")
+		if err != nil {
+			return nil, err
+		}
+		fsys = sub
+	}
+
+	files, err := fs.Glob(fsys, "*/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Templates{templates: map[string]*template.Template{}, defaultLocale: defaultLocale, appURL: appURL}
+	for _, file := range files {
+		parsed, err := template.ParseFS(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("invalid notification template %s: %v", file, err)
+		}
+		for _, name := range []string{"subject", "body"} {
+			if parsed.Lookup(name) == nil {
+				return nil, fmt.Errorf("notification template %s has no %q template", file, name)
+			}
+		}
+		t.templates[strings.TrimSuffix(file, ".tmpl")] = parsed
+	}
+
+	for _, event := range []string{EventInvitation, EventVerificationCode} {
+		if _, ok := t.templates[path.Join(defaultLocale, event)]; !ok {
+			return nil, fmt.Errorf("no %s notification template for locale %q", event, defaultLocale)
+		}
+	}
+	return t, nil
+}
+
+// Render renders a notification in its locale, falling back to the default locale.
+func (t *Templates) Render(notification Notification) (Message, error) {
+	parsed, ok := t.templates[path.Join(notification.Locale, notification.Event)]
+	if !ok {
+		parsed, ok = t.templates[path.Join(t.defaultLocale, notification.Event)]
+	}
+	if !ok {
+		return Message{}, fmt.Errorf("no template for notification %s", notification.Event)
+	}
+
+	data := templateData{Notification: notification, AppURL: t.appURL}
+	var subject, body bytes.Buffer
+	if err := parsed.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := parsed.ExecuteTemplate(&body, "body", data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		Notification: notification,
+		Subject:      strings.TrimSpace(subject.String()),
+		Body:         strings.TrimSpace(body.String()) + "\n",
+	}, nil
+}
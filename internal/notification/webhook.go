@@ -0,0 +1,64 @@
+// internal/notification/webhook.go
+
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maqhaa/auth_service/internal/config"
+	"net/http"
+	"time"
+)
+
+// WebhookSender is the channel posting every message as JSON to an endpoint, which can deliver it
+// through channels the service has no sender for (SMS, chat, push).
+type WebhookSender struct {
+	cfg    config.WebhookConfig
+	client *http.Client
+}
+
+func NewWebhookSender(cfg config.WebhookConfig) *WebhookSender {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &WebhookSender{cfg: cfg, client: &http.Client{Timeout: timeout}}
+}
+
+func (s *WebhookSender) Name() string {
+	return "webhook"
+}
+
+// Send posts the message. Any status other than 2xx is a failure and is retried.
+func (s *WebhookSender) Send(ctx context.Context, message Message) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.cfg.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(s.cfg.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
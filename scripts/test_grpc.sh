@@ -14,13 +14,22 @@ if ! command -v grpcurl &> /dev/null; then
     go install github.com/fullstorydev/grpcurl/cmd/grpcurl@latest
 fi
 
+# gRPC is served on appport, or on grpcport when grpc.dedicatedport is true.
+# The service must run with grpc.reflection: true so grpcurl can resolve the methods.
 GRPC_ENDPOINT="${1:-localhost:50053}"
 echo "Testing gRPC endpoint: $GRPC_ENDPOINT"
 echo ""
 
+# Test 0: Health check (reports NOT_SERVING while the database is unreachable)
+echo "=== Test 0: Health Check ==="
+grpcurl -plaintext "$GRPC_ENDPOINT" grpc.health.v1.Health/Check
+grpcurl -plaintext -d '{"service": "model.User"}' "$GRPC_ENDPOINT" grpc.health.v1.Health/Check
+echo ""
+
 # Test 1: List available services
 echo "=== Test 1: List Available Services ==="
-grpcurl -plaintext "$GRPC_ENDPOINT" list || echo "Note: Service reflection might not be enabled"
+grpcurl -plaintext "$GRPC_ENDPOINT" list
+grpcurl -plaintext "$GRPC_ENDPOINT" describe model.User
 echo ""
 
 # Test 2: GetUser with valid token
@@ -58,4 +67,25 @@ grpcurl -plaintext \
   "$GRPC_ENDPOINT" model.User.GetUser
 echo ""
 
+# Test 7: Login
+echo "=== Test 7: Login ==="
+grpcurl -plaintext \
+  -d '{"username": "admin", "password": "rahasia"}' \
+  "$GRPC_ENDPOINT" model.User.Login
+echo ""
+
+# Test 8: ListUsers with admin token
+echo "=== Test 8: ListUsers with Valid Token (admin) ==="
+grpcurl -plaintext \
+  -d '{"token": "admin_token_12345"}' \
+  "$GRPC_ENDPOINT" model.User.ListUsers
+echo ""
+
+# Test 9: ListUsers with staff token
+echo "=== Test 9: ListUsers with Valid Token (staff) ==="
+grpcurl -plaintext \
+  -d '{"token": "staff_token_12345"}' \
+  "$GRPC_ENDPOINT" model.User.ListUsers
+echo ""
+
 echo "=== gRPC Tests Completed ==="
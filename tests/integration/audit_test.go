@@ -0,0 +1,225 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"maqhaa/auth_service/internal/app/entity"
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// listAuditEvents requests a page of the audit log through the v1 routes.
+func listAuditEvents(t *testing.T, token, query string) (model.HTTPResponse, *model.AuditEventPage) {
+	req, err := http.NewRequest("GET", "/v1/audit?"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", token)
+	rr := httptest.NewRecorder()
+	versionedRouter(time.Now(), time.Now()).GetRouter().ServeHTTP(rr, req)
+
+	var response struct {
+		model.HTTPResponse
+		Data *model.AuditEventPage `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return response.HTTPResponse, response.Data
+}
+
+func TestAuditLog_Login(t *testing.T) {
+	tables := []string{"audit_event", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	admin, staff := statusFixture()
+
+	var payload bytes.Buffer
+	json.NewEncoder(&payload).Encode(model.LoginRequest{Username: staff.Username, Password: "salah"})
+	req, _ := http.NewRequest("POST", "/v1/login", &payload)
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	req.RemoteAddr = "10.0.0.2:41000"
+	req.Header.Set("User-Agent", "kasir-app/1.0")
+	req.Header.Set("X-Request-Id", "audit-login-1")
+	rr := httptest.NewRecorder()
+	versionedRouter(time.Now(), time.Now()).GetRouter().ServeHTTP(rr, req)
+	assert.Equal(t, "audit-login-1", rr.Header().Get("X-Request-Id"))
+
+	// Callers that are not trusted proxies cannot forge their address
+	payload.Reset()
+	json.NewEncoder(&payload).Encode(model.LoginRequest{Username: staff.Username, Password: "salah"})
+	req, _ = http.NewRequest("POST", "/v1/login", &payload)
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	req.RemoteAddr = "192.0.2.9:41000"
+	rr = httptest.NewRecorder()
+	versionedRouter(time.Now(), time.Now()).GetRouter().ServeHTTP(rr, req)
+
+	assert.Equal(t, service.SuccessError, loginCode(t, staff.Username))
+	// Unknown usernames without a client code cannot be tied to a client and stay out of every client's log
+	assert.Equal(t, service.InvalidUsername, loginCode(t, "tidak.ada"))
+
+	response, page := listAuditEvents(t, admin.Token, "event=login")
+	assert.Equal(t, service.SuccessError, response.Code)
+	if assert.Len(t, page.Events, 3) {
+		success, forged, failure := page.Events[0], page.Events[1], page.Events[2]
+		assert.Equal(t, "192.0.2.9", forged.IP)
+
+		assert.Equal(t, entity.AuditOutcomeSuccess, success.Outcome)
+		if assert.NotNil(t, success.ActorID) {
+			assert.Equal(t, staff.ID, *success.ActorID)
+		}
+		assert.NotEmpty(t, success.RequestID)
+
+		assert.Equal(t, entity.AuditOutcomeFailure, failure.Outcome)
+		assert.Nil(t, failure.ActorID)
+		if assert.NotNil(t, failure.TargetID) {
+			assert.Equal(t, staff.ID, *failure.TargetID)
+		}
+		assert.Equal(t, staff.Username, failure.Username)
+		assert.Equal(t, service.InvalidPasswordMessage, failure.Reason)
+		assert.Equal(t, "203.0.113.7", failure.IP)
+		assert.Equal(t, "kasir-app/1.0", failure.UserAgent)
+		assert.Equal(t, "audit-login-1", failure.RequestID)
+	}
+
+	_, page = listAuditEvents(t, admin.Token, "event=login&outcome=failure")
+	assert.Len(t, page.Events, 2)
+
+	var unknown int64
+	db.Model(&entity.AuditEvent{}).Where("client_id = 0 AND username = ?", "tidak.ada").Count(&unknown)
+	assert.Equal(t, int64(1), unknown)
+
+	// With a client code, guessed usernames are recorded under that client
+	db.Model(&entity.Client{}).Where("id = ?", admin.ClientID).Update("code", "kopi")
+	_, loginResponse := requestV1(t, "POST", "/v1/login", "", model.LoginRequest{ClientCode: "KOPI", Username: "tebakan", Password: "salah"})
+	assert.Equal(t, service.InvalidUsername, loginResponse.Code)
+	assert.Equal(t, service.InvalidUsername, loginCode(t, "tebakan2@kopi"))
+
+	_, page = listAuditEvents(t, admin.Token, "event=login&outcome=failure")
+	if assert.Len(t, page.Events, 4) {
+		assert.Equal(t, "tebakan2@kopi", page.Events[0].Username)
+		assert.Equal(t, "tebakan", page.Events[1].Username)
+		assert.Nil(t, page.Events[1].TargetID)
+	}
+}
+
+func TestAuditLog_UserChanges(t *testing.T) {
+	tables := []string{"audit_event", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	admin, staff := statusFixture()
+	userPath := fmt.Sprintf("/v1/user/%d", staff.ID)
+
+	fullName := "Kepala Kasir"
+	role := uint(entity.RoleAdminCode)
+	password := "rahasia-baru"
+	_, response := requestV1(t, "PATCH", userPath, admin.Token, model.PatchUserRequest{FullName: &fullName, Role: &role, Password: &password})
+	assert.Equal(t, service.SuccessError, response.Code)
+
+	_, response = requestV1(t, "POST", userPath+"/deactivate", admin.Token, model.UserStatusRequest{Reason: "cuti panjang"})
+	assert.Equal(t, service.SuccessError, response.Code)
+
+	_, page := listAuditEvents(t, admin.Token, fmt.Sprintf("target_id=%d", staff.ID))
+	if assert.Len(t, page.Events, 3) {
+		deactivated, roleChanged, updated := page.Events[0], page.Events[1], page.Events[2]
+
+		assert.Equal(t, service.AuditEventUserDeactivated, deactivated.Event)
+		assert.Equal(t, "cuti panjang", deactivated.Reason)
+		if assert.NotNil(t, deactivated.ActorID) {
+			assert.Equal(t, admin.ID, *deactivated.ActorID)
+		}
+
+		assert.Equal(t, service.AuditEventRoleChanged, roleChanged.Event)
+		assert.Equal(t, model.FieldChange{Old: float64(entity.RoleEmployeCode), New: float64(entity.RoleAdminCode)}, roleChanged.Changes["role"])
+
+		assert.Equal(t, service.AuditEventUserUpdated, updated.Event)
+		assert.Equal(t, model.FieldChange{Old: "Sample User", New: fullName}, updated.Changes["full_name"])
+		assert.Equal(t, model.FieldChange{Old: "***", New: "***"}, updated.Changes["password"])
+		assert.Len(t, updated.Changes, 3)
+	}
+}
+
+func TestAuditLog_Scope(t *testing.T) {
+	tables := []string{"audit_event", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	adminA, adminB, employeeB := tenantFixture()
+
+	for _, reason := range []string{"satu", "dua", "tiga"} {
+		_, response := requestV1(t, "POST", fmt.Sprintf("/v1/user/%d/deactivate", employeeB.ID), adminB.Token, model.UserStatusRequest{Reason: reason})
+		assert.Equal(t, service.SuccessError, response.Code)
+	}
+
+	// Another client sees none of the events, employees cannot read the log
+	response, page := listAuditEvents(t, adminA.Token, "")
+	assert.Equal(t, service.SuccessError, response.Code)
+	assert.Empty(t, page.Events)
+	response, _ = listAuditEvents(t, employeeB.Token, "")
+	assert.Equal(t, service.UserNotAllowError, response.Code)
+
+	response, page = listAuditEvents(t, adminB.Token, "page_size=2")
+	assert.Equal(t, service.SuccessError, response.Code)
+	if assert.Len(t, page.Events, 2) {
+		assert.Equal(t, "tiga", page.Events[0].Reason)
+		assert.Equal(t, "dua", page.Events[1].Reason)
+	}
+	assert.NotEmpty(t, page.NextCursor)
+
+	_, page = listAuditEvents(t, adminB.Token, "page_size=2&cursor="+page.NextCursor)
+	if assert.Len(t, page.Events, 1) {
+		assert.Equal(t, "satu", page.Events[0].Reason)
+	}
+	assert.Empty(t, page.NextCursor)
+
+	response, _ = listAuditEvents(t, adminB.Token, "outcome=maybe")
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+	response, _ = listAuditEvents(t, adminB.Token, "from=kemarin")
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+}
+
+func TestAuditLog_Erase(t *testing.T) {
+	tables := []string{"audit_event", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	admin, staff := statusFixture()
+	userPath := fmt.Sprintf("/v1/user/%d", staff.ID)
+
+	fullName := "Kepala Kasir"
+	role := uint(entity.RoleAdminCode)
+	_, response := requestV1(t, "PATCH", userPath, admin.Token, model.PatchUserRequest{FullName: &fullName, Role: &role})
+	assert.Equal(t, service.SuccessError, response.Code)
+	assert.Equal(t, service.SuccessError, loginCode(t, staff.Username))
+
+	_, response = requestV1(t, "POST", userPath+"/erase", admin.Token, nil)
+	assert.Equal(t, service.SuccessError, response.Code)
+
+	// The events stay but no longer name the erased user
+	_, page := listAuditEvents(t, admin.Token, fmt.Sprintf("target_id=%d", staff.ID))
+	events := map[string]*model.AuditEvent{}
+	for _, event := range page.Events {
+		events[event.Event] = event
+	}
+	if assert.Contains(t, events, service.AuditEventUserUpdated) {
+		assert.Equal(t, model.FieldChange{Old: "***", New: "***"}, events[service.AuditEventUserUpdated].Changes["full_name"])
+	}
+	if assert.Contains(t, events, service.AuditEventRoleChanged) {
+		assert.Equal(t, model.FieldChange{Old: float64(entity.RoleEmployeCode), New: float64(entity.RoleAdminCode)}, events[service.AuditEventRoleChanged].Changes["role"])
+	}
+	if assert.Contains(t, events, service.AuditEventLogin) {
+		assert.Equal(t, entity.ErasedUsername(staff.ID), events[service.AuditEventLogin].Username)
+		assert.Empty(t, events[service.AuditEventLogin].IP)
+	}
+	assert.Contains(t, events, service.AuditEventUserErased)
+
+	var named int64
+	db.Model(&entity.AuditEvent{}).Where("username = ? OR changes LIKE ?", staff.Username, "%"+fullName+"%").Count(&named)
+	assert.Equal(t, int64(0), named)
+}
@@ -270,6 +270,11 @@ func TestAddUserHandler_Positive(t *testing.T) {
 	// Perform assertions based on the expected login response
 	assert.Equal(t, service.SuccessMessage, response.Message)
 	assert.Equal(t, service.SuccessError, response.Code)
+
+	// Added users can log in right away
+	var stored entity.User
+	db.Where("client_id = ? AND username = ?", client.ID, addUserRequest.Username).First(&stored)
+	assert.True(t, stored.IsActive)
 }
 
 func TestAddUserHandler_UserNotAllowed(t *testing.T) {
@@ -1286,6 +1291,8 @@ func TestLogoutHandler_Positive(t *testing.T) {
 	}
 
 	assert.NotEqual(t, userLogin.Token, userNew.Token)
+	assert.Empty(t, userNew.Token)
+	assert.NotNil(t, userNew.TokenRevokedAt)
 }
 
 func TestLogoutHandler_InvalidToken(t *testing.T) {
@@ -1552,3 +1559,337 @@ func TestGetUserGRPCHandler_Positive(t *testing.T) {
 	// ...
 
 }
+
+func TestGetUserHandler_Positive(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	admin := SampleUser(client.ID)
+	db.Create(admin)
+	staff := SampleUserCS(client.ID, "staff")
+	db.Create(staff)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/user/{userID}", authHandler.GetUserHandler).Methods("GET")
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("/user/%d", staff.ID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", admin.Token)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response struct {
+		model.HTTPResponse
+		Data *model.User `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, service.SuccessError, response.Code)
+	assert.Equal(t, staff.ID, response.Data.ID)
+	assert.Equal(t, staff.Username, response.Data.Username)
+	assert.Equal(t, staff.Role, response.Data.Role)
+}
+
+func TestGetUserHandler_OtherClient(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	admin := SampleUser(client.ID)
+	db.Create(admin)
+	otherClient := SampleClient()
+	otherClient.Token = "other-client-token"
+	db.Create(otherClient)
+	other := SampleUserCS(otherClient.ID, "other")
+	db.Create(other)
+	staff := SampleUserCS(client.ID, "staff")
+	db.Create(staff)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/user/{userID}", authHandler.GetUserHandler).Methods("GET")
+
+	request := func(userID uint, token string) model.HTTPResponse {
+		req, err := http.NewRequest("GET", fmt.Sprintf("/user/%d", userID), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Token", token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		var response model.HTTPResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+
+	// Users of another client are reported as not found
+	assert.Equal(t, service.UserIDNotFoundError, request(other.ID, admin.Token).Code)
+	// Only admins may read other users
+	assert.Equal(t, service.UserNotAllowError, request(admin.ID, staff.Token).Code)
+}
+
+func TestMeHandler_Positive(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	staff := SampleUserCS(client.ID, "staff")
+	db.Create(staff)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/me", authHandler.MeHandler).Methods("GET")
+
+	req, err := http.NewRequest("GET", "/me", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", staff.Token)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response struct {
+		model.HTTPResponse
+		Data *model.Profile `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, service.SuccessError, response.Code)
+	assert.Equal(t, staff.ID, response.Data.ID)
+	assert.Equal(t, uint(entity.RoleEmployeCode), response.Data.Role)
+	assert.Equal(t, client.CompanyName, response.Data.ClientName)
+	assert.Equal(t, []string{"profile.read"}, response.Data.Permissions)
+	assert.WithinDuration(t, staff.TokenExpired, *response.Data.TokenExpired, time.Second)
+}
+
+func TestMeHandler_InvalidToken(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/me", authHandler.MeHandler).Methods("GET")
+
+	req, err := http.NewRequest("GET", "/me", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", "unknown")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+// listUsers calls GET /user with the given query string and returns the page.
+func listUsers(t *testing.T, token, query string) (int, model.HTTPResponse, *model.UserPage) {
+	router := mux.NewRouter()
+	router.HandleFunc("/user", authHandler.ListUsersHandler).Methods("GET")
+
+	req, err := http.NewRequest("GET", "/user?"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", token)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	var response struct {
+		model.HTTPResponse
+		Data *model.UserPage `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return rr.Code, response.HTTPResponse, response.Data
+}
+
+func TestListUsersHandler_Pagination(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	admin := SampleUser(client.ID)
+	db.Create(admin)
+	for _, username := range []string{"user1", "user12", "user3", "user4", "user5"} {
+		db.Create(SampleUserCS(client.ID, username))
+	}
+
+	var usernames []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		code, response, page := listUsers(t, admin.Token, "page_size=2&sort=-username&cursor="+cursor)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, service.SuccessError, response.Code)
+		assert.Equal(t, int64(6), page.Total)
+		for _, user := range page.Users {
+			usernames = append(usernames, user.Username)
+		}
+		if page.NextCursor == "" {
+			assert.Equal(t, 2, pages)
+			break
+		}
+		cursor = page.NextCursor
+	}
+	assert.Equal(t, []string{"user5", "user4", "user3", "user12", "user1", "sample"}, usernames)
+
+	// A cursor only continues the order it was issued for
+	_, _, page := listUsers(t, admin.Token, "page_size=2&sort=-username")
+	code, response, _ := listUsers(t, admin.Token, "page_size=2&sort=username&cursor="+page.NextCursor)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+}
+
+func TestListUsersHandler_Filters(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	admin := SampleUser(client.ID)
+	db.Create(admin)
+	outlet := uint(7)
+	for _, username := range []string{"user1", "user12", "user3"} {
+		user := SampleUserCS(client.ID, username)
+		user.OutletID = &outlet
+		db.Create(user)
+	}
+	inactive := SampleUserCS(client.ID, "inactive")
+	inactive.FullName = "User Lama"
+	db.Create(inactive)
+	db.Model(inactive).Update("is_active", false)
+
+	_, _, page := listUsers(t, admin.Token, "role=2")
+	assert.Equal(t, int64(4), page.Total)
+
+	_, _, page = listUsers(t, admin.Token, "outlet_id=7")
+	assert.Equal(t, int64(3), page.Total)
+	assert.Equal(t, outlet, *page.Users[0].OutletID)
+
+	_, _, page = listUsers(t, admin.Token, "is_active=false")
+	assert.Equal(t, int64(1), page.Total)
+	assert.Equal(t, "inactive", page.Users[0].Username)
+	assert.False(t, page.Users[0].IsActive)
+
+	// Prefix search on username or full name, ignoring case
+	_, _, page = listUsers(t, admin.Token, "q=USER1")
+	assert.Equal(t, int64(2), page.Total)
+	_, _, page = listUsers(t, admin.Token, "q=user%20la")
+	assert.Equal(t, int64(1), page.Total)
+	_, _, page = listUsers(t, admin.Token, "q=%25")
+	assert.Equal(t, int64(0), page.Total)
+
+	code, response, _ := listUsers(t, admin.Token, "sort=password")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+
+	code, response, _ = listUsers(t, admin.Token, "page_size=1000")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+}
+
+// patchUser calls PATCH /user/{userID} with a raw JSON body.
+func patchUser(t *testing.T, token string, userID uint, body string) (int, model.HTTPResponse) {
+	router := mux.NewRouter()
+	router.HandleFunc("/user/{userID}", authHandler.PatchUserHandler).Methods("PATCH")
+
+	req, err := http.NewRequest("PATCH", fmt.Sprintf("/user/%d", userID), bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", token)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	var response model.HTTPResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return rr.Code, response
+}
+
+func TestPatchUserHandler_Positive(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	admin := SampleUser(client.ID)
+	db.Create(admin)
+	staff := SampleUserCS(client.ID, "staff")
+	hashedPassword, _ := helper.HashPassword(staff.Password)
+	staff.Password = hashedPassword
+	outlet := uint(3)
+	staff.OutletID = &outlet
+	db.Create(staff)
+
+	// Renaming leaves the password alone
+	code, response := patchUser(t, admin.Token, staff.ID, `{"fullName":"Staff Baru"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, service.SuccessError, response.Code)
+
+	var stored entity.User
+	db.First(&stored, staff.ID)
+	assert.Equal(t, "Staff Baru", stored.FullName)
+	assert.Equal(t, "staff", stored.Username)
+	assert.Equal(t, staff.Password, stored.Password)
+	assert.Equal(t, uint(entity.RoleEmployeCode), stored.Role)
+
+	// Role and password change, outlet assignment removed
+	code, _ = patchUser(t, admin.Token, staff.ID, `{"role":1,"password":"baru123","outlet_id":0}`)
+	assert.Equal(t, http.StatusOK, code)
+
+	db.First(&stored, staff.ID)
+	assert.Equal(t, uint(entity.RoleAdminCode), stored.Role)
+	assert.Nil(t, stored.OutletID)
+	assert.NoError(t, helper.CompareHashAndPassword(stored.Password, "baru123"))
+}
+
+func TestPatchUserHandler_Invalid(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	admin := SampleUser(client.ID)
+	db.Create(admin)
+	staff := SampleUserCS(client.ID, "staff")
+	db.Create(staff)
+	otherClient := SampleClient()
+	otherClient.Token = "other-client-token"
+	db.Create(otherClient)
+	other := SampleUserCS(otherClient.ID, "other")
+	db.Create(other)
+
+	// Roles outside the catalogue are rejected
+	code, response := patchUser(t, admin.Token, staff.ID, `{"role":9}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+
+	code, response = patchUser(t, admin.Token, staff.ID, `{}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+
+	_, response = patchUser(t, admin.Token, staff.ID, `{"username":"sample"}`)
+	assert.Equal(t, service.DuplicateUserError, response.Code)
+
+	_, response = patchUser(t, admin.Token, other.ID, `{"fullName":"Bukan Staff"}`)
+	assert.Equal(t, service.UserIDNotFoundError, response.Code)
+
+	_, response = patchUser(t, staff.Token, admin.ID, `{"fullName":"Bukan Admin"}`)
+	assert.Equal(t, service.UserNotAllowError, response.Code)
+
+	var stored entity.User
+	db.First(&stored, staff.ID)
+	assert.Equal(t, uint(entity.RoleEmployeCode), stored.Role)
+	assert.Equal(t, "staff", stored.Username)
+}
@@ -0,0 +1,124 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"maqhaa/auth_service/internal/app/service"
+	"maqhaa/library/helper"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// requestGateway sends a JSON request to the gRPC gateway.
+func requestGateway(t *testing.T, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, path, &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-Id", "gateway-"+method)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rr := httptest.NewRecorder()
+	gatewayHandler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestGateway_Positive(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	admin := SampleUser(client.ID)
+	hashedPassword, _ := helper.HashPassword(admin.Password)
+	admin.Password = hashedPassword
+	db.Create(admin)
+
+	rr := requestGateway(t, http.MethodPost, "/api/login", "", map[string]string{"username": admin.Username, "password": "rahasia"})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var login struct {
+		Code int `json:"code"`
+		Data struct {
+			Token        string `json:"token"`
+			TokenExpired string `json:"token_expired"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &login); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, service.SuccessError, login.Code)
+	assert.NotEmpty(t, login.Data.Token)
+	assert.NotEmpty(t, login.Data.TokenExpired)
+
+	// The token is taken from the Authorization header and the request id is echoed back
+	rr = requestGateway(t, http.MethodGet, "/api/user", login.Data.Token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "gateway-GET", rr.Header().Get("X-Request-Id"))
+	var user struct {
+		Data struct {
+			ID       uint   `json:"id"`
+			Username string `json:"username"`
+			FullName string `json:"full_name"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &user); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, admin.ID, user.Data.ID)
+	assert.Equal(t, admin.Username, user.Data.Username)
+	assert.Equal(t, admin.FullName, user.Data.FullName)
+
+	rr = requestGateway(t, http.MethodPost, "/api/users", login.Data.Token, map[string]interface{}{
+		"username":  "kasir1",
+		"password":  "kasir123",
+		"full_name": "Kasir Satu",
+		"role":      2,
+	})
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = requestGateway(t, http.MethodGet, "/api/users", login.Data.Token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var users struct {
+		Data []struct {
+			Username string `json:"username"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &users); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, users.Data, 2)
+}
+
+func TestGateway_Errors(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	admin := SampleUser(client.ID)
+	db.Create(admin)
+
+	// gRPC status codes are translated to HTTP status codes
+	rr := requestGateway(t, http.MethodGet, fmt.Sprintf("/api/users/%d", 99999), admin.Token, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = requestGateway(t, http.MethodGet, "/api/user", "unknown", nil)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	rr = requestGateway(t, http.MethodGet, "/api/unknown", admin.Token, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
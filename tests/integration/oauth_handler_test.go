@@ -0,0 +1,781 @@
+package handler_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"maqhaa/auth_service/internal/app/entity"
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/service"
+	"maqhaa/library/helper"
+	"maqhaa/library/logging"
+
+	"maqhaa/library/middleware"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcMetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "maqhaa/auth_service/internal/interface/grpc/model"
+)
+
+func requestServiceToken(t *testing.T, form url.Values, clientID, clientSecret string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("POST", "/oauth/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if clientID != "" {
+		req.SetBasicAuth(clientID, clientSecret)
+	}
+	requestID := uuid.New().String()
+	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, requestID)
+	req = req.WithContext(ctx)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(oauthHandler.TokenHandler).ServeHTTP(rr, req)
+	logging.Log.WithFields(logrus.Fields{
+		"RequestID": requestID,
+		"Status":    rr.Code,
+		"Body":      rr.Body.String(),
+	}).Info("Outgoing response")
+	return rr
+}
+
+func TestTokenHandler_ClientCredentials_Positive(t *testing.T) {
+	tables := []string{"oauth_token", "oauth_client"}
+	defer clearDB(tables)
+
+	oauthClient := SampleOAuthClient("order-service", "user.read order.write")
+	db.Create(oauthClient)
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("scope", "user.read")
+	rr := requestServiceToken(t, form, oauthClient.ClientID, "service-secret")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+
+	var response model.TokenResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotEmpty(t, response.AccessToken)
+	assert.Equal(t, "Bearer", response.TokenType)
+	assert.Equal(t, "user.read", response.Scope)
+	assert.True(t, response.ExpiresIn > 0)
+}
+
+func TestTokenHandler_ClientCredentials_InvalidClient(t *testing.T) {
+	tables := []string{"oauth_token", "oauth_client"}
+	defer clearDB(tables)
+
+	oauthClient := SampleOAuthClient("order-service", "user.read")
+	db.Create(oauthClient)
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	rr := requestServiceToken(t, form, oauthClient.ClientID, "wrong-secret")
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	var response model.OAuthErrorResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "invalid_client", response.Error)
+}
+
+func TestTokenHandler_ClientCredentials_InvalidScope(t *testing.T) {
+	tables := []string{"oauth_token", "oauth_client"}
+	defer clearDB(tables)
+
+	oauthClient := SampleOAuthClient("order-service", "user.read")
+	db.Create(oauthClient)
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("scope", "user.write")
+	rr := requestServiceToken(t, form, oauthClient.ClientID, "service-secret")
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var response model.OAuthErrorResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "invalid_scope", response.Error)
+}
+
+func TestTokenHandler_UnsupportedGrantType(t *testing.T) {
+	form := url.Values{}
+	form.Set("grant_type", "password")
+	rr := requestServiceToken(t, form, "", "")
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var response model.OAuthErrorResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "unsupported_grant_type", response.Error)
+}
+
+func TestGetUserGRPCHandler_ServiceToken(t *testing.T) {
+	tables := []string{"oauth_token", "oauth_client", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+
+	userLogin := SampleUser(client.ID)
+	hashedPassword, _ := helper.HashPassword(userLogin.Password)
+	userLogin.Password = hashedPassword
+	db.Create(userLogin)
+
+	reader := SampleOAuthClient("order-service", "user.read")
+	db.Create(reader)
+	other := SampleOAuthClient("report-service", "report.read")
+	db.Create(other)
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	var readerToken, otherToken model.TokenResponse
+	rr := requestServiceToken(t, form, reader.ClientID, "service-secret")
+	if err := json.Unmarshal(rr.Body.Bytes(), &readerToken); err != nil {
+		t.Fatal(err)
+	}
+	rr = requestServiceToken(t, form, other.ClientID, "service-secret")
+	if err := json.Unmarshal(rr.Body.Bytes(), &otherToken); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := grpc.Dial("localhost:50051", grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Error creating gRPC client connection: %v", err)
+	}
+	defer conn.Close()
+
+	clientServer := pb.NewUserClient(conn)
+	reqRpc := &pb.GetUserRequest{
+		Token: userLogin.Token,
+	}
+
+	// A service holding the user.read scope is allowed
+	ctx := grpcMetadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+readerToken.AccessToken)
+	resp, err := clientServer.GetUser(ctx, reqRpc)
+	if err != nil {
+		t.Fatalf("Error calling GetUser gRPC method: %v", err)
+	}
+	assert.Equal(t, uint32(userLogin.ID), resp.Data.Id)
+
+	// A service without the scope is rejected
+	ctx = grpcMetadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+otherToken.AccessToken)
+	_, err = clientServer.GetUser(ctx, reqRpc)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// An unknown token is rejected
+	ctx = grpcMetadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer unknown")
+	_, err = clientServer.GetUser(ctx, reqRpc)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+const backofficeRedirectURI = "https://backoffice.example.com/callback"
+
+func pkcePair() (string, string) {
+	verifier, _ := helper.GenerateRandomString(64)
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func requestAuthorize(t *testing.T, method string, form url.Values) *httptest.ResponseRecorder {
+	var req *http.Request
+	var err error
+	if method == "GET" {
+		req, err = http.NewRequest(method, "/oauth/authorize?"+form.Encode(), nil)
+	} else {
+		req, err = http.NewRequest(method, "/oauth/authorize", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	requestID := uuid.New().String()
+	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, requestID)
+	req = req.WithContext(ctx)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(oauthHandler.AuthorizeHandler).ServeHTTP(rr, req)
+	logging.Log.WithFields(logrus.Fields{
+		"RequestID": requestID,
+		"Status":    rr.Code,
+		"Body":      rr.Body.String(),
+	}).Info("Outgoing response")
+	return rr
+}
+
+func authorizeForm(clientID, challenge string) url.Values {
+	form := url.Values{}
+	form.Set("response_type", "code")
+	form.Set("client_id", clientID)
+	form.Set("redirect_uri", backofficeRedirectURI)
+	form.Set("scope", "profile")
+	form.Set("state", "xyz")
+	form.Set("code_challenge", challenge)
+	form.Set("code_challenge_method", "S256")
+	return form
+}
+
+// authorizationCode signs the user in through the authorization endpoint and returns the issued code.
+func authorizationCode(t *testing.T, clientID, challenge string) string {
+	user := SampleUser(0)
+	form := authorizeForm(clientID, challenge)
+	form.Set("username", user.Username)
+	form.Set("password", user.Password)
+	rr := requestAuthorize(t, "POST", form)
+	assert.Equal(t, http.StatusFound, rr.Code)
+
+	location, err := url.Parse(rr.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "xyz", location.Query().Get("state"))
+	return location.Query().Get("code")
+}
+
+func TestAuthorizeHandler_RendersLoginPage(t *testing.T) {
+	tables := []string{"oauth_client"}
+	defer clearDB(tables)
+
+	oauthClient := SamplePublicOAuthClient("backoffice-web", backofficeRedirectURI)
+	db.Create(oauthClient)
+
+	_, challenge := pkcePair()
+	rr := requestAuthorize(t, "GET", authorizeForm(oauthClient.ClientID, challenge))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `name="password"`)
+	assert.Contains(t, rr.Body.String(), oauthClient.Name)
+}
+
+func TestAuthorizeHandler_UnregisteredRedirectURI(t *testing.T) {
+	tables := []string{"oauth_client"}
+	defer clearDB(tables)
+
+	oauthClient := SamplePublicOAuthClient("backoffice-web", backofficeRedirectURI)
+	db.Create(oauthClient)
+
+	_, challenge := pkcePair()
+	form := authorizeForm(oauthClient.ClientID, challenge)
+	form.Set("redirect_uri", "https://evil.example.com/callback")
+	rr := requestAuthorize(t, "GET", form)
+
+	// The error is shown to the user instead of redirecting to an unregistered URI
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Empty(t, rr.Header().Get("Location"))
+	assert.Contains(t, rr.Body.String(), service.InvalidRedirectURIMessage)
+}
+
+func TestAuthorizeHandler_MissingCodeChallenge(t *testing.T) {
+	tables := []string{"oauth_client"}
+	defer clearDB(tables)
+
+	oauthClient := SamplePublicOAuthClient("backoffice-web", backofficeRedirectURI)
+	db.Create(oauthClient)
+
+	rr := requestAuthorize(t, "GET", authorizeForm(oauthClient.ClientID, ""))
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	location, err := url.Parse(rr.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "invalid_request", location.Query().Get("error"))
+	assert.Equal(t, "xyz", location.Query().Get("state"))
+}
+
+func TestTokenHandler_AuthorizationCode_Positive(t *testing.T) {
+	tables := []string{"oauth_authorization_code", "oauth_token", "oauth_client", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	userLogin := SampleUser(client.ID)
+	hashedPassword, _ := helper.HashPassword(userLogin.Password)
+	userLogin.Password = hashedPassword
+	db.Create(userLogin)
+
+	oauthClient := SamplePublicOAuthClient("backoffice-web", backofficeRedirectURI)
+	db.Create(oauthClient)
+
+	since := time.Now()
+	verifier, challenge := pkcePair()
+	code := authorizationCode(t, oauthClient.ClientID, challenge)
+	assert.NotEmpty(t, code)
+
+	// Authorizing checks the credentials only, the existing login session is kept
+	var stored entity.User
+	db.First(&stored, userLogin.ID)
+	assert.Equal(t, userLogin.Token, stored.Token)
+	var logins int64
+	db.Model(&entity.AuditEvent{}).Where("event = ? AND outcome = ? AND created_at >= ?", service.AuditEventLogin, entity.AuditOutcomeSuccess, since).Count(&logins)
+	assert.Equal(t, int64(0), logins)
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("client_id", oauthClient.ClientID)
+	form.Set("code", code)
+	form.Set("redirect_uri", backofficeRedirectURI)
+	form.Set("code_verifier", verifier)
+	rr := requestServiceToken(t, form, "", "")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response model.TokenResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, response.AccessToken)
+	assert.NotEmpty(t, response.RefreshToken)
+
+	// The access token is accepted wherever the Token header is
+	req, err := http.NewRequest("GET", "/user", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", response.AccessToken)
+	userRR := httptest.NewRecorder()
+	http.HandlerFunc(authHandler.GetAllUserHandler).ServeHTTP(userRR, req)
+	assert.Equal(t, http.StatusOK, userRR.Code)
+
+	// A code can only be redeemed once
+	rr = requestServiceToken(t, form, "", "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var errorResponse model.OAuthErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &errorResponse); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "invalid_grant", errorResponse.Error)
+
+	// The refresh token yields a new access token and is rotated
+	refreshForm := url.Values{}
+	refreshForm.Set("grant_type", "refresh_token")
+	refreshForm.Set("client_id", oauthClient.ClientID)
+	refreshForm.Set("refresh_token", response.RefreshToken)
+	rr = requestServiceToken(t, refreshForm, "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var refreshed model.TokenResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &refreshed); err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, refreshed.RefreshToken)
+	assert.NotEqual(t, response.RefreshToken, refreshed.RefreshToken)
+
+	var rotated entity.OAuthToken
+	db.Where("token = ?", refreshed.RefreshToken).First(&rotated)
+	var original entity.OAuthToken
+	db.Where("token = ?", response.RefreshToken).First(&original)
+	assert.NotNil(t, original.RevokedAt)
+	assert.WithinDuration(t, original.ExpiresAt, rotated.ExpiresAt, time.Second)
+
+	// Reusing the old refresh token fails and revokes the whole grant
+	rr = requestServiceToken(t, refreshForm, "", "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	refreshForm.Set("refresh_token", refreshed.RefreshToken)
+	rr = requestServiceToken(t, refreshForm, "", "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestTokenHandler_RefreshToken_InactiveUser(t *testing.T) {
+	tables := []string{"oauth_authorization_code", "oauth_token", "oauth_client", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	userLogin := SampleUser(client.ID)
+	hashedPassword, _ := helper.HashPassword(userLogin.Password)
+	userLogin.Password = hashedPassword
+	db.Create(userLogin)
+
+	oauthClient := SamplePublicOAuthClient("backoffice-web", backofficeRedirectURI)
+	db.Create(oauthClient)
+
+	tokens := openIDTokens(t, oauthClient.ClientID, "")
+	refreshForm := url.Values{}
+	refreshForm.Set("grant_type", "refresh_token")
+	refreshForm.Set("client_id", oauthClient.ClientID)
+	refreshForm.Set("refresh_token", tokens.RefreshToken)
+
+	// The refresh token stops working once its user is deactivated or deleted
+	db.Model(&entity.User{}).Where("id = ?", userLogin.ID).Update("is_active", false)
+	rr := requestServiceToken(t, refreshForm, "", "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var errorResponse model.OAuthErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &errorResponse); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "invalid_grant", errorResponse.Error)
+
+	db.Model(&entity.User{}).Where("id = ?", userLogin.ID).Updates(map[string]interface{}{"is_active": true, "deleted_at": time.Now()})
+	rr = requestServiceToken(t, refreshForm, "", "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// A refused refresh does not consume the token
+	db.Unscoped().Model(&entity.User{}).Where("id = ?", userLogin.ID).Update("deleted_at", nil)
+	rr = requestServiceToken(t, refreshForm, "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestTokenHandler_AuthorizationCode_InvalidVerifier(t *testing.T) {
+	tables := []string{"oauth_authorization_code", "oauth_token", "oauth_client", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	userLogin := SampleUser(client.ID)
+	hashedPassword, _ := helper.HashPassword(userLogin.Password)
+	userLogin.Password = hashedPassword
+	db.Create(userLogin)
+
+	oauthClient := SamplePublicOAuthClient("backoffice-web", backofficeRedirectURI)
+	db.Create(oauthClient)
+
+	_, challenge := pkcePair()
+	code := authorizationCode(t, oauthClient.ClientID, challenge)
+	otherVerifier, _ := pkcePair()
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("client_id", oauthClient.ClientID)
+	form.Set("code", code)
+	form.Set("redirect_uri", backofficeRedirectURI)
+	form.Set("code_verifier", otherVerifier)
+	rr := requestServiceToken(t, form, "", "")
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var response model.OAuthErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "invalid_grant", response.Error)
+}
+
+func requestIntrospect(t *testing.T, token, clientID, clientSecret string) *httptest.ResponseRecorder {
+	form := url.Values{}
+	form.Set("token", token)
+	req, err := http.NewRequest("POST", "/oauth/introspect", strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(clientID, clientSecret)
+	requestID := uuid.New().String()
+	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, requestID)
+	req = req.WithContext(ctx)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(oauthHandler.IntrospectHandler).ServeHTTP(rr, req)
+	logging.Log.WithFields(logrus.Fields{
+		"RequestID": requestID,
+		"Status":    rr.Code,
+		"Body":      rr.Body.String(),
+	}).Info("Outgoing response")
+	return rr
+}
+
+func TestIntrospectHandler_ServiceToken(t *testing.T) {
+	tables := []string{"oauth_token", "oauth_client"}
+	defer clearDB(tables)
+
+	introspector := SampleOAuthClient("gateway", "token.introspect")
+	db.Create(introspector)
+	oauthClient := SampleOAuthClient("order-service", "user.read")
+	db.Create(oauthClient)
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	rr := requestServiceToken(t, form, oauthClient.ClientID, "service-secret")
+	var token model.TokenResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &token); err != nil {
+		t.Fatal(err)
+	}
+
+	rr = requestIntrospect(t, token.AccessToken, introspector.ClientID, "service-secret")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response model.IntrospectionResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, response.Active)
+	assert.Equal(t, oauthClient.ClientID, response.ClientID)
+	assert.Equal(t, oauthClient.ClientID, response.Subject)
+	assert.Equal(t, "user.read", response.Scope)
+	assert.True(t, response.Expiry > 0)
+}
+
+func TestIntrospectHandler_UserToken(t *testing.T) {
+	tables := []string{"oauth_token", "oauth_client", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	userLogin := SampleUser(client.ID)
+	db.Create(userLogin)
+
+	introspector := SampleOAuthClient("gateway", "token.introspect")
+	db.Create(introspector)
+
+	rr := requestIntrospect(t, userLogin.Token, introspector.ClientID, "service-secret")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response model.IntrospectionResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, response.Active)
+	assert.Equal(t, strconv.Itoa(int(userLogin.ID)), response.Subject)
+	assert.Equal(t, userLogin.Username, response.Username)
+	assert.Equal(t, client.ID, response.TenantID)
+	assert.Equal(t, userLogin.Role, response.Role)
+	assert.True(t, response.IsAdmin)
+	assert.Equal(t, userLogin.TokenExpired.Unix(), response.Expiry)
+}
+
+func TestIntrospectHandler_RefreshToken(t *testing.T) {
+	tables := []string{"oauth_authorization_code", "oauth_token", "oauth_client", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	userLogin := SampleUser(client.ID)
+	hashedPassword, _ := helper.HashPassword(userLogin.Password)
+	userLogin.Password = hashedPassword
+	db.Create(userLogin)
+
+	oauthClient := SamplePublicOAuthClient("backoffice-web", backofficeRedirectURI)
+	db.Create(oauthClient)
+	introspector := SampleOAuthClient("gateway", "token.introspect")
+	db.Create(introspector)
+
+	tokens := openIDTokens(t, oauthClient.ClientID, "")
+
+	rr := requestIntrospect(t, tokens.RefreshToken, introspector.ClientID, "service-secret")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response model.IntrospectionResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, response.Active)
+	assert.Equal(t, strconv.Itoa(int(userLogin.ID)), response.Subject)
+	assert.Equal(t, userLogin.Username, response.Username)
+	assert.Equal(t, client.ID, response.TenantID)
+	assert.Equal(t, entity.TokenTypeRefresh, response.TokenType)
+
+	// A refresh token of a suspended user is no longer active
+	db.Model(&entity.User{}).Where("id = ?", userLogin.ID).Update("is_active", false)
+	rr = requestIntrospect(t, tokens.RefreshToken, introspector.ClientID, "service-secret")
+	assert.JSONEq(t, `{"active":false}`, rr.Body.String())
+}
+
+func TestIntrospectHandler_UnknownToken(t *testing.T) {
+	tables := []string{"oauth_client"}
+	defer clearDB(tables)
+
+	introspector := SampleOAuthClient("gateway", "token.introspect")
+	db.Create(introspector)
+
+	rr := requestIntrospect(t, "unknown", introspector.ClientID, "service-secret")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"active":false}`, rr.Body.String())
+}
+
+func TestIntrospectHandler_UnauthorizedCaller(t *testing.T) {
+	tables := []string{"oauth_client"}
+	defer clearDB(tables)
+
+	introspector := SampleOAuthClient("gateway", "token.introspect")
+	db.Create(introspector)
+	other := SampleOAuthClient("order-service", "user.read")
+	db.Create(other)
+
+	rr := requestIntrospect(t, "unknown", introspector.ClientID, "wrong-secret")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	rr = requestIntrospect(t, "unknown", other.ClientID, "service-secret")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var response model.OAuthErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "unauthorized_client", response.Error)
+}
+
+func requestRevoke(t *testing.T, form url.Values, clientID, clientSecret string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("POST", "/oauth/revoke", strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if clientID != "" {
+		req.SetBasicAuth(clientID, clientSecret)
+	}
+	requestID := uuid.New().String()
+	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, requestID)
+	req = req.WithContext(ctx)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(oauthHandler.RevokeHandler).ServeHTTP(rr, req)
+	logging.Log.WithFields(logrus.Fields{
+		"RequestID": requestID,
+		"Status":    rr.Code,
+		"Body":      rr.Body.String(),
+	}).Info("Outgoing response")
+	return rr
+}
+
+func TestRevokeHandler_RefreshToken(t *testing.T) {
+	tables := []string{"oauth_authorization_code", "oauth_token", "oauth_client", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	userLogin := SampleUser(client.ID)
+	hashedPassword, _ := helper.HashPassword(userLogin.Password)
+	userLogin.Password = hashedPassword
+	db.Create(userLogin)
+
+	oauthClient := SamplePublicOAuthClient("backoffice-web", backofficeRedirectURI)
+	db.Create(oauthClient)
+	introspector := SampleOAuthClient("gateway", "token.introspect")
+	db.Create(introspector)
+
+	tokens := openIDTokens(t, oauthClient.ClientID, "")
+
+	form := url.Values{}
+	form.Set("client_id", oauthClient.ClientID)
+	form.Set("token", tokens.RefreshToken)
+	form.Set("token_type_hint", "refresh_token")
+	rr := requestRevoke(t, form, "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Body.String())
+
+	// Revoking the refresh token also revokes the access tokens issued with it
+	rr = requestIntrospect(t, tokens.AccessToken, introspector.ClientID, "service-secret")
+	assert.JSONEq(t, `{"active":false}`, rr.Body.String())
+
+	var revoked entity.OAuthToken
+	if err := db.Where("token = ?", tokens.RefreshToken).First(&revoked).Error; err != nil {
+		t.Fatal(err)
+	}
+	assert.NotNil(t, revoked.RevokedAt)
+
+	refreshForm := url.Values{}
+	refreshForm.Set("grant_type", "refresh_token")
+	refreshForm.Set("client_id", oauthClient.ClientID)
+	refreshForm.Set("refresh_token", tokens.RefreshToken)
+	rr = requestServiceToken(t, refreshForm, "", "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// Revoking again is not an error
+	rr = requestRevoke(t, form, "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestRevokeHandler_OtherClientToken(t *testing.T) {
+	tables := []string{"oauth_token", "oauth_client"}
+	defer clearDB(tables)
+
+	oauthClient := SampleOAuthClient("order-service", "user.read")
+	db.Create(oauthClient)
+	other := SampleOAuthClient("report-service", "user.read")
+	db.Create(other)
+	revoker := SampleOAuthClient("gateway", "token.revoke")
+	db.Create(revoker)
+
+	tokenForm := url.Values{}
+	tokenForm.Set("grant_type", "client_credentials")
+	rr := requestServiceToken(t, tokenForm, oauthClient.ClientID, "service-secret")
+	var token model.TokenResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &token); err != nil {
+		t.Fatal(err)
+	}
+
+	form := url.Values{}
+	form.Set("token", token.AccessToken)
+	rr = requestRevoke(t, form, other.ClientID, "service-secret")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var response model.OAuthErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "unauthorized_client", response.Error)
+
+	rr = requestRevoke(t, form, revoker.ClientID, "service-secret")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var revoked entity.OAuthToken
+	if err := db.Where("token = ?", token.AccessToken).First(&revoked).Error; err != nil {
+		t.Fatal(err)
+	}
+	assert.NotNil(t, revoked.RevokedAt)
+}
+
+func TestRevokeHandler_LoginToken(t *testing.T) {
+	tables := []string{"oauth_client", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	userLogin := SampleUser(client.ID)
+	db.Create(userLogin)
+
+	oauthClient := SampleOAuthClient("order-service", "user.read")
+	db.Create(oauthClient)
+	revoker := SampleOAuthClient("gateway", "token.revoke")
+	db.Create(revoker)
+
+	// Without the token.revoke scope the login token is left untouched
+	form := url.Values{}
+	form.Set("token", userLogin.Token)
+	rr := requestRevoke(t, form, oauthClient.ClientID, "service-secret")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var userNew entity.User
+	if err := db.First(&userNew, userLogin.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, userLogin.Token, userNew.Token)
+
+	rr = requestRevoke(t, form, revoker.ClientID, "service-secret")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	if err := db.First(&userNew, userLogin.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, userNew.Token)
+	assert.NotNil(t, userNew.TokenRevokedAt)
+}
@@ -0,0 +1,184 @@
+package handler_test
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/library/helper"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// openIDTokens runs the authorization-code flow with the openid scope and returns the token response.
+func openIDTokens(t *testing.T, clientID, nonce string) model.TokenResponse {
+	verifier, challenge := pkcePair()
+	user := SampleUser(0)
+	form := authorizeForm(clientID, challenge)
+	form.Set("scope", "openid profile")
+	form.Set("nonce", nonce)
+	form.Set("username", user.Username)
+	form.Set("password", user.Password)
+	rr := requestAuthorize(t, "POST", form)
+	assert.Equal(t, http.StatusFound, rr.Code)
+
+	location, err := url.Parse(rr.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokenForm := url.Values{}
+	tokenForm.Set("grant_type", "authorization_code")
+	tokenForm.Set("client_id", clientID)
+	tokenForm.Set("code", location.Query().Get("code"))
+	tokenForm.Set("redirect_uri", backofficeRedirectURI)
+	tokenForm.Set("code_verifier", verifier)
+	rr = requestServiceToken(t, tokenForm, "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response model.TokenResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+// verifyIDToken checks the ID token signature against the published JWKS and returns its claims.
+func verifyIDToken(t *testing.T, idToken string) model.IDTokenClaims {
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(oauthHandler.JWKSHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+	var jwks model.JSONWebKeySet
+	if err := json.Unmarshal(rr.Body.Bytes(), &jwks); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, jwks.Keys, 1)
+
+	modulus, _ := base64.RawURLEncoding.DecodeString(jwks.Keys[0].Modulus)
+	exponent, _ := base64.RawURLEncoding.DecodeString(jwks.Keys[0].Exponent)
+	publicKey := &rsa.PublicKey{
+		N: new(big.Int).SetBytes(modulus),
+		E: int(new(big.Int).SetBytes(exponent).Int64()),
+	}
+
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		t.Fatalf("malformed ID token %q", idToken)
+	}
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature); err != nil {
+		t.Fatalf("invalid ID token signature: %v", err)
+	}
+
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims model.IDTokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	return claims
+}
+
+func TestOpenIDConfigurationHandler(t *testing.T) {
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(oauthHandler.OpenIDConfigurationHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/.well-known/openid-configuration", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response model.OpenIDConfiguration
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotEmpty(t, response.Issuer)
+	assert.Equal(t, response.Issuer+"/oauth/authorize", response.AuthorizationEndpoint)
+	assert.Equal(t, response.Issuer+"/oauth/token", response.TokenEndpoint)
+	assert.Equal(t, response.Issuer+"/userinfo", response.UserInfoEndpoint)
+	assert.Equal(t, response.Issuer+"/.well-known/jwks.json", response.JWKSURI)
+	assert.Contains(t, response.IDTokenSigningAlgValuesSupported, "RS256")
+}
+
+func TestIDToken_Positive(t *testing.T) {
+	tables := []string{"oauth_authorization_code", "oauth_token", "oauth_client", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	userLogin := SampleUser(client.ID)
+	hashedPassword, _ := helper.HashPassword(userLogin.Password)
+	userLogin.Password = hashedPassword
+	db.Create(userLogin)
+
+	oauthClient := SamplePublicOAuthClient("backoffice-web", backofficeRedirectURI)
+	db.Create(oauthClient)
+
+	response := openIDTokens(t, oauthClient.ClientID, "n-0S6_WzA2Mj")
+	assert.NotEmpty(t, response.IDToken)
+
+	claims := verifyIDToken(t, response.IDToken)
+	assert.Equal(t, strconv.Itoa(int(userLogin.ID)), claims.Subject)
+	assert.Equal(t, oauthClient.ClientID, claims.Audience)
+	assert.Equal(t, "n-0S6_WzA2Mj", claims.Nonce)
+	assert.Equal(t, userLogin.FullName, claims.Name)
+	assert.Equal(t, userLogin.Username, claims.PreferredUsername)
+	assert.True(t, claims.Expiry > claims.IssuedAt)
+}
+
+func TestUserInfoHandler_Positive(t *testing.T) {
+	tables := []string{"oauth_authorization_code", "oauth_token", "oauth_client", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	userLogin := SampleUser(client.ID)
+	hashedPassword, _ := helper.HashPassword(userLogin.Password)
+	userLogin.Password = hashedPassword
+	db.Create(userLogin)
+
+	oauthClient := SamplePublicOAuthClient("backoffice-web", backofficeRedirectURI)
+	db.Create(oauthClient)
+
+	tokens := openIDTokens(t, oauthClient.ClientID, "")
+
+	req := httptest.NewRequest("GET", "/userinfo", nil)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(oauthHandler.UserInfoHandler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response model.UserInfo
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, strconv.Itoa(int(userLogin.ID)), response.Subject)
+	assert.Equal(t, userLogin.FullName, response.Name)
+	assert.Equal(t, client.ID, response.TenantID)
+	assert.True(t, response.IsAdmin)
+}
+
+func TestUserInfoHandler_InvalidToken(t *testing.T) {
+	req := httptest.NewRequest("GET", "/userinfo", nil)
+	req.Header.Set("Authorization", "Bearer unknown")
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(oauthHandler.UserInfoHandler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Contains(t, rr.Header().Get("WWW-Authenticate"), `error="invalid_token"`)
+
+	var response model.OAuthErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "invalid_token", response.Error)
+}
@@ -0,0 +1,86 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/service"
+	"maqhaa/auth_service/internal/interface/http/handler"
+	"maqhaa/auth_service/internal/interface/http/router"
+	"maqhaa/library/helper"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// versionedRouter registers the auth routes the way cmd/main.go does.
+func versionedRouter(deprecatedAt, sunset time.Time) router.Router {
+	httpRouter := router.NewMuxRouter()
+	httpRouter.GetRouter().Use(handler.RequestMetadataMiddleware(trustedProxies))
+	authHandler.RegisterRoutesV1(httpRouter.VERSION("v1"))
+	authHandler.RegisterLegacyRoutes(httpRouter.DEPRECATED("v1", deprecatedAt, sunset))
+	return httpRouter
+}
+
+func TestVersionedRoutes_Login(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	userLogin := SampleUser(client.ID)
+	hashedPassword, _ := helper.HashPassword(userLogin.Password)
+	userLogin.Password = hashedPassword
+	db.Create(userLogin)
+
+	deprecatedAt := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, 5, 1, 0, 0, 0, 0, time.UTC)
+	httpRouter := versionedRouter(deprecatedAt, sunset)
+
+	login := func(path string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(model.LoginRequest{Username: userLogin.Username, Password: "rahasia"})
+		req, err := http.NewRequest("POST", path, bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		httpRouter.GetRouter().ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := login("/v1/login")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response model.LoginResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, service.SuccessError, response.Code)
+	assert.Empty(t, rr.Header().Get("Deprecation"))
+	assert.Empty(t, rr.Header().Get("Sunset"))
+
+	// The unversioned path still works and announces its deprecation
+	rr = login("/login")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "@1793491200", rr.Header().Get("Deprecation"))
+	assert.Equal(t, "Sat, 01 May 2027 00:00:00 GMT", rr.Header().Get("Sunset"))
+	assert.Equal(t, "</v1/login>; rel=\"successor-version\"", rr.Header().Get("Link"))
+}
+
+func TestVersionedRoutes_NoSunset(t *testing.T) {
+	httpRouter := versionedRouter(time.Time{}, time.Time{})
+
+	req, err := http.NewRequest("DELETE", "/logout", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	httpRouter.GetRouter().ServeHTTP(rr, req)
+
+	assert.Empty(t, rr.Header().Get("Deprecation"))
+	assert.Empty(t, rr.Header().Get("Sunset"))
+	assert.Equal(t, "</v1/logout>; rel=\"successor-version\"", rr.Header().Get("Link"))
+}
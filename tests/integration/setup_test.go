@@ -3,10 +3,12 @@
 package handler_test
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"testing"
 	"time"
@@ -17,20 +19,30 @@ import (
 	"maqhaa/auth_service/internal/config"
 	"maqhaa/auth_service/internal/database"
 	"maqhaa/auth_service/internal/interface/http/handler"
+	"maqhaa/auth_service/internal/notification"
 	"maqhaa/library/helper"
 	"maqhaa/library/logging"
-	"maqhaa/library/middleware"
 
+	"maqhaa/auth_service/internal/interface/grpc/gateway"
 	gRPCHandler "maqhaa/auth_service/internal/interface/grpc/handler"
+	"maqhaa/auth_service/internal/interface/grpc/interceptor"
 	pb "maqhaa/auth_service/internal/interface/grpc/model"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 	"gorm.io/gorm"
 )
 
 var db *gorm.DB
 var authHandler *handler.AuthHandler
 var userHandlerGrpc *gRPCHandler.UserHandler
+var legacyUserHandlerGrpc *gRPCHandler.UserHandler
+var oauthHandler *handler.OAuthHandler
+var gatewayHandler http.Handler
+var notificationLog string
+var trustedProxies service.TrustedProxies
 
 func TestMain(m *testing.M) {
 	setup()
@@ -53,8 +65,8 @@ func setup() {
 	}
 
 	// Apply database migrations for tests
-	_ = db.Migrator().DropTable(&entity.User{}, &entity.Client{})
-	if err := db.AutoMigrate(&entity.Client{}, &entity.User{}); err != nil {
+	_ = db.Migrator().DropTable(&entity.AuditEvent{}, &entity.UserVerification{}, &entity.UserInvitation{}, &entity.User{}, &entity.Client{}, &entity.OAuthClient{}, &entity.OAuthToken{}, &entity.OAuthAuthorizationCode{})
+	if err := db.AutoMigrate(&entity.Client{}, &entity.User{}, &entity.OAuthClient{}, &entity.OAuthToken{}, &entity.OAuthAuthorizationCode{}, &entity.UserInvitation{}, &entity.UserVerification{}, &entity.AuditEvent{}); err != nil {
 		panic(err)
 	}
 
@@ -82,17 +94,61 @@ func setup() {
 	// You can use db.AutoMigrate(&YourModel{}) to automatically apply migrations
 
 	userRepository := repository.NewUserRepository(db)
-	authService := service.NewAuthService(userRepository)
+	oauthRepository := repository.NewOAuthRepository(db)
+	notificationLog = cfg.Notification.LogFile
+	trustedProxies, err = service.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		panic(err)
+	}
+	notifier, err := notification.New(cfg.Notification)
+	if err != nil {
+		panic(err)
+	}
+	auditRepository := repository.NewAuditRepository(db)
+	authService := service.NewAuthService(userRepository, oauthRepository, auditRepository, notifier)
 	authHandler = handler.NewAuthHandler(authService)
-	userHandlerGrpc = gRPCHandler.NewUserGRPCHandler(authService)
+	userHandlerGrpc = gRPCHandler.NewUserGRPCHandler(authService, cfg.Grpc.LegacyErrors)
+	legacyUserHandlerGrpc = gRPCHandler.NewUserGRPCHandler(authService, true)
+
+	tokenSigner, err := service.NewTokenSigner("")
+	if err != nil {
+		panic(err)
+	}
+	oauthService := service.NewOAuthService(oauthRepository, authService, tokenSigner, cfg.OAuth)
+	oauthHandler = handler.NewOAuthHandler(oauthService)
+
+	gatewayHandler, err = gateway.NewHandler(context.Background(), "localhost:50051")
+	if err != nil {
+		panic(err)
+	}
 
 	go func() {
 		// Create a gRPC server
-		grpcServer := grpc.NewServer(grpc.UnaryInterceptor(middleware.LoggingInterceptor))
+		grpcServer := grpc.NewServer(
+			grpc.ChainUnaryInterceptor(
+				interceptor.RequestIDInterceptor,
+				interceptor.RequestMetadataInterceptor(trustedProxies),
+				interceptor.AuthInterceptor(authService, oauthService, false),
+			),
+			grpc.ChainStreamInterceptor(
+				interceptor.RequestIDStreamInterceptor,
+				interceptor.RequestMetadataStreamInterceptor(trustedProxies),
+				interceptor.AuthStreamInterceptor(authService, oauthService),
+			),
+		)
 
 		// Register your gRPC service implementation
 		pb.RegisterUserServer(grpcServer, userHandlerGrpc)
 
+		healthServer := health.NewServer()
+		healthpb.RegisterHealthServer(grpcServer, healthServer)
+		sqlDB, err := db.DB()
+		if err != nil {
+			panic(err)
+		}
+		go gRPCHandler.WatchDatabaseHealth(context.Background(), healthServer, sqlDB, cfg.Grpc.HealthCheckInterval)
+		reflection.Register(grpcServer)
+
 		// Start gRPC server on a specific port
 		listen, err := net.Listen("tcp", "localhost:50051")
 		if err != nil {
@@ -174,3 +230,30 @@ func SampleClient() *entity.Client {
 		CreatedAt:   time.Now(),
 	}
 }
+
+func SampleOAuthClient(clientID string, scopes string) *entity.OAuthClient {
+	hashedSecret, _ := helper.HashPassword("service-secret")
+	return &entity.OAuthClient{
+		ID:           0,
+		ClientID:     clientID,
+		ClientSecret: hashedSecret,
+		Name:         "Sample Service",
+		Scopes:       scopes,
+		GrantTypes:   entity.GrantTypeClientCredentials,
+		IsActive:     true,
+		CreatedAt:    time.Now(),
+	}
+}
+
+func SamplePublicOAuthClient(clientID string, redirectURI string) *entity.OAuthClient {
+	return &entity.OAuthClient{
+		ID:           0,
+		ClientID:     clientID,
+		Name:         "Sample Back-office",
+		Scopes:       "openid profile user.read",
+		GrantTypes:   entity.GrantTypeAuthorizationCode + " " + entity.GrantTypeRefreshToken,
+		RedirectURIs: redirectURI,
+		IsActive:     true,
+		CreatedAt:    time.Now(),
+	}
+}
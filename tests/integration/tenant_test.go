@@ -0,0 +1,216 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"maqhaa/auth_service/internal/app/entity"
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/service"
+	pb "maqhaa/auth_service/internal/interface/grpc/model"
+	"maqhaa/library/helper"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tenantFixture creates two clients, each with an admin, and an employee of the second client.
+func tenantFixture() (adminA *entity.User, adminB *entity.User, employeeB *entity.User) {
+	clientA := SampleClient()
+	db.Create(clientA)
+	clientB := SampleClient()
+	clientB.CompanyName = "Other Coffee"
+	clientB.Token = "other-client-token"
+	db.Create(clientB)
+
+	adminA = SampleUser(clientA.ID)
+	db.Create(adminA)
+	adminB = SampleUserCS(clientB.ID, "admin.b")
+	adminB.Role = entity.RoleAdminCode
+	db.Create(adminB)
+	employeeB = SampleUserCS(clientB.ID, "kasir.b")
+	db.Create(employeeB)
+	return adminA, adminB, employeeB
+}
+
+// requestV1 sends a request to the v1 routes.
+func requestV1(t *testing.T, method, path, token string, body interface{}) (int, model.HTTPResponse) {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, path, &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", token)
+
+	rr := httptest.NewRecorder()
+	versionedRouter(time.Now(), time.Now()).GetRouter().ServeHTTP(rr, req)
+
+	var response model.HTTPResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return rr.Code, response
+}
+
+func TestTenantIsolation_HTTP(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	adminA, _, employeeB := tenantFixture()
+	userPath := fmt.Sprintf("/v1/user/%d", employeeB.ID)
+
+	code, response := requestV1(t, "GET", userPath, adminA.Token, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, service.UserIDNotFoundError, response.Code)
+
+	code, response = requestV1(t, "PUT", "/v1/user", adminA.Token, model.EditUserRequest{
+		ID: employeeB.ID,
+		AddUserRequest: model.AddUserRequest{
+			Username: "diambil.alih",
+			Password: "rahasia",
+			FullName: "Diambil Alih",
+			Role:     entity.RoleAdminCode,
+		},
+	})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, service.UserIDNotFoundError, response.Code)
+
+	_, response = requestV1(t, "PATCH", userPath, adminA.Token, map[string]interface{}{"role": entity.RoleAdminCode})
+	assert.Equal(t, service.UserIDNotFoundError, response.Code)
+
+	_, response = requestV1(t, "DELETE", userPath, adminA.Token, nil)
+	assert.Equal(t, service.UserIDNotFoundError, response.Code)
+
+	// The employee of the other client is untouched
+	var stored entity.User
+	db.First(&stored, employeeB.ID)
+	assert.Equal(t, employeeB.Username, stored.Username)
+	assert.Equal(t, employeeB.FullName, stored.FullName)
+	assert.Equal(t, uint(entity.RoleEmployeCode), stored.Role)
+	assert.True(t, stored.IsActive)
+
+	// Listings only contain the users of the caller's client
+	_, _, page := listUsers(t, adminA.Token, "")
+	if assert.Len(t, page.Users, 1) {
+		assert.Equal(t, adminA.ID, page.Users[0].ID)
+	}
+	assert.Equal(t, int64(1), page.Total)
+}
+
+func TestTenantIsolation_SameClient(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	_, adminB, employeeB := tenantFixture()
+	userPath := fmt.Sprintf("/v1/user/%d", employeeB.ID)
+
+	code, response := requestV1(t, "PATCH", userPath, adminB.Token, map[string]interface{}{"fullName": "Kasir B"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, service.SuccessError, response.Code)
+
+	code, response = requestV1(t, "DELETE", userPath, adminB.Token, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, service.SuccessError, response.Code)
+
+	var stored entity.User
+	db.First(&stored, employeeB.ID)
+	assert.Equal(t, "Kasir B", stored.FullName)
+	assert.False(t, stored.IsActive)
+
+	// Unknown ids are reported the same way as users of other clients
+	_, response = requestV1(t, "DELETE", "/v1/user/99999", adminB.Token, nil)
+	assert.Equal(t, service.UserIDNotFoundError, response.Code)
+}
+
+func TestTenantIsolation_GRPC(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	adminA, _, employeeB := tenantFixture()
+
+	clientServer, closeConn := dialUserGRPC(t)
+	defer closeConn()
+
+	_, err := clientServer.GetUserByID(context.Background(), &pb.GetUserByIDRequest{Token: adminA.Token, Id: uint32(employeeB.ID)})
+	assert.Equal(t, service.UserIDNotFoundError, appErrorCode(t, err))
+
+	_, err = clientServer.EditUser(context.Background(), &pb.EditUserRequest{
+		Token:    adminA.Token,
+		Id:       uint32(employeeB.ID),
+		Username: "diambil.alih",
+		Password: "rahasia",
+		FullName: "Diambil Alih",
+		Role:     entity.RoleAdminCode,
+	})
+	assert.Equal(t, service.UserIDNotFoundError, appErrorCode(t, err))
+
+	_, err = clientServer.DeactivateUser(context.Background(), &pb.DeactivateUserRequest{Token: adminA.Token, Id: uint32(employeeB.ID)})
+	assert.Equal(t, service.UserIDNotFoundError, appErrorCode(t, err))
+
+	var stored entity.User
+	db.First(&stored, employeeB.ID)
+	assert.Equal(t, employeeB.Username, stored.Username)
+	assert.True(t, stored.IsActive)
+}
+
+func TestTenantUsernames(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	adminA, adminB, _ := tenantFixture()
+	db.Model(&entity.Client{}).Where("id = ?", adminA.ClientID).Update("code", "kopi-a")
+	db.Model(&entity.Client{}).Where("id = ?", adminB.ClientID).Update("code", "kopi-b")
+
+	// Two clients can both have a kasir1, one client cannot have two
+	request := model.AddUserRequest{Username: "kasir1", Password: "rahasia", FullName: "Kasir Satu", Role: entity.RoleEmployeCode}
+	_, response := requestV1(t, "POST", "/v1/user", adminA.Token, request)
+	assert.Equal(t, service.SuccessError, response.Code)
+	_, response = requestV1(t, "POST", "/v1/user", adminB.Token, request)
+	assert.Equal(t, service.SuccessError, response.Code)
+	_, response = requestV1(t, "POST", "/v1/user", adminA.Token, request)
+	assert.Equal(t, service.DuplicateUserError, response.Code)
+
+	login := func(request model.LoginRequest) (int, int) {
+		request.Password = "rahasia"
+		code, response := requestV1(t, "POST", "/v1/login", "", request)
+		return code, response.Code
+	}
+	tokenOf := func(clientID uint) string {
+		var user entity.User
+		db.Where("client_id = ? AND username = ?", clientID, "kasir1").First(&user)
+		return user.Token
+	}
+
+	// A username used by several clients needs the client code
+	code, appCode := login(model.LoginRequest{Username: "kasir1"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, service.ClientCodeRequiredError, appCode)
+
+	_, appCode = login(model.LoginRequest{ClientCode: "KOPI-A", Username: "kasir1"})
+	assert.Equal(t, service.SuccessError, appCode)
+	assert.NotEmpty(t, tokenOf(adminA.ClientID))
+	assert.Empty(t, tokenOf(adminB.ClientID))
+
+	_, appCode = login(model.LoginRequest{Username: "kasir1@kopi-b"})
+	assert.Equal(t, service.SuccessError, appCode)
+	assert.NotEmpty(t, tokenOf(adminB.ClientID))
+
+	_, appCode = login(model.LoginRequest{ClientCode: "kopi-c", Username: "kasir1"})
+	assert.Equal(t, service.InvalidUsername, appCode)
+
+	// A username used by a single client keeps working without a code
+	hashedPassword, _ := helper.HashPassword("rahasia")
+	db.Model(&entity.User{}).Where("id = ?", adminA.ID).Update("password", hashedPassword)
+	_, appCode = login(model.LoginRequest{Username: adminA.Username})
+	assert.Equal(t, service.SuccessError, appCode)
+}
@@ -0,0 +1,206 @@
+package handler_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"maqhaa/auth_service/internal/app/entity"
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/service"
+	pb "maqhaa/auth_service/internal/interface/grpc/model"
+	"maqhaa/auth_service/internal/notification"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserContact_AddAndPatch(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	admin, staff := statusFixture()
+
+	request := model.AddUserRequest{
+		Username: "baru",
+		Password: "rahasia123",
+		FullName: "Kasir Baru",
+		Role:     entity.RoleEmployeCode,
+		Email:    " Kasir.Baru@Example.com ",
+		Phone:    "+62 812-3456-7890",
+	}
+	_, response := requestV1(t, "POST", "/v1/user", admin.Token, request)
+	assert.Equal(t, service.SuccessError, response.Code)
+
+	var stored entity.User
+	db.Where("username = ?", "baru").First(&stored)
+	assert.Equal(t, "kasir.baru@example.com", stored.Email)
+	assert.Equal(t, "+6281234567890", stored.Phone)
+	assert.Nil(t, stored.EmailVerifiedAt)
+
+	// Emails and phones are unique per client, whatever their case or separators
+	request.Username, request.Email, request.Phone = "lain", "KASIR.BARU@example.com", ""
+	_, response = requestV1(t, "POST", "/v1/user", admin.Token, request)
+	assert.Equal(t, service.DuplicateContactError, response.Code)
+	request.Email, request.Phone = "", "+62 (812) 3456 7890"
+	_, response = requestV1(t, "POST", "/v1/user", admin.Token, request)
+	assert.Equal(t, service.DuplicateContactError, response.Code)
+
+	request.Email, request.Phone = "bukan-email", ""
+	_, response = requestV1(t, "POST", "/v1/user", admin.Token, request)
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+	request.Email, request.Phone = "", "12ab"
+	_, response = requestV1(t, "POST", "/v1/user", admin.Token, request)
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+
+	// A changed email has to be verified again, an empty one removes it
+	verifiedAt := time.Now()
+	db.Model(&entity.User{}).Where("id = ?", stored.ID).Update("email_verified_at", verifiedAt)
+	userPath := fmt.Sprintf("/v1/user/%d", stored.ID)
+	email := "kasir@example.com"
+	_, response = requestV1(t, "PATCH", userPath, admin.Token, model.PatchUserRequest{Email: &email})
+	assert.Equal(t, service.SuccessError, response.Code)
+	db.First(&stored, stored.ID)
+	assert.Equal(t, email, stored.Email)
+	assert.Nil(t, stored.EmailVerifiedAt)
+
+	_, response = requestV1(t, "PATCH", fmt.Sprintf("/v1/user/%d", staff.ID), admin.Token, model.PatchUserRequest{Email: &email})
+	assert.Equal(t, service.DuplicateContactError, response.Code)
+
+	empty := ""
+	_, response = requestV1(t, "PATCH", userPath, admin.Token, model.PatchUserRequest{Phone: &empty})
+	assert.Equal(t, service.SuccessError, response.Code)
+	db.First(&stored, stored.ID)
+	assert.Empty(t, stored.Phone)
+	assert.Equal(t, email, stored.Email)
+}
+
+func TestUserVerification_Email(t *testing.T) {
+	tables := []string{"user_verification", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	admin, staff := statusFixture()
+	db.Model(&entity.User{}).Where("id = ?", staff.ID).Update("email", "kasir@example.com")
+
+	_, response := requestV1(t, "POST", "/v1/me/verification", staff.Token, model.VerificationRequest{Channel: "phone"})
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+
+	since := time.Now()
+	_, response = requestV1(t, "POST", "/v1/me/verification", staff.Token, model.VerificationRequest{Channel: "email"})
+	assert.Equal(t, service.SuccessError, response.Code)
+
+	message := waitNotification(t, notification.EventVerificationCode, staff.ID, since)
+	assert.Equal(t, "kasir@example.com", message.Recipient.Email)
+	code := message.Data["code"]
+	assert.Len(t, code, 6)
+	assert.Contains(t, message.Body, code)
+
+	// The code is stored hashed and cannot be requested again right away
+	var verification entity.UserVerification
+	db.Where("user_id = ?", staff.ID).First(&verification)
+	assert.NotEqual(t, code, verification.CodeHash)
+	_, response = requestV1(t, "POST", "/v1/me/verification", staff.Token, model.VerificationRequest{Channel: "email"})
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	_, response = requestV1(t, "POST", "/v1/me/verification/confirm", staff.Token, model.ConfirmVerificationRequest{Channel: "email", Code: wrong})
+	assert.Equal(t, service.InvalidVerificationError, response.Code)
+	_, response = requestV1(t, "POST", "/v1/me/verification/confirm", admin.Token, model.ConfirmVerificationRequest{Channel: "email", Code: code})
+	assert.Equal(t, service.InvalidVerificationError, response.Code)
+
+	_, response = requestV1(t, "POST", "/v1/me/verification/confirm", staff.Token, model.ConfirmVerificationRequest{Channel: "email", Code: code})
+	assert.Equal(t, service.SuccessError, response.Code)
+
+	// The code is single use
+	_, response = requestV1(t, "POST", "/v1/me/verification/confirm", staff.Token, model.ConfirmVerificationRequest{Channel: "email", Code: code})
+	assert.Equal(t, service.InvalidVerificationError, response.Code)
+
+	var stored entity.User
+	db.First(&stored, staff.ID)
+	assert.NotNil(t, stored.EmailVerifiedAt)
+	assert.Nil(t, stored.PhoneVerifiedAt)
+
+	_, response = requestV1(t, "POST", "/v1/me/verification", staff.Token, model.VerificationRequest{Channel: "email"})
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+}
+
+func TestUserVerification_Attempts(t *testing.T) {
+	tables := []string{"user_verification", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	_, staff := statusFixture()
+	db.Model(&entity.User{}).Where("id = ?", staff.ID).Update("phone", "081234567890")
+
+	since := time.Now()
+	_, response := requestV1(t, "POST", "/v1/me/verification", staff.Token, model.VerificationRequest{Channel: "phone"})
+	assert.Equal(t, service.SuccessError, response.Code)
+	message := waitNotification(t, notification.EventVerificationCode, staff.ID, since)
+	assert.Equal(t, "081234567890", message.Recipient.Phone)
+	assert.Empty(t, message.Recipient.Email)
+	code := message.Data["code"]
+
+	// The last attempt is counted even when the code is wrong, then even the right code is refused
+	db.Model(&entity.UserVerification{}).Where("user_id = ?", staff.ID).Update("attempts", 4)
+	_, response = requestV1(t, "POST", "/v1/me/verification/confirm", staff.Token, model.ConfirmVerificationRequest{Channel: "phone", Code: "000000x"})
+	assert.Equal(t, service.InvalidVerificationError, response.Code)
+	var verification entity.UserVerification
+	db.Where("user_id = ?", staff.ID).First(&verification)
+	assert.Equal(t, 5, verification.Attempts)
+	_, response = requestV1(t, "POST", "/v1/me/verification/confirm", staff.Token, model.ConfirmVerificationRequest{Channel: "phone", Code: code})
+	assert.Equal(t, service.InvalidVerificationError, response.Code)
+
+	// So does a phone changed since the code was sent
+	db.Model(&entity.UserVerification{}).Where("user_id = ?", staff.ID).Update("attempts", 0)
+	db.Model(&entity.User{}).Where("id = ?", staff.ID).Update("phone", "089876543210")
+	_, response = requestV1(t, "POST", "/v1/me/verification/confirm", staff.Token, model.ConfirmVerificationRequest{Channel: "phone", Code: code})
+	assert.Equal(t, service.InvalidVerificationError, response.Code)
+
+	var stored entity.User
+	db.First(&stored, staff.ID)
+	assert.Nil(t, stored.PhoneVerifiedAt)
+}
+
+func TestUserContactGRPC_Positive(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	admin, staff := statusFixture()
+	verifiedAt := time.Now()
+	db.Model(&entity.User{}).Where("id = ?", staff.ID).Updates(map[string]interface{}{
+		"email":             "kasir@example.com",
+		"email_verified_at": verifiedAt,
+	})
+
+	clientServer, closeConn := dialUserGRPC(t)
+	defer closeConn()
+
+	resp, err := clientServer.GetUserByID(context.Background(), &pb.GetUserByIDRequest{Token: admin.Token, Id: uint32(staff.ID)})
+	if err != nil {
+		t.Fatalf("Error calling GetUserByID gRPC method: %v", err)
+	}
+	if assert.NotNil(t, resp.Data) {
+		assert.Equal(t, "kasir@example.com", resp.Data.Email)
+		assert.True(t, resp.Data.EmailVerified)
+		assert.Empty(t, resp.Data.Phone)
+		assert.False(t, resp.Data.PhoneVerified)
+	}
+
+	phone := "0812 3456 7890"
+	patch, err := clientServer.PatchUser(context.Background(), &pb.PatchUserRequest{Token: admin.Token, Id: uint32(staff.ID), Phone: &phone})
+	if err != nil {
+		t.Fatalf("Error calling PatchUser gRPC method: %v", err)
+	}
+	assert.Equal(t, int32(service.SuccessError), patch.Code)
+
+	me, err := clientServer.GetMe(context.Background(), &pb.GetMeRequest{Token: staff.Token})
+	if err != nil {
+		t.Fatalf("Error calling GetMe gRPC method: %v", err)
+	}
+	if assert.NotNil(t, me.Data) {
+		assert.Equal(t, "081234567890", me.Data.User.Phone)
+		assert.True(t, me.Data.User.EmailVerified)
+	}
+}
@@ -0,0 +1,126 @@
+package handler_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/service"
+	"maqhaa/library/helper"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// exportUsers calls GET /v1/user/export and returns the raw response.
+func exportUsers(t *testing.T, token, query string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("GET", "/v1/user/export?"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", token)
+
+	rr := httptest.NewRecorder()
+	versionedRouter(time.Now(), time.Now()).GetRouter().ServeHTTP(rr, req)
+	return rr
+}
+
+func TestExportUsersHandler_CSV(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	adminA, _, _ := tenantFixture()
+	staff := SampleUserCS(adminA.ClientID, "kasir")
+	hashedPassword, _ := helper.HashPassword(staff.Password)
+	staff.Password = hashedPassword
+	outletID := uint(7)
+	staff.OutletID = &outletID
+	staff.FullName = "+Kasir Pagi"
+	staff.Phone = "+6281234567890"
+	db.Create(staff)
+
+	deleted := SampleUserCS(adminA.ClientID, "mantan")
+	db.Create(deleted)
+	requestV1(t, "POST", fmt.Sprintf("/v1/user/%d/delete", deleted.ID), adminA.Token, nil)
+
+	assert.Equal(t, service.SuccessError, loginCode(t, staff.Username))
+
+	rr := exportUsers(t, adminA.Token, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Header().Get("Content-Type"), "text/csv")
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), "users.csv")
+
+	records, err := csv.NewReader(strings.NewReader(rr.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Deleted users and users of other clients are left out
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, []string{"id", "username", "full_name", "role", "role_name", "is_active", "outlet_id", "created_at", "last_login_at", "email", "phone"}, records[0])
+		assert.Equal(t, adminA.Username, records[1][1])
+		assert.Equal(t, "admin", records[1][4])
+		assert.Empty(t, records[1][8])
+
+		assert.Equal(t, staff.Username, records[2][1])
+		assert.Equal(t, "employee", records[2][4])
+		assert.Equal(t, "true", records[2][5])
+		assert.Equal(t, "7", records[2][6])
+		assert.NotEmpty(t, records[2][8])
+		// Free text is escaped against formulas, valid phone numbers are exported as stored
+		assert.Equal(t, "'+Kasir Pagi", records[2][2])
+		assert.Equal(t, "+6281234567890", records[2][10])
+	}
+
+	// Credentials are never exported
+	assert.NotContains(t, rr.Body.String(), adminA.Token)
+	assert.NotContains(t, rr.Body.String(), "password")
+}
+
+func TestExportUsersHandler_JSON(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	admin, staff := statusFixture()
+
+	rr := exportUsers(t, admin.Token, "format=json")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Header().Get("Content-Type"), "application/json")
+
+	var rows []model.UserExportRow
+	if err := json.Unmarshal(rr.Body.Bytes(), &rows); err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, rows, 2) {
+		assert.Equal(t, admin.ID, rows[0].ID)
+		assert.Equal(t, staff.Username, rows[1].Username)
+		assert.Equal(t, "employee", rows[1].RoleName)
+		assert.Nil(t, rows[1].LastLoginAt)
+	}
+	assert.NotContains(t, rr.Body.String(), staff.Token)
+}
+
+func TestExportUsersHandler_Invalid(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	admin, staff := statusFixture()
+
+	var response model.HTTPResponse
+	rr := exportUsers(t, admin.Token, "format=xlsx")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+
+	rr = exportUsers(t, staff.Token, "")
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, service.UserNotAllowError, response.Code)
+
+	rr = exportUsers(t, "", "")
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, service.InvalidToken, response.Code)
+}
@@ -0,0 +1,468 @@
+package handler_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"maqhaa/auth_service/internal/app/entity"
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/service"
+	"maqhaa/library/helper"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcMetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	gRPCHandler "maqhaa/auth_service/internal/interface/grpc/handler"
+	pb "maqhaa/auth_service/internal/interface/grpc/model"
+)
+
+// appErrorCode reads the legacy AppError code from the ErrorInfo detail of a gRPC status error.
+func appErrorCode(t *testing.T, err error) int {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Domain == gRPCHandler.ErrorDomain {
+			code, err := strconv.Atoi(info.Metadata[gRPCHandler.ErrorCodeKey])
+			if err != nil {
+				t.Fatal(err)
+			}
+			return code
+		}
+	}
+	t.Fatalf("no ErrorInfo detail in %v", err)
+	return 0
+}
+
+// dialUserGRPC connects to the gRPC test server.
+func dialUserGRPC(t *testing.T) (pb.UserClient, func()) {
+	conn, err := grpc.Dial("localhost:50051", grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Error creating gRPC client connection: %v", err)
+	}
+	return pb.NewUserClient(conn), func() { conn.Close() }
+}
+
+func TestLoginGRPCHandler_Positive(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	userLogin := SampleUser(client.ID)
+	hashedPassword, _ := helper.HashPassword(userLogin.Password)
+	userLogin.Password = hashedPassword
+	db.Create(userLogin)
+
+	clientServer, closeConn := dialUserGRPC(t)
+	defer closeConn()
+
+	resp, err := clientServer.Login(context.Background(), &pb.LoginRequest{Username: userLogin.Username, Password: "rahasia"})
+	if err != nil {
+		t.Fatalf("Error calling Login gRPC method: %v", err)
+	}
+	assert.Equal(t, int32(service.SuccessError), resp.Code)
+	assert.NotEmpty(t, resp.Data.Token)
+	assert.NotNil(t, resp.Data.TokenExpired)
+
+	user, err := clientServer.GetUser(context.Background(), &pb.GetUserRequest{Token: resp.Data.Token})
+	if err != nil {
+		t.Fatalf("Error calling GetUser gRPC method: %v", err)
+	}
+	assert.Equal(t, userLogin.Username, user.Data.Username)
+	assert.Equal(t, userLogin.FullName, user.Data.FullName)
+	assert.Equal(t, uint32(userLogin.Role), user.Data.Role)
+
+	logout, err := clientServer.Logout(context.Background(), &pb.LogoutRequest{Token: resp.Data.Token})
+	if err != nil {
+		t.Fatalf("Error calling Logout gRPC method: %v", err)
+	}
+	assert.Equal(t, int32(service.SuccessError), logout.Code)
+
+	_, err = clientServer.GetUser(context.Background(), &pb.GetUserRequest{Token: resp.Data.Token})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestLoginGRPCHandler_InvalidPassword(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	userLogin := SampleUser(client.ID)
+	hashedPassword, _ := helper.HashPassword(userLogin.Password)
+	userLogin.Password = hashedPassword
+	db.Create(userLogin)
+
+	clientServer, closeConn := dialUserGRPC(t)
+	defer closeConn()
+
+	resp, err := clientServer.Login(context.Background(), &pb.LoginRequest{Username: userLogin.Username, Password: "salah"})
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Equal(t, service.InvalidPassword, appErrorCode(t, err))
+}
+
+func TestManageUserGRPCHandler_Positive(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	userLogin := SampleUser(client.ID)
+	db.Create(userLogin)
+
+	clientServer, closeConn := dialUserGRPC(t)
+	defer closeConn()
+	ctx := context.Background()
+
+	added, err := clientServer.AddUser(ctx, &pb.AddUserRequest{
+		Token:    userLogin.Token,
+		Username: "kasir1",
+		Password: "kasir123",
+		FullName: "Kasir Satu",
+		Role:     entity.RoleEmployeCode,
+	})
+	if err != nil {
+		t.Fatalf("Error calling AddUser gRPC method: %v", err)
+	}
+	assert.Equal(t, int32(service.SuccessError), added.Code)
+
+	list, err := clientServer.ListUsers(ctx, &pb.ListUsersRequest{Token: userLogin.Token})
+	if err != nil {
+		t.Fatalf("Error calling ListUsers gRPC method: %v", err)
+	}
+	assert.Equal(t, int32(service.SuccessError), list.Code)
+	assert.Len(t, list.Data, 2)
+
+	var newUser entity.User
+	if err := db.Where("username = ?", "kasir1").First(&newUser).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	edited, err := clientServer.EditUser(ctx, &pb.EditUserRequest{
+		Token:    userLogin.Token,
+		Id:       uint32(newUser.ID),
+		Username: "kasir1",
+		Password: "kasir456",
+		FullName: "Kasir Pertama",
+		Role:     entity.RoleEmployeCode,
+	})
+	if err != nil {
+		t.Fatalf("Error calling EditUser gRPC method: %v", err)
+	}
+	assert.Equal(t, int32(service.SuccessError), edited.Code)
+
+	got, err := clientServer.GetUserByID(ctx, &pb.GetUserByIDRequest{Token: userLogin.Token, Id: uint32(newUser.ID)})
+	if err != nil {
+		t.Fatalf("Error calling GetUserByID gRPC method: %v", err)
+	}
+	assert.Equal(t, int32(service.SuccessError), got.Code)
+	assert.Equal(t, "Kasir Pertama", got.Data.FullName)
+	assert.Equal(t, uint32(client.ID), got.Data.ClientId)
+	assert.False(t, got.Data.IsAdmin)
+
+	deactivated, err := clientServer.DeactivateUser(ctx, &pb.DeactivateUserRequest{Token: userLogin.Token, Id: uint32(newUser.ID)})
+	if err != nil {
+		t.Fatalf("Error calling DeactivateUser gRPC method: %v", err)
+	}
+	assert.Equal(t, int32(service.SuccessError), deactivated.Code)
+}
+
+func TestGetUserByIDGRPCHandler_NotAllowed(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	admin := SampleUser(client.ID)
+	db.Create(admin)
+	staff := SampleUserCS(client.ID, "staff")
+	db.Create(staff)
+
+	clientServer, closeConn := dialUserGRPC(t)
+	defer closeConn()
+
+	_, err := clientServer.GetUserByID(context.Background(), &pb.GetUserByIDRequest{Token: staff.Token, Id: uint32(admin.ID)})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, service.UserNotAllowError, appErrorCode(t, err))
+
+	_, err = clientServer.GetUserByID(context.Background(), &pb.GetUserByIDRequest{Token: admin.Token, Id: 99999})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, service.UserIDNotFoundError, appErrorCode(t, err))
+}
+
+func TestGetUserGRPCHandler_LegacyErrors(t *testing.T) {
+	resp, err := legacyUserHandlerGrpc.GetUser(context.Background(), &pb.GetUserRequest{Token: "unknown"})
+	if err != nil {
+		t.Fatalf("Error calling GetUser gRPC method: %v", err)
+	}
+	assert.Equal(t, int32(service.InvalidUsername), resp.Code)
+	assert.Nil(t, resp.Data)
+
+	_, err = userHandlerGrpc.GetUser(context.Background(), &pb.GetUserRequest{Token: "unknown"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Equal(t, service.InvalidUsername, appErrorCode(t, err))
+}
+
+// watchRevocations opens a revocation stream and waits until the subscription is in place.
+func watchRevocations(t *testing.T, ctx context.Context, clientServer pb.UserClient, cursor string) pb.User_WatchRevocationsClient {
+	stream, err := clientServer.WatchRevocations(ctx, &pb.WatchRevocationsRequest{Cursor: cursor})
+	if err != nil {
+		t.Fatalf("Error calling WatchRevocations gRPC method: %v", err)
+	}
+	if _, err := stream.Header(); err != nil {
+		t.Fatalf("Error opening WatchRevocations stream: %v", err)
+	}
+	return stream
+}
+
+func TestWatchRevocationsGRPCHandler_Positive(t *testing.T) {
+	tables := []string{"oauth_token", "oauth_client", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	admin := SampleUser(client.ID)
+	db.Create(admin)
+	staff := SampleUserCS(client.ID, "staff")
+	db.Create(staff)
+
+	watcher := SampleOAuthClient("order-service", "user.read")
+	db.Create(watcher)
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	rr := requestServiceToken(t, form, watcher.ClientID, "service-secret")
+	var serviceToken model.TokenResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &serviceToken); err != nil {
+		t.Fatal(err)
+	}
+
+	clientServer, closeConn := dialUserGRPC(t)
+	defer closeConn()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx = grpcMetadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+serviceToken.AccessToken)
+
+	stream := watchRevocations(t, ctx, clientServer, "")
+
+	if _, err := clientServer.DeactivateUser(context.Background(), &pb.DeactivateUserRequest{Token: admin.Token, Id: uint32(staff.ID)}); err != nil {
+		t.Fatalf("Error calling DeactivateUser gRPC method: %v", err)
+	}
+	deactivated, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, pb.RevocationEventType_REVOCATION_EVENT_TYPE_USER_DEACTIVATED, deactivated.Type)
+	assert.Equal(t, uint32(staff.ID), deactivated.UserId)
+	assert.Equal(t, uint32(client.ID), deactivated.ClientId)
+
+	if _, err := clientServer.Logout(context.Background(), &pb.LogoutRequest{Token: admin.Token}); err != nil {
+		t.Fatalf("Error calling Logout gRPC method: %v", err)
+	}
+	revoked, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(admin.Token))
+	assert.Equal(t, pb.RevocationEventType_REVOCATION_EVENT_TYPE_SESSION_REVOKED, revoked.Type)
+	assert.Equal(t, uint32(admin.ID), revoked.UserId)
+	assert.Equal(t, hex.EncodeToString(sum[:]), revoked.TokenHash)
+
+	// A reconnecting subscriber catches up from its last cursor
+	resumed := watchRevocations(t, ctx, clientServer, deactivated.Cursor)
+	missed, err := resumed.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, revoked.Cursor, missed.Cursor)
+}
+
+func TestWatchRevocationsGRPCHandler_InvalidCursor(t *testing.T) {
+	tables := []string{"oauth_token", "oauth_client"}
+	defer clearDB(tables)
+
+	watcher := SampleOAuthClient("order-service", "user.read")
+	db.Create(watcher)
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	rr := requestServiceToken(t, form, watcher.ClientID, "service-secret")
+	var serviceToken model.TokenResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &serviceToken); err != nil {
+		t.Fatal(err)
+	}
+
+	clientServer, closeConn := dialUserGRPC(t)
+	defer closeConn()
+
+	// Streams are only served to services
+	stream, err := clientServer.WatchRevocations(context.Background(), &pb.WatchRevocationsRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := grpcMetadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+serviceToken.AccessToken)
+	stream, err = clientServer.WatchRevocations(ctx, &pb.WatchRevocationsRequest{Cursor: "expired.1"})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	assert.Equal(t, codes.OutOfRange, status.Code(err))
+	assert.Equal(t, service.InvalidCursorError, appErrorCode(t, err))
+}
+
+func TestAuthInterceptorGRPC_UserToken(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	admin := SampleUser(client.ID)
+	db.Create(admin)
+	staff := SampleUserCS(client.ID, "staff")
+	db.Create(staff)
+
+	clientServer, closeConn := dialUserGRPC(t)
+	defer closeConn()
+
+	// The user token is taken from the metadata and the request id is echoed back
+	ctx := grpcMetadata.AppendToOutgoingContext(context.Background(),
+		"authorization", "Bearer "+staff.Token,
+		"x-request-id", "req-user-035")
+	var header grpcMetadata.MD
+	resp, err := clientServer.GetUser(ctx, &pb.GetUserRequest{}, grpc.Header(&header))
+	if err != nil {
+		t.Fatalf("Error calling GetUser gRPC method: %v", err)
+	}
+	assert.Equal(t, uint32(staff.ID), resp.Data.Id)
+	assert.Equal(t, []string{"req-user-035"}, header.Get("x-request-id"))
+
+	// A request id is generated when the caller sends none
+	header = nil
+	ctx = grpcMetadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+staff.Token)
+	if _, err := clientServer.GetUser(ctx, &pb.GetUserRequest{}, grpc.Header(&header)); err != nil {
+		t.Fatalf("Error calling GetUser gRPC method: %v", err)
+	}
+	assert.Len(t, header.Get("x-request-id"), 1)
+	assert.NotEmpty(t, header.Get("x-request-id")[0])
+
+	// Admin RPCs are rejected for other roles before reaching the handler
+	_, err = clientServer.ListUsers(ctx, &pb.ListUsersRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	ctx = grpcMetadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+admin.Token)
+	users, err := clientServer.ListUsers(ctx, &pb.ListUsersRequest{})
+	if err != nil {
+		t.Fatalf("Error calling ListUsers gRPC method: %v", err)
+	}
+	assert.Len(t, users.Data, 2)
+
+	// Streams are only served to services
+	stream, err := clientServer.WatchRevocations(ctx, &pb.WatchRevocationsRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestGetMeGRPCHandler_Positive(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	admin := SampleUser(client.ID)
+	db.Create(admin)
+
+	clientServer, closeConn := dialUserGRPC(t)
+	defer closeConn()
+
+	ctx := grpcMetadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+admin.Token)
+	resp, err := clientServer.GetMe(ctx, &pb.GetMeRequest{})
+	if err != nil {
+		t.Fatalf("Error calling GetMe gRPC method: %v", err)
+	}
+	assert.Equal(t, uint32(admin.ID), resp.Data.User.Id)
+	assert.True(t, resp.Data.User.IsAdmin)
+	assert.NotNil(t, resp.Data.User.TokenExpired)
+	assert.Equal(t, client.CompanyName, resp.Data.ClientName)
+	assert.Equal(t, []string{"profile.read", "user.read", "user.write"}, resp.Data.Permissions)
+
+	_, err = clientServer.GetMe(context.Background(), &pb.GetMeRequest{Token: "unknown"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestListUsersGRPCHandler_Pagination(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	admin := SampleUser(client.ID)
+	db.Create(admin)
+	for _, username := range []string{"user1", "user2", "user3"} {
+		db.Create(SampleUserCS(client.ID, username))
+	}
+
+	clientServer, closeConn := dialUserGRPC(t)
+	defer closeConn()
+
+	active := true
+	first, err := clientServer.ListUsers(context.Background(), &pb.ListUsersRequest{Token: admin.Token, PageSize: 2, Role: 2, IsActive: &active})
+	if err != nil {
+		t.Fatalf("Error calling ListUsers gRPC method: %v", err)
+	}
+	assert.Equal(t, int64(3), first.Total)
+	assert.Len(t, first.Data, 2)
+	assert.NotEmpty(t, first.NextCursor)
+	assert.True(t, first.Data[0].IsActive)
+
+	second, err := clientServer.ListUsers(context.Background(), &pb.ListUsersRequest{Token: admin.Token, PageSize: 2, Role: 2, IsActive: &active, Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatalf("Error calling ListUsers gRPC method: %v", err)
+	}
+	assert.Len(t, second.Data, 1)
+	assert.Empty(t, second.NextCursor)
+	assert.Equal(t, "user3", second.Data[0].Username)
+
+	_, err = clientServer.ListUsers(context.Background(), &pb.ListUsersRequest{Token: admin.Token, Cursor: "not-a-cursor"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestPatchUserGRPCHandler_Positive(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	admin := SampleUser(client.ID)
+	db.Create(admin)
+	staff := SampleUserCS(client.ID, "staff")
+	db.Create(staff)
+
+	clientServer, closeConn := dialUserGRPC(t)
+	defer closeConn()
+
+	fullName := "Kasir Utama"
+	resp, err := clientServer.PatchUser(context.Background(), &pb.PatchUserRequest{Token: admin.Token, Id: uint32(staff.ID), FullName: &fullName})
+	if err != nil {
+		t.Fatalf("Error calling PatchUser gRPC method: %v", err)
+	}
+	assert.Equal(t, int32(service.SuccessError), resp.Code)
+
+	var stored entity.User
+	db.First(&stored, staff.ID)
+	assert.Equal(t, fullName, stored.FullName)
+	assert.Equal(t, staff.Password, stored.Password)
+
+	role := uint32(9)
+	_, err = clientServer.PatchUser(context.Background(), &pb.PatchUserRequest{Token: admin.Token, Id: uint32(staff.ID), Role: &role})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,165 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"maqhaa/auth_service/internal/app/entity"
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/service"
+	pb "maqhaa/auth_service/internal/interface/grpc/model"
+	"maqhaa/library/helper"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// importUsers posts a CSV file to POST /v1/user/import.
+func importUsers(t *testing.T, token, query, file string) (int, model.HTTPResponse, *model.UserImportReport) {
+	req, err := http.NewRequest("POST", "/v1/user/import?"+query, strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", token)
+	req.Header.Set("Content-Type", "text/csv")
+
+	rr := httptest.NewRecorder()
+	versionedRouter(time.Now(), time.Now()).GetRouter().ServeHTTP(rr, req)
+
+	var response struct {
+		model.HTTPResponse
+		Data *model.UserImportReport `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return rr.Code, response.HTTPResponse, response.Data
+}
+
+func TestImportUsersHandler_Positive(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	admin := SampleUser(client.ID)
+	db.Create(admin)
+
+	file := "username,full_name,role,outlet_id,password\n" +
+		"kasir1,Kasir Satu,2,7,kasir1234\n" +
+		"kasir2,Kasir Dua,2,,\n" +
+		"manajer,Manajer Outlet,1,7,\n"
+
+	// A dry run validates without creating anything
+	code, response, report := importUsers(t, admin.Token, "dry_run=true", file)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, service.SuccessError, response.Code)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 0, report.Imported)
+	assert.Equal(t, 0, report.Failed)
+	assert.Len(t, report.Rows, 3)
+	var count int64
+	db.Model(&entity.User{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	code, _, report = importUsers(t, admin.Token, "", file)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 3, report.Imported)
+	if assert.Len(t, report.Rows, 3) {
+		assert.Equal(t, 2, report.Rows[0].Row)
+		assert.Equal(t, "ok", report.Rows[0].Status)
+		assert.Empty(t, report.Rows[0].Password)
+		assert.NotEmpty(t, report.Rows[1].Password)
+	}
+
+	var kasir1, kasir2 entity.User
+	db.Where("username = ?", "kasir1").First(&kasir1)
+	assert.Equal(t, client.ID, kasir1.ClientID)
+	assert.Equal(t, "Kasir Satu", kasir1.FullName)
+	assert.Equal(t, uint(entity.RoleEmployeCode), kasir1.Role)
+	if assert.NotNil(t, kasir1.OutletID) {
+		assert.Equal(t, uint(7), *kasir1.OutletID)
+	}
+	assert.True(t, kasir1.IsActive)
+	assert.NoError(t, helper.CompareHashAndPassword(kasir1.Password, "kasir1234"))
+
+	// Generated passwords are returned once and work for login
+	db.Where("username = ?", "kasir2").First(&kasir2)
+	assert.Nil(t, kasir2.OutletID)
+	assert.NoError(t, helper.CompareHashAndPassword(kasir2.Password, report.Rows[1].Password))
+}
+
+func TestImportUsersHandler_Invalid(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	admin := SampleUser(client.ID)
+	db.Create(admin)
+
+	file := "username,full_name,role,password\n" +
+		"kasir1,Kasir Satu,2,kasir1234\n" +
+		"sample,Nama Terpakai,2,\n" +
+		"kasir1,Kasir Lagi,2,\n" +
+		"kasir3,,2,\n" +
+		"kasir4,Kasir Empat,9,\n" +
+		"kasir5,Kasir Lima,2,pendek\n"
+
+	// One invalid row is enough for nothing to be imported
+	code, response, report := importUsers(t, admin.Token, "", file)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, service.SuccessError, response.Code)
+	assert.Equal(t, 0, report.Imported)
+	assert.Equal(t, 5, report.Failed)
+	if assert.Len(t, report.Rows, 6) {
+		assert.Equal(t, "ok", report.Rows[0].Status)
+		assert.Equal(t, "username is taken", report.Rows[1].Error)
+		assert.Equal(t, "duplicate username in file", report.Rows[2].Error)
+		assert.Equal(t, "full_name is required", report.Rows[3].Error)
+		assert.Equal(t, "invalid role", report.Rows[4].Error)
+		assert.Equal(t, "password must be at least 8 characters", report.Rows[5].Error)
+	}
+	var count int64
+	db.Model(&entity.User{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	code, response, _ = importUsers(t, admin.Token, "", "username,role\nkasir1,2\n")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+
+	staff := SampleUserCS(client.ID, "kasir")
+	db.Create(staff)
+	_, response, _ = importUsers(t, staff.Token, "", file)
+	assert.Equal(t, service.UserNotAllowError, response.Code)
+}
+
+func TestImportUsersGRPCHandler_Positive(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	client := SampleClient()
+	db.Create(client)
+	admin := SampleUser(client.ID)
+	db.Create(admin)
+
+	clientServer, closeConn := dialUserGRPC(t)
+	defer closeConn()
+
+	resp, err := clientServer.ImportUsers(context.Background(), &pb.ImportUsersRequest{
+		Token: admin.Token,
+		Csv:   []byte("username,full_name,role\nkasir1,Kasir Satu,2\n"),
+	})
+	if err != nil {
+		t.Fatalf("Error calling ImportUsers gRPC method: %v", err)
+	}
+	assert.Equal(t, int32(service.SuccessError), resp.Code)
+	assert.Equal(t, uint32(1), resp.Data.Imported)
+	if assert.Len(t, resp.Data.Rows, 1) {
+		assert.NotEmpty(t, resp.Data.Rows[0].Password)
+	}
+}
@@ -0,0 +1,339 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"maqhaa/auth_service/internal/app/entity"
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/service"
+	pb "maqhaa/auth_service/internal/interface/grpc/model"
+	"maqhaa/auth_service/internal/notification"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// invitationRequest calls an /v1 endpoint answering with an invitation.
+func invitationRequest(t *testing.T, method, path, token string, body interface{}) (model.HTTPResponse, *model.UserInvitation) {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, path, &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", token)
+
+	rr := httptest.NewRecorder()
+	versionedRouter(time.Now(), time.Now()).GetRouter().ServeHTTP(rr, req)
+
+	var response struct {
+		model.HTTPResponse
+		Data *model.UserInvitation `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return response.HTTPResponse, response.Data
+}
+
+func listInvitations(t *testing.T, token string) []model.UserInvitation {
+	req, err := http.NewRequest("GET", "/v1/user/invitations", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", token)
+
+	rr := httptest.NewRecorder()
+	versionedRouter(time.Now(), time.Now()).GetRouter().ServeHTTP(rr, req)
+
+	var response struct {
+		model.HTTPResponse
+		Data []model.UserInvitation `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return response.Data
+}
+
+func acceptInvitation(t *testing.T, token, password string) int {
+	_, response := requestV1(t, "POST", "/v1/invite/accept", "", model.AcceptInvitationRequest{Token: token, Password: password})
+	return response.Code
+}
+
+// waitNotification returns the message sent to a user through the log channel, waiting for the
+// dispatcher to deliver it.
+func waitNotification(t *testing.T, event string, userID uint, since time.Time) *notification.Message {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		content, err := os.ReadFile(notificationLog)
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			var message notification.Message
+			if json.Unmarshal([]byte(line), &message) != nil {
+				continue
+			}
+			if message.Event == event && message.UserID == userID && !message.CreatedAt.Before(since) {
+				return &message
+			}
+		}
+	}
+	t.Fatalf("no %s notification sent to user %d", event, userID)
+	return nil
+}
+
+func TestUserInvitation_Notification(t *testing.T) {
+	tables := []string{"user_invitation", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	admin, _ := statusFixture()
+	since := time.Now()
+
+	request := model.AddUserRequest{Username: "baru", FullName: "Kasir Baru", Role: entity.RoleEmployeCode, Invite: true}
+	_, invitation := invitationRequest(t, "POST", "/v1/user", admin.Token, request)
+	if !assert.NotNil(t, invitation) {
+		return
+	}
+
+	message := waitNotification(t, notification.EventInvitation, invitation.UserID, since)
+	assert.Equal(t, "Kasir Baru", message.Recipient.Name)
+	assert.Equal(t, invitation.Token, message.Data["token"])
+	assert.NotEmpty(t, message.Subject)
+	assert.Contains(t, message.Body, "token="+invitation.Token)
+	assert.Contains(t, message.Body, "baru")
+
+	// A resent invitation carries the new token
+	since = time.Now()
+	requestV1(t, "POST", fmt.Sprintf("/v1/user/invitations/%d/revoke", invitation.ID), admin.Token, nil)
+	_, resent := invitationRequest(t, "POST", fmt.Sprintf("/v1/user/%d/invite", invitation.UserID), admin.Token, nil)
+	if assert.NotNil(t, resent) {
+		message = waitNotification(t, notification.EventInvitation, invitation.UserID, since)
+		assert.Equal(t, resent.Token, message.Data["token"])
+	}
+}
+
+func TestUserInvitation_Accept(t *testing.T) {
+	tables := []string{"user_invitation", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	admin, _ := statusFixture()
+
+	request := model.AddUserRequest{Username: "baru", FullName: "Kasir Baru", Role: entity.RoleEmployeCode, Invite: true}
+	response, invitation := invitationRequest(t, "POST", "/v1/user", admin.Token, request)
+	assert.Equal(t, service.SuccessError, response.Code)
+	if !assert.NotNil(t, invitation) {
+		return
+	}
+	assert.NotEmpty(t, invitation.Token)
+	assert.Equal(t, "baru", invitation.Username)
+	assert.Equal(t, entity.InvitationStatusPending, invitation.Status)
+	assert.Equal(t, admin.ID, invitation.InvitedBy)
+
+	// The invitee is pending until the invitation is accepted
+	var stored entity.User
+	db.First(&stored, invitation.UserID)
+	assert.False(t, stored.IsActive)
+	assert.Empty(t, stored.Password)
+
+	var invitationRow entity.UserInvitation
+	db.First(&invitationRow, invitation.ID)
+	assert.NotEqual(t, invitation.Token, invitationRow.TokenHash)
+
+	invitations := listInvitations(t, admin.Token)
+	if assert.Len(t, invitations, 1) {
+		assert.Empty(t, invitations[0].Token)
+		assert.Equal(t, entity.InvitationStatusPending, invitations[0].Status)
+	}
+
+	assert.Equal(t, service.InvalidRequestError, acceptInvitation(t, invitation.Token, "pendek"))
+	assert.Equal(t, service.SuccessError, acceptInvitation(t, invitation.Token, "rahasia123"))
+
+	// The token is single use
+	assert.Equal(t, service.InvalidInvitationError, acceptInvitation(t, invitation.Token, "rahasia456"))
+	assert.Equal(t, service.InvalidInvitationError, acceptInvitation(t, "unknown-token", "rahasia456"))
+
+	db.First(&stored, invitation.UserID)
+	assert.True(t, stored.IsActive)
+	_, loginResponse := requestV1(t, "POST", "/v1/login", "", model.LoginRequest{Username: "baru", Password: "rahasia123"})
+	assert.Equal(t, service.SuccessError, loginResponse.Code)
+
+	invitations = listInvitations(t, admin.Token)
+	if assert.Len(t, invitations, 1) {
+		assert.Equal(t, entity.InvitationStatusAccepted, invitations[0].Status)
+		assert.NotNil(t, invitations[0].AcceptedAt)
+	}
+
+	// Plain adds still need a password, invitations must not carry one
+	request = model.AddUserRequest{Username: "lain", FullName: "Kasir Lain", Role: entity.RoleEmployeCode}
+	response, _ = invitationRequest(t, "POST", "/v1/user", admin.Token, request)
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+	request.Password, request.Invite = "rahasia123", true
+	response, _ = invitationRequest(t, "POST", "/v1/user", admin.Token, request)
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+}
+
+func TestUserInvitation_RevokeResendExpire(t *testing.T) {
+	tables := []string{"user_invitation", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	admin, staff := statusFixture()
+
+	request := model.AddUserRequest{Username: "baru", FullName: "Kasir Baru", Role: entity.RoleEmployeCode, Invite: true}
+	_, first := invitationRequest(t, "POST", "/v1/user", admin.Token, request)
+	if !assert.NotNil(t, first) {
+		return
+	}
+
+	revokePath := fmt.Sprintf("/v1/user/invitations/%d/revoke", first.ID)
+	_, response := requestV1(t, "POST", revokePath, admin.Token, nil)
+	assert.Equal(t, service.SuccessError, response.Code)
+	_, response = requestV1(t, "POST", revokePath, admin.Token, nil)
+	assert.Equal(t, service.InvalidInvitationError, response.Code)
+	assert.Equal(t, service.InvalidInvitationError, acceptInvitation(t, first.Token, "rahasia123"))
+
+	// A revoked or expired invitation is replaced by a new one
+	invitePath := fmt.Sprintf("/v1/user/%d/invite", first.UserID)
+	resendResponse, second := invitationRequest(t, "POST", invitePath, admin.Token, nil)
+	assert.Equal(t, service.SuccessError, resendResponse.Code)
+	if !assert.NotNil(t, second) {
+		return
+	}
+	assert.NotEqual(t, first.Token, second.Token)
+
+	db.Model(&entity.UserInvitation{}).Where("id = ?", second.ID).Update("expires_at", time.Now().Add(-time.Minute))
+	assert.Equal(t, service.InvalidInvitationError, acceptInvitation(t, second.Token, "rahasia123"))
+
+	invitations := listInvitations(t, admin.Token)
+	if assert.Len(t, invitations, 2) {
+		assert.Equal(t, entity.InvitationStatusExpired, invitations[0].Status)
+		assert.Equal(t, entity.InvitationStatusRevoked, invitations[1].Status)
+	}
+
+	_, third := invitationRequest(t, "POST", invitePath, admin.Token, nil)
+	if assert.NotNil(t, third) {
+		assert.Equal(t, service.SuccessError, acceptInvitation(t, third.Token, "rahasia123"))
+	}
+
+	// Only pending users can be invited again
+	resendResponse, _ = invitationRequest(t, "POST", invitePath, admin.Token, nil)
+	assert.Equal(t, service.InvalidRequestError, resendResponse.Code)
+	resendResponse, _ = invitationRequest(t, "POST", fmt.Sprintf("/v1/user/%d/invite", staff.ID), admin.Token, nil)
+	assert.Equal(t, service.InvalidRequestError, resendResponse.Code)
+
+	_, response = requestV1(t, "GET", "/v1/user/invitations", staff.Token, nil)
+	assert.Equal(t, service.UserNotAllowError, response.Code)
+}
+
+func TestUserInvitation_AcceptAfterDeactivation(t *testing.T) {
+	tables := []string{"user_invitation", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	admin, _ := statusFixture()
+
+	request := model.AddUserRequest{Username: "baru", FullName: "Kasir Baru", Role: entity.RoleEmployeCode, Invite: true}
+	_, invitation := invitationRequest(t, "POST", "/v1/user", admin.Token, request)
+	if !assert.NotNil(t, invitation) {
+		return
+	}
+	userPath := fmt.Sprintf("/v1/user/%d", invitation.UserID)
+
+	// Deactivation revokes the open invitation
+	_, response := requestV1(t, "POST", userPath+"/deactivate", admin.Token, model.UserStatusRequest{Reason: "batal"})
+	assert.Equal(t, service.SuccessError, response.Code)
+	assert.Equal(t, service.InvalidInvitationError, acceptInvitation(t, invitation.Token, "rahasia123"))
+
+	invitations := listInvitations(t, admin.Token)
+	if assert.Len(t, invitations, 1) {
+		assert.Equal(t, entity.InvitationStatusRevoked, invitations[0].Status)
+	}
+
+	// A token still open cannot activate a deactivated user either
+	db.Model(&entity.UserInvitation{}).Where("id = ?", invitation.ID).Update("revoked_at", nil)
+	assert.Equal(t, service.InvalidInvitationError, acceptInvitation(t, invitation.Token, "rahasia123"))
+
+	var stored entity.User
+	db.First(&stored, invitation.UserID)
+	assert.False(t, stored.IsActive)
+	assert.Empty(t, stored.Password)
+
+	// Nor can a new invitation until the user is activated again
+	invitePath := userPath + "/invite"
+	resendResponse, _ := invitationRequest(t, "POST", invitePath, admin.Token, nil)
+	assert.Equal(t, service.InvalidRequestError, resendResponse.Code)
+
+	_, response = requestV1(t, "POST", userPath+"/activate", admin.Token, nil)
+	assert.Equal(t, service.SuccessError, response.Code)
+	_, second := invitationRequest(t, "POST", invitePath, admin.Token, nil)
+	if assert.NotNil(t, second) {
+		assert.Equal(t, service.SuccessError, acceptInvitation(t, second.Token, "rahasia123"))
+	}
+
+	// Deleted then restored users stay out as well
+	request = model.AddUserRequest{Username: "lain", FullName: "Kasir Lain", Role: entity.RoleEmployeCode, Invite: true}
+	_, other := invitationRequest(t, "POST", "/v1/user", admin.Token, request)
+	if !assert.NotNil(t, other) {
+		return
+	}
+	otherPath := fmt.Sprintf("/v1/user/%d", other.UserID)
+	_, response = requestV1(t, "POST", otherPath+"/delete", admin.Token, nil)
+	assert.Equal(t, service.SuccessError, response.Code)
+	_, response = requestV1(t, "POST", otherPath+"/restore", admin.Token, nil)
+	assert.Equal(t, service.SuccessError, response.Code)
+	db.Model(&entity.UserInvitation{}).Where("id = ?", other.ID).Update("revoked_at", nil)
+	assert.Equal(t, service.InvalidInvitationError, acceptInvitation(t, other.Token, "rahasia123"))
+	db.First(&stored, other.UserID)
+	assert.False(t, stored.IsActive)
+}
+
+func TestUserInvitationGRPC_Positive(t *testing.T) {
+	tables := []string{"user_invitation", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	admin, _ := statusFixture()
+
+	clientServer, closeConn := dialUserGRPC(t)
+	defer closeConn()
+
+	resp, err := clientServer.AddUser(context.Background(), &pb.AddUserRequest{
+		Token:    admin.Token,
+		Username: "baru",
+		FullName: "Kasir Baru",
+		Role:     uint32(entity.RoleEmployeCode),
+		Invite:   true,
+	})
+	if err != nil {
+		t.Fatalf("Error calling AddUser gRPC method: %v", err)
+	}
+	assert.Equal(t, int32(service.SuccessError), resp.Code)
+	if !assert.NotNil(t, resp.Data) {
+		return
+	}
+
+	list, err := clientServer.ListInvitations(context.Background(), &pb.ListInvitationsRequest{Token: admin.Token})
+	if err != nil {
+		t.Fatalf("Error calling ListInvitations gRPC method: %v", err)
+	}
+	assert.Len(t, list.Data, 1)
+
+	accept, err := clientServer.AcceptInvitation(context.Background(), &pb.AcceptInvitationRequest{
+		InvitationToken: resp.Data.Token,
+		Password:        "rahasia123",
+	})
+	if err != nil {
+		t.Fatalf("Error calling AcceptInvitation gRPC method: %v", err)
+	}
+	assert.Equal(t, int32(service.SuccessError), accept.Code)
+}
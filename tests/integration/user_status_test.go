@@ -0,0 +1,293 @@
+package handler_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"maqhaa/auth_service/internal/app/entity"
+	"maqhaa/auth_service/internal/app/model"
+	"maqhaa/auth_service/internal/app/service"
+	pb "maqhaa/auth_service/internal/interface/grpc/model"
+	"maqhaa/library/helper"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// statusFixture creates a client with an admin and an employee whose password is "rahasia".
+func statusFixture() (admin *entity.User, staff *entity.User) {
+	client := SampleClient()
+	db.Create(client)
+	admin = SampleUser(client.ID)
+	db.Create(admin)
+	staff = SampleUserCS(client.ID, "kasir")
+	hashedPassword, _ := helper.HashPassword(staff.Password)
+	staff.Password = hashedPassword
+	db.Create(staff)
+	return admin, staff
+}
+
+func loginCode(t *testing.T, username string) int {
+	_, response := requestV1(t, "POST", "/v1/login", "", model.LoginRequest{Username: username, Password: "rahasia"})
+	return response.Code
+}
+
+func TestUserStatus_DeactivateAndActivate(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	admin, staff := statusFixture()
+	userPath := fmt.Sprintf("/v1/user/%d", staff.ID)
+
+	code, response := requestV1(t, "POST", userPath+"/deactivate", admin.Token, model.UserStatusRequest{Reason: "cuti panjang"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, service.SuccessError, response.Code)
+
+	var stored entity.User
+	db.First(&stored, staff.ID)
+	assert.False(t, stored.IsActive)
+	assert.Equal(t, "cuti panjang", stored.StatusReason)
+	if assert.NotNil(t, stored.StatusChangedBy) {
+		assert.Equal(t, admin.ID, *stored.StatusChangedBy)
+	}
+	assert.NotNil(t, stored.StatusChangedAt)
+	assert.Empty(t, stored.Token)
+
+	// Logging in no longer reactivates the user
+	assert.Equal(t, service.UserNotActiveError, loginCode(t, staff.Username))
+	db.First(&stored, staff.ID)
+	assert.False(t, stored.IsActive)
+
+	code, response = requestV1(t, "POST", userPath+"/activate", admin.Token, model.UserStatusRequest{Reason: "kembali bekerja"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, service.SuccessError, response.Code)
+
+	db.First(&stored, staff.ID)
+	assert.True(t, stored.IsActive)
+	assert.Equal(t, "kembali bekerja", stored.StatusReason)
+	assert.Equal(t, service.SuccessError, loginCode(t, staff.Username))
+
+	// DELETE keeps deactivating, without a body
+	code, _ = requestV1(t, "DELETE", userPath, admin.Token, nil)
+	assert.Equal(t, http.StatusOK, code)
+	db.First(&stored, staff.ID)
+	assert.False(t, stored.IsActive)
+}
+
+func TestUserStatus_DeleteAndRestore(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	admin, staff := statusFixture()
+	userPath := fmt.Sprintf("/v1/user/%d", staff.ID)
+
+	code, response := requestV1(t, "POST", userPath+"/delete", admin.Token, model.UserStatusRequest{Reason: "resign"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, service.SuccessError, response.Code)
+
+	// Deleted users are hidden and cannot log in
+	_, _, page := listUsers(t, admin.Token, "")
+	assert.Len(t, page.Users, 1)
+	_, response = requestV1(t, "GET", userPath, admin.Token, nil)
+	assert.Equal(t, service.UserIDNotFoundError, response.Code)
+	assert.Equal(t, service.InvalidUsername, loginCode(t, staff.Username))
+
+	var stored entity.User
+	err := db.First(&stored, staff.ID).Error
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	db.Unscoped().First(&stored, staff.ID)
+	assert.True(t, stored.DeletedAt.Valid)
+	assert.Equal(t, "resign", stored.StatusReason)
+
+	_, _, page = listUsers(t, admin.Token, "deleted=true")
+	if assert.Len(t, page.Users, 1) {
+		assert.Equal(t, staff.ID, page.Users[0].ID)
+		assert.Equal(t, "resign", page.Users[0].StatusReason)
+	}
+
+	// Deleting twice reports the user as not found
+	_, response = requestV1(t, "POST", userPath+"/delete", admin.Token, nil)
+	assert.Equal(t, service.UserIDNotFoundError, response.Code)
+
+	code, response = requestV1(t, "POST", userPath+"/restore", admin.Token, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, service.SuccessError, response.Code)
+
+	_, _, page = listUsers(t, admin.Token, "")
+	assert.Len(t, page.Users, 2)
+	assert.Equal(t, service.SuccessError, loginCode(t, staff.Username))
+
+	// Only deleted users can be restored
+	_, response = requestV1(t, "POST", userPath+"/restore", admin.Token, nil)
+	assert.Equal(t, service.UserIDNotFoundError, response.Code)
+}
+
+// seedUserOAuthTokens stores an access and a refresh token issued by oauthClientID to a user.
+func seedUserOAuthTokens(userID uint, oauthClientID, suffix string) (access, refresh entity.OAuthToken) {
+	access = entity.OAuthToken{Token: "access-" + suffix, TokenType: entity.TokenTypeAccess, ClientID: oauthClientID, UserID: userID, ExpiresAt: time.Now().Add(time.Hour)}
+	refresh = entity.OAuthToken{Token: "refresh-" + suffix, TokenType: entity.TokenTypeRefresh, ClientID: oauthClientID, UserID: userID, ExpiresAt: time.Now().Add(time.Hour)}
+	db.Create(&access)
+	db.Create(&refresh)
+	return access, refresh
+}
+
+func TestUserStatus_RevokesOAuthTokens(t *testing.T) {
+	tables := []string{"oauth_token", "oauth_client", "\"user\"", "client"}
+	defer clearDB(tables)
+
+	admin, staff := statusFixture()
+	userPath := fmt.Sprintf("/v1/user/%d", staff.ID)
+	oauthClient := SamplePublicOAuthClient("backoffice-web", backofficeRedirectURI)
+	db.Create(oauthClient)
+
+	refreshCode := func(refreshToken string) int {
+		form := url.Values{}
+		form.Set("grant_type", "refresh_token")
+		form.Set("client_id", oauthClient.ClientID)
+		form.Set("refresh_token", refreshToken)
+		return requestServiceToken(t, form, "", "").Code
+	}
+	assertRevoked := func(tokens ...entity.OAuthToken) {
+		for _, token := range tokens {
+			var stored entity.OAuthToken
+			db.First(&stored, token.ID)
+			assert.NotNil(t, stored.RevokedAt, token.Token)
+		}
+	}
+
+	// A deactivated user can no longer refresh its tokens
+	access, refresh := seedUserOAuthTokens(staff.ID, oauthClient.ClientID, "deactivated")
+	_, response := requestV1(t, "POST", userPath+"/deactivate", admin.Token, nil)
+	assert.Equal(t, service.SuccessError, response.Code)
+	assertRevoked(access, refresh)
+	assert.Equal(t, http.StatusBadRequest, refreshCode(refresh.Token))
+
+	// Neither can a deleted one
+	_, response = requestV1(t, "POST", userPath+"/activate", admin.Token, nil)
+	assert.Equal(t, service.SuccessError, response.Code)
+	access, refresh = seedUserOAuthTokens(staff.ID, oauthClient.ClientID, "deleted")
+	_, response = requestV1(t, "POST", userPath+"/delete", admin.Token, nil)
+	assert.Equal(t, service.SuccessError, response.Code)
+	assertRevoked(access, refresh)
+	assert.Equal(t, http.StatusBadRequest, refreshCode(refresh.Token))
+}
+
+func TestUserStatus_Invalid(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	admin, staff := statusFixture()
+
+	// Admins cannot lock themselves out
+	code, response := requestV1(t, "POST", fmt.Sprintf("/v1/user/%d/deactivate", admin.ID), admin.Token, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+
+	_, response = requestV1(t, "POST", fmt.Sprintf("/v1/user/%d/delete", admin.ID), admin.Token, nil)
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+
+	_, response = requestV1(t, "POST", fmt.Sprintf("/v1/user/%d/deactivate", admin.ID), staff.Token, nil)
+	assert.Equal(t, service.UserNotAllowError, response.Code)
+
+	_, response = requestV1(t, "POST", "/v1/user/99999/activate", admin.Token, nil)
+	assert.Equal(t, service.UserIDNotFoundError, response.Code)
+}
+
+func TestUserStatusGRPC_Positive(t *testing.T) {
+	tables := []string{"\"user\"", "client"}
+	defer clearDB(tables)
+
+	admin, staff := statusFixture()
+
+	clientServer, closeConn := dialUserGRPC(t)
+	defer closeConn()
+
+	resp, err := clientServer.DeleteUser(context.Background(), &pb.DeleteUserRequest{Token: admin.Token, Id: uint32(staff.ID), Reason: "resign"})
+	if err != nil {
+		t.Fatalf("Error calling DeleteUser gRPC method: %v", err)
+	}
+	assert.Equal(t, int32(service.SuccessError), resp.Code)
+
+	list, err := clientServer.ListUsers(context.Background(), &pb.ListUsersRequest{Token: admin.Token, Deleted: true})
+	if err != nil {
+		t.Fatalf("Error calling ListUsers gRPC method: %v", err)
+	}
+	if assert.Len(t, list.Data, 1) {
+		assert.Equal(t, "resign", list.Data[0].StatusReason)
+	}
+
+	_, err = clientServer.RestoreUser(context.Background(), &pb.RestoreUserRequest{Token: admin.Token, Id: uint32(staff.ID)})
+	if err != nil {
+		t.Fatalf("Error calling RestoreUser gRPC method: %v", err)
+	}
+
+	_, err = clientServer.DeactivateUser(context.Background(), &pb.DeactivateUserRequest{Token: admin.Token, Id: uint32(staff.ID), Reason: "cuti"})
+	if err != nil {
+		t.Fatalf("Error calling DeactivateUser gRPC method: %v", err)
+	}
+	_, err = clientServer.ActivateUser(context.Background(), &pb.ActivateUserRequest{Token: admin.Token, Id: uint32(staff.ID)})
+	if err != nil {
+		t.Fatalf("Error calling ActivateUser gRPC method: %v", err)
+	}
+
+	var stored entity.User
+	db.First(&stored, staff.ID)
+	assert.True(t, stored.IsActive)
+	assert.False(t, stored.DeletedAt.Valid)
+}
+
+func TestUserStatus_Erase(t *testing.T) {
+	tables := []string{"\"user\"", "client", "oauth_token"}
+	defer clearDB(tables)
+
+	admin, staff := statusFixture()
+	userPath := fmt.Sprintf("/v1/user/%d", staff.ID)
+	db.Create(&entity.OAuthToken{
+		Token:     "staff-access-token",
+		TokenType: entity.TokenTypeAccess,
+		ClientID:  "pos-app",
+		UserID:    staff.ID,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+
+	code, response := requestV1(t, "POST", userPath+"/erase", admin.Token, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, service.SuccessError, response.Code)
+
+	// Only an anonymized, deleted row is left
+	var stored entity.User
+	if err := db.Unscoped().First(&stored, staff.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, entity.ErasedUsername(staff.ID), stored.Username)
+	assert.Empty(t, stored.FullName)
+	assert.Empty(t, stored.Password)
+	assert.Empty(t, stored.Token)
+	assert.False(t, stored.IsActive)
+	assert.True(t, stored.DeletedAt.Valid)
+	assert.NotNil(t, stored.ErasedAt)
+	assert.Equal(t, staff.ClientID, stored.ClientID)
+
+	var tokens int64
+	db.Model(&entity.OAuthToken{}).Where("user_id = ?", staff.ID).Count(&tokens)
+	assert.Zero(t, tokens)
+	assert.Equal(t, service.InvalidUsername, loginCode(t, staff.Username))
+
+	// Erasure cannot be undone or repeated
+	_, response = requestV1(t, "POST", userPath+"/restore", admin.Token, nil)
+	assert.Equal(t, service.UserIDNotFoundError, response.Code)
+	_, response = requestV1(t, "POST", userPath+"/erase", admin.Token, nil)
+	assert.Equal(t, service.UserIDNotFoundError, response.Code)
+
+	// Nor is the erased user listed with the deleted users
+	_, _, page := listUsers(t, admin.Token, "deleted=true")
+	assert.Empty(t, page.Users)
+	assert.Zero(t, page.Total)
+
+	_, response = requestV1(t, "POST", fmt.Sprintf("/v1/user/%d/erase", admin.ID), admin.Token, nil)
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+}